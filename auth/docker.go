@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/docker/cli/cli/config"
+	"github.com/docker/cli/cli/config/configfile"
+	bkauth "github.com/moby/buildkit/session/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DockerAuthConfigEnv is the environment variable that may hold a Docker
+// config, as in ~/.docker/config.json, whose credentials take precedence over
+// those of the config file.
+const DockerAuthConfigEnv = "DOCKER_AUTH_CONFIG"
+
+// Docker Hub's credentials are stored under its legacy index address.
+const dockerHubConfigKey = "https://index.docker.io/v1/"
+
+// DockerConfigAuthProvider serves the registry credentials of the host's
+// Docker config to the engine, which asks for them when a registry has no
+// credentials set with Container.withRegistryAuth. Clients opt in to it with
+// client.Params.DockerConfigAuth, or the CLI's --docker-config-auth flag.
+//
+// Credentials are looked up in DOCKER_AUTH_CONFIG first, then in the Docker
+// config file and the credential helpers it configures. Neither is read until
+// the engine first asks for credentials.
+type DockerConfigAuthProvider struct {
+	stderr io.Writer
+
+	loadOnce sync.Once
+	configs  []*configfile.ConfigFile
+	loadErr  error
+
+	bkauth.UnimplementedAuthServer
+}
+
+// NewDockerConfigAuthProvider returns a provider of the host's Docker
+// credentials, which writes warnings about the config file to stderr.
+func NewDockerConfigAuthProvider(stderr io.Writer) *DockerConfigAuthProvider {
+	return &DockerConfigAuthProvider{stderr: stderr}
+}
+
+func (p *DockerConfigAuthProvider) Register(server *grpc.Server) {
+	bkauth.RegisterAuthServer(server, p)
+}
+
+func (p *DockerConfigAuthProvider) load() ([]*configfile.ConfigFile, error) {
+	p.loadOnce.Do(func() {
+		if env := os.Getenv(DockerAuthConfigEnv); env != "" {
+			envConfig := configfile.New("")
+			if err := envConfig.LoadFromReader(strings.NewReader(env)); err != nil {
+				p.loadErr = fmt.Errorf("parse %s: %w", DockerAuthConfigEnv, err)
+				return
+			}
+			p.configs = append(p.configs, envConfig)
+		}
+		p.configs = append(p.configs, config.LoadDefaultConfigFile(p.stderr))
+	})
+	return p.configs, p.loadErr
+}
+
+// Credentials returns the credentials of the requested registry, or a
+// NotFound error if the host has none.
+func (p *DockerConfigAuthProvider) Credentials(ctx context.Context, req *bkauth.CredentialsRequest) (*bkauth.CredentialsResponse, error) {
+	configs, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+
+	host := req.GetHost()
+	if host == defaultDockerDomain || host == "registry-1.docker.io" || host == "index.docker.io" {
+		host = dockerHubConfigKey
+	}
+
+	for _, cfg := range configs {
+		ac, err := cfg.GetAuthConfig(host)
+		if err != nil {
+			return nil, fmt.Errorf("get credentials of %s: %w", req.GetHost(), err)
+		}
+		switch {
+		case ac.IdentityToken != "":
+			return &bkauth.CredentialsResponse{Secret: ac.IdentityToken}, nil
+		case ac.Username != "" || ac.Password != "":
+			return &bkauth.CredentialsResponse{Username: ac.Username, Secret: ac.Password}, nil
+		}
+	}
+
+	return nil, status.Errorf(codes.NotFound, "no credential found for %s", req.GetHost())
+}
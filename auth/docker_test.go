@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moby/buildkit/session/auth"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDockerConfigAuthProvider(t *testing.T) {
+	ctx := context.Background()
+
+	basicAuth := func(user, pass string) string {
+		return base64.StdEncoding.EncodeToString([]byte(user + ":" + pass))
+	}
+
+	configDir := t.TempDir()
+	err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(`{
+		"auths": {
+			"https://index.docker.io/v1/": {"auth": "`+basicAuth("hub", "hubsecret")+`"},
+			"registry.dagger.io": {"auth": "`+basicAuth("file", "filesecret")+`"}
+		}
+	}`), 0o600)
+	require.NoError(t, err)
+	t.Setenv("DOCKER_CONFIG", configDir)
+	t.Setenv(DockerAuthConfigEnv, `{
+		"auths": {
+			"registry.dagger.io": {"auth": "`+basicAuth("env", "envsecret")+`"}
+		}
+	}`)
+
+	provider := NewDockerConfigAuthProvider(os.Stderr)
+
+	for _, host := range []string{"docker.io", "registry-1.docker.io"} {
+		res, err := provider.Credentials(ctx, &auth.CredentialsRequest{Host: host})
+		require.NoError(t, err)
+		require.Equal(t, "hub", res.Username)
+		require.Equal(t, "hubsecret", res.Secret)
+	}
+
+	// DOCKER_AUTH_CONFIG takes precedence over the config file
+	res, err := provider.Credentials(ctx, &auth.CredentialsRequest{Host: "registry.dagger.io"})
+	require.NoError(t, err)
+	require.Equal(t, "env", res.Username)
+	require.Equal(t, "envsecret", res.Secret)
+
+	_, err = provider.Credentials(ctx, &auth.CredentialsRequest{Host: "unknown.dagger.io"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
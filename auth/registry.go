@@ -32,6 +32,10 @@ type RegistryAuthProvider struct {
 	// Memory map credential storage.
 	credentials map[string]*bkauth.CredentialsResponse
 
+	// Credentials of the client's host, looked up once per registry domain.
+	host            bkauth.AuthClient
+	hostCredentials map[string]*bkauth.CredentialsResponse
+
 	// Mutex to handle concurrency.
 	m sync.RWMutex
 
@@ -40,7 +44,21 @@ type RegistryAuthProvider struct {
 
 // NewRegistryAuthProvider initializes a new store.
 func NewRegistryAuthProvider() *RegistryAuthProvider {
-	return &RegistryAuthProvider{credentials: map[string]*bkauth.CredentialsResponse{}}
+	return &RegistryAuthProvider{
+		credentials:     map[string]*bkauth.CredentialsResponse{},
+		hostCredentials: map[string]*bkauth.CredentialsResponse{},
+	}
+}
+
+// SetHostAuth sets the auth service of the client's host, which is asked for
+// the credentials of registries that none were added for. The client opts in
+// by serving it, e.g. with DockerConfigAuthProvider.
+func (r *RegistryAuthProvider) SetHostAuth(host bkauth.AuthClient) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.host = host
+	r.hostCredentials = map[string]*bkauth.CredentialsResponse{}
 }
 
 // AddCredential inserts a new credential for the corresponding address.
@@ -171,15 +189,54 @@ func (r *RegistryAuthProvider) credential(domain string) *bkauth.CredentialsResp
 	return nil
 }
 
+// hostCredential asks the client's host for the credential of the given
+// domain, remembering the answer, including when it has none.
+func (r *RegistryAuthProvider) hostCredential(ctx context.Context, domain string) (*bkauth.CredentialsResponse, error) {
+	r.m.RLock()
+	host := r.host
+	credential, found := r.hostCredentials[domain]
+	r.m.RUnlock()
+	if host == nil || found {
+		return credential, nil
+	}
+
+	res, err := host.Credentials(ctx, &bkauth.CredentialsRequest{Host: domain})
+	switch {
+	case err == nil:
+		if res.GetUsername() != "" || res.GetSecret() != "" {
+			credential = res
+		}
+	case status.Code(err) == codes.NotFound || status.Code(err) == codes.Unimplemented:
+		// the host has no credential, or doesn't serve them
+	default:
+		return nil, err
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.hostCredentials[domain] = credential
+	return credential, nil
+}
+
 // Credentials retrieves credentials of the requested address.
 // It searches in the memory map for the standardize address.
 //
-// If the address isn't registered in the memory map, it will search
-// on DockerAuthProvider.
+// If the address isn't registered in the memory map, it asks the client's
+// host, if set. Credentials added with AddCredential always take precedence
+// over those of the host.
 func (r *RegistryAuthProvider) Credentials(ctx context.Context, req *bkauth.CredentialsRequest) (*bkauth.CredentialsResponse, error) {
 	memoryCredential := r.credential(req.GetHost())
 	if memoryCredential != nil {
 		return memoryCredential, nil
 	}
+
+	hostCredential, err := r.hostCredential(ctx, req.GetHost())
+	if err != nil {
+		return nil, err
+	}
+	if hostCredential != nil {
+		return hostCredential, nil
+	}
+
 	return nil, status.Errorf(codes.NotFound, "no credential found for %s", req.GetHost())
 }
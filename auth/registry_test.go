@@ -6,6 +6,9 @@ import (
 
 	"github.com/moby/buildkit/session/auth"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
@@ -130,3 +133,56 @@ func TestRegistryAuthProvider(t *testing.T) {
 		require.Equal(t, testRegistrySecret, credentialsRes.Secret)
 	})
 }
+
+type fakeHostAuth struct {
+	auth.AuthClient
+	credentials map[string]*auth.CredentialsResponse
+	requests    int
+}
+
+func (h *fakeHostAuth) Credentials(ctx context.Context, req *auth.CredentialsRequest, _ ...grpc.CallOption) (*auth.CredentialsResponse, error) {
+	h.requests++
+	if res, ok := h.credentials[req.Host]; ok {
+		return res, nil
+	}
+	return nil, status.Errorf(codes.NotFound, "no credential found for %s", req.Host)
+}
+
+func TestRegistryAuthProviderHost(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	host := &fakeHostAuth{
+		credentials: map[string]*auth.CredentialsResponse{
+			testRegistryAddress: {Username: "host", Secret: "hostsecret"},
+			"host.dagger.io":    {Username: "host", Secret: "hostsecret"},
+		},
+	}
+	registry := NewRegistryAuthProvider()
+	registry.SetHostAuth(host)
+
+	t.Run("host credentials are looked up once", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			res, err := registry.Credentials(ctx, &auth.CredentialsRequest{Host: "host.dagger.io"})
+			require.NoError(t, err)
+			require.Equal(t, "host", res.Username)
+		}
+
+		_, err := registry.Credentials(ctx, &auth.CredentialsRequest{Host: "unknown.dagger.io"})
+		require.Equal(t, codes.NotFound, status.Code(err))
+		_, err = registry.Credentials(ctx, &auth.CredentialsRequest{Host: "unknown.dagger.io"})
+		require.Equal(t, codes.NotFound, status.Code(err))
+
+		require.Equal(t, 2, host.requests)
+	})
+
+	t.Run("added credentials take precedence", func(t *testing.T) {
+		err := registry.AddCredential(testRegistryAddress, testRegistryUser, testRegistrySecret)
+		require.NoError(t, err)
+
+		res, err := registry.Credentials(ctx, &auth.CredentialsRequest{Host: testRegistryAddress})
+		require.NoError(t, err)
+		require.Equal(t, testRegistryUser, res.Username)
+		require.Equal(t, testRegistrySecret, res.Secret)
+	})
+}
@@ -20,6 +20,8 @@ import (
 var silent bool
 
 var progress string
+
+var dockerConfigAuth bool
 var stdoutIsTTY = isatty.IsTerminal(os.Stdout.Fd())
 var stderrIsTTY = isatty.IsTerminal(os.Stderr.Fd())
 
@@ -40,6 +42,13 @@ func init() {
 		"auto",
 		"progress output format (auto, plain, tty)",
 	)
+
+	rootCmd.PersistentFlags().BoolVar(
+		&dockerConfigAuth,
+		"docker-config-auth",
+		false,
+		"look up registry credentials in DOCKER_AUTH_CONFIG, then the Docker config, per registry when needed (withRegistryAuth takes precedence)",
+	)
 }
 
 // show only focused vertices. enabled by default for dagger do.
@@ -59,6 +68,12 @@ func withEngineAndTUI(
 	}
 
 	params.DisableHostRW = disableHostRW
+	params.DockerConfigAuth = dockerConfigAuth
+
+	params, err := withImageLock(params)
+	if err != nil {
+		return err
+	}
 
 	if params.JournalFile == "" {
 		params.JournalFile = os.Getenv("_EXPERIMENTAL_DAGGER_JOURNAL")
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/dagger/dagger/engine"
+	"github.com/dagger/dagger/engine/client"
+	"github.com/spf13/cobra"
+)
+
+var (
+	imageLockMode string
+	imageLockFile string
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(
+		&imageLockMode,
+		"lock",
+		"off",
+		"pin image references to the digests in the lockfile (off, record, strict, update)",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&imageLockFile,
+		"lock-file",
+		engine.ImageLockFile,
+		"path of the image lockfile",
+	)
+
+	lockCmd.AddCommand(lockUpdateCmd)
+	rootCmd.AddCommand(lockCmd)
+}
+
+var lockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Manage the lockfile of image references",
+	Long: `Manage the lockfile of image references.
+
+Running with --lock=record resolves each image reference passed to
+Container.from to the digest in the lockfile, recording the digest of any
+reference that isn't in it yet. With --lock=strict, references that aren't in
+the lockfile are refused instead.`,
+}
+
+var lockUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Resolve every image reference in the lockfile again",
+	Args:  cobra.NoArgs,
+	RunE:  lockUpdate,
+}
+
+// withImageLock sets the image lock params from the global flags, unless
+// they're already set.
+func withImageLock(params client.Params) (client.Params, error) {
+	if params.ImageLockMode != "" {
+		return params, nil
+	}
+
+	mode, err := engine.ParseImageLockMode(imageLockMode)
+	if err != nil {
+		return params, err
+	}
+	params.ImageLockMode = mode
+	params.ImageLockPath = imageLockFile
+	return params, nil
+}
+
+func lockUpdate(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+
+	lock, err := engine.ReadImageLock(imageLockFile, engine.ImageLockModeUpdate)
+	if err != nil {
+		return err
+	}
+	refs := lock.Refs()
+	if len(refs) == 0 {
+		if _, err := os.Stat(imageLockFile); errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("no lockfile at %s; run with --lock=%s to create one", imageLockFile, engine.ImageLockModeRecord)
+		}
+		return nil
+	}
+
+	params := client.Params{
+		ImageLockMode: engine.ImageLockModeUpdate,
+		ImageLockPath: imageLockFile,
+	}
+	return withEngineAndTUI(ctx, params, func(ctx context.Context, engineClient *client.Client) error {
+		for _, ref := range refs {
+			var res struct {
+				Container struct {
+					From struct {
+						ImageRef string
+					}
+				}
+			}
+			err := engineClient.Do(ctx, `query LockUpdate($ref: String!) {
+				container {
+					from(address: $ref) {
+						imageRef
+					}
+				}
+			}`, "LockUpdate", map[string]any{"ref": ref}, &res)
+			if err != nil {
+				return fmt.Errorf("resolve %s: %w", ref, err)
+			}
+		}
+		return nil
+	})
+}
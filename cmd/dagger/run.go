@@ -14,6 +14,7 @@ import (
 	"github.com/dagger/dagger/engine/client"
 	"github.com/dagger/dagger/internal/tui"
 	"github.com/google/uuid"
+	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 	"github.com/vito/progrock"
 )
@@ -24,7 +25,10 @@ var runCmd = &cobra.Command{
 	DisableFlagsInUseLine: true,
 	Long: `Runs the specified command in a Dagger session and shows progress in a TUI
 
-DAGGER_SESSION_PORT and DAGGER_SESSION_TOKEN will be convieniently injected automatically.`,
+DAGGER_SESSION_PORT and DAGGER_SESSION_TOKEN will be convieniently injected automatically.
+
+When --debug is set and stdin is a terminal, an interactive shell is opened
+into the container of any exec that fails, before its error is returned.`,
 	Short: "Runs a command in a Dagger session",
 	Example: `  Run a Dagger pipeline written in Go:
     dagger run go run main.go
@@ -90,6 +94,13 @@ func run(ctx context.Context, args []string) error {
 	sessionToken := u.String()
 
 	focus = runFocus
+
+	// debug shells need the terminal, so they can't be used alongside the TUI
+	debugShells := debug && isatty.IsTerminal(os.Stdin.Fd())
+	if debugShells && progress == "auto" {
+		progress = "plain"
+	}
+
 	return withEngineAndTUI(ctx, client.Params{
 		SecretToken: sessionToken,
 	}, func(ctx context.Context, engineClient *client.Client) error {
@@ -115,6 +126,16 @@ func run(ctx context.Context, args []string) error {
 
 		go http.Serve(sessionL, engineClient) // nolint:gosec
 
+		if debugShells {
+			debugCtx, cancelDebug := context.WithCancel(ctx)
+			defer cancelDebug()
+			go func() {
+				if err := debugFailedExecs(debugCtx, engineClient); err != nil {
+					fmt.Fprintln(os.Stderr, "debug shell:", err)
+				}
+			}()
+		}
+
 		var cmdErr error
 		if !silent {
 			rec := progrock.FromContext(ctx)
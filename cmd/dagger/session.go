@@ -71,16 +71,23 @@ func EngineSession(cmd *cobra.Command, args []string) error {
 
 	port := l.Addr().(*net.TCPAddr).Port
 
-	sess, _, err := client.Connect(ctx, client.Params{
+	params, err := withImageLock(client.Params{
 		SecretToken:    sessionToken.String(),
 		RunnerHost:     engine.RunnerHost(),
 		UserAgent:      labels.AppendCILabel().AppendAnonymousGitLabels(workdir).String(),
 		ProgrockWriter: console.NewWriter(os.Stderr),
 		JournalFile:    os.Getenv("_EXPERIMENTAL_DAGGER_JOURNAL"),
+
+		DockerConfigAuth: dockerConfigAuth,
 	})
 	if err != nil {
 		return err
 	}
+
+	sess, _, err := client.Connect(ctx, params)
+	if err != nil {
+		return err
+	}
 	defer sess.Close()
 
 	srv := http.Server{
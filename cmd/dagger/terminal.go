@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dagger/dagger/engine"
+	"github.com/dagger/dagger/engine/client"
+	"golang.org/x/term"
+)
+
+// debugFailedExecs opens an interactive shell into the state of every exec
+// that fails until ctx is canceled. The failed exec's error is returned to
+// the caller once the shell exits.
+func debugFailedExecs(ctx context.Context, engineClient *client.Client) error {
+	return engineClient.WatchDebugTerminals(ctx, func(ctx context.Context, dbg engine.DebugTerminal) error {
+		fmt.Fprintf(os.Stderr, "\nexec %q failed with exit code %d, opening debug shell (exit to continue)\n",
+			strings.Join(dbg.Cmd, " "), dbg.ExitCode)
+
+		_, err := attachTerminal(ctx, engineClient, dbg.ID)
+		return err
+	})
+}
+
+// attachTerminal attaches the current terminal to the terminal with the given
+// ID, returning the exit code of its process.
+func attachTerminal(ctx context.Context, engineClient *client.Client, id string) (int, error) {
+	stdinFd := int(os.Stdin.Fd())
+
+	oldState, err := term.MakeRaw(stdinFd)
+	if err != nil {
+		return -1, fmt.Errorf("make terminal raw: %w", err)
+	}
+	defer term.Restore(stdinFd, oldState)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	resize := make(chan engine.TerminalSize, 1)
+	go watchTerminalSize(ctx, int(os.Stdout.Fd()), resize)
+
+	return engineClient.AttachTerminal(ctx, id, os.Stdin, os.Stdout, os.Stderr, resize)
+}
+
+func sendTerminalSize(fd int, resize chan<- engine.TerminalSize) {
+	width, height, err := term.GetSize(fd)
+	if err != nil {
+		return
+	}
+	select {
+	case resize <- engine.TerminalSize{Width: width, Height: height}:
+	default:
+		// a resize is already pending
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+// +build !unix
+
+package main
+
+import (
+	"context"
+
+	"github.com/dagger/dagger/engine"
+)
+
+func watchTerminalSize(ctx context.Context, fd int, resize chan<- engine.TerminalSize) {
+	sendTerminalSize(fd, resize)
+}
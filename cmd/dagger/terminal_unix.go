@@ -0,0 +1,29 @@
+//go:build unix
+// +build unix
+
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/dagger/dagger/engine"
+)
+
+func watchTerminalSize(ctx context.Context, fd int, resize chan<- engine.TerminalSize) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGWINCH)
+	defer signal.Stop(sigCh)
+
+	sendTerminalSize(fd, resize)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigCh:
+			sendTerminalSize(fd, resize)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/dagger/dagger/engine/client"
+	"github.com/spf13/cobra"
+	"github.com/vito/progrock"
+)
+
+var (
+	upPorts  []string
+	upNative bool
+)
+
+var upCmd = &cobra.Command{
+	Use:   "up [flags] SERVICE_ID",
+	Short: "Forward ports of the host to a service",
+	Long: `Start a service and forward ports of the host to it until interrupted.
+
+The service is given by its ID, e.g. as returned by Service.id. Unless ports
+are given with --port, every port exposed by the service is forwarded, to a
+random port of the host, or to the same port with --native.`,
+	Example: `  dagger up --port 8080:80 "$SERVICE_ID"
+  dagger up --port 5353:53/udp "$SERVICE_ID"`,
+	Args: cobra.ExactArgs(1),
+	RunE: Up,
+}
+
+func init() {
+	upCmd.Flags().StringSliceVarP(&upPorts, "port", "p", nil, "forward a port of the host to the service, as FRONTEND:BACKEND[/PROTOCOL]")
+	upCmd.Flags().BoolVarP(&upNative, "native", "n", false, "forward each port of the service to the same port of the host")
+
+	rootCmd.AddCommand(upCmd)
+}
+
+type portForward struct {
+	Frontend int    `json:"frontend"`
+	Backend  int    `json:"backend"`
+	Protocol string `json:"protocol"`
+}
+
+// parsePortForward parses a port forward given as FRONTEND:BACKEND[/PROTOCOL],
+// where FRONTEND may be omitted to use a random port.
+func parsePortForward(s string) (portForward, error) {
+	forward := portForward{Protocol: "TCP"}
+
+	ports, proto, hasProto := strings.Cut(s, "/")
+	if hasProto {
+		forward.Protocol = strings.ToUpper(proto)
+		if forward.Protocol != "TCP" && forward.Protocol != "UDP" {
+			return forward, fmt.Errorf("port %q: unknown protocol %q", s, proto)
+		}
+	}
+
+	frontend, backend, hasFrontend := strings.Cut(ports, ":")
+	if !hasFrontend {
+		frontend, backend = "", frontend
+	}
+
+	var err error
+	if frontend != "" {
+		forward.Frontend, err = strconv.Atoi(frontend)
+		if err != nil {
+			return forward, fmt.Errorf("port %q: invalid frontend port: %w", s, err)
+		}
+	}
+	forward.Backend, err = strconv.Atoi(backend)
+	if err != nil {
+		return forward, fmt.Errorf("port %q: invalid backend port: %w", s, err)
+	}
+
+	return forward, nil
+}
+
+func Up(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serviceID := args[0]
+
+	ports := make([]portForward, 0, len(upPorts))
+	for _, s := range upPorts {
+		forward, err := parsePortForward(s)
+		if err != nil {
+			return err
+		}
+		ports = append(ports, forward)
+	}
+
+	return withEngineAndTUI(ctx, client.Params{}, func(ctx context.Context, engineClient *client.Client) error {
+		var stderr io.Writer
+		if silent {
+			stderr = os.Stderr
+		} else {
+			vtx := progrock.FromContext(ctx).Vertex("up", "up")
+			defer vtx.Done(nil)
+			stderr = vtx.Stderr()
+		}
+
+		var res struct {
+			Host struct {
+				Tunnel struct {
+					Start string
+				}
+			}
+		}
+		err := engineClient.Do(ctx, `query Up($service: ServiceID!, $ports: [PortForward!], $native: Boolean) {
+			host {
+				tunnel(service: $service, ports: $ports, native: $native) {
+					start
+				}
+			}
+		}`, "Up", map[string]any{
+			"service": serviceID,
+			"ports":   ports,
+			"native":  upNative,
+		}, &res)
+		if err != nil {
+			return fmt.Errorf("start tunnel: %w", err)
+		}
+
+		var tunnel struct {
+			Service struct {
+				Ports []struct {
+					Port        int
+					Protocol    string
+					Description string
+				}
+			}
+		}
+		err = engineClient.Do(ctx, `query UpPorts($tunnel: ServiceID!) {
+			service(id: $tunnel) {
+				ports {
+					port
+					protocol
+					description
+				}
+			}
+		}`, "UpPorts", map[string]any{"tunnel": res.Host.Tunnel.Start}, &tunnel)
+		if err != nil {
+			return fmt.Errorf("get tunnel ports: %w", err)
+		}
+
+		for _, port := range tunnel.Service.Ports {
+			fmt.Fprintf(stderr, "==> %s (%s)\n", port.Description, strings.ToLower(port.Protocol))
+		}
+
+		<-ctx.Done()
+		fmt.Fprintln(stderr, "==> stopping")
+		return nil
+	})
+}
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/moby/buildkit/cmd/buildkitd/config"
+	resolverconfig "github.com/moby/buildkit/util/resolver/config"
 )
 
 // engineDefaultStateDir is the directory that we map to a volume by default.
@@ -10,6 +14,9 @@ const engineDefaultStateDir = "/var/lib/dagger"
 // engineDefaultShimBin is the path to the shim binary we use as our oci runtime.
 const engineDefaultShimBin = "/usr/local/bin/dagger-shim"
 
+// defaultRegistry is the registry that mirrors apply to when none is given.
+const defaultRegistry = "docker.io"
+
 // servicesDNSEnvName is the feature flag for enabling the services network
 // stack.
 const servicesDNSEnvName = "_EXPERIMENTAL_DAGGER_SERVICES_DNS"
@@ -53,3 +60,44 @@ func setNetworkDefaults(cfg *config.NetworkConfig, cniConfigPath string) {
 		cfg.CNIPoolSize = 16
 	}
 }
+
+// setRegistryConfig adds the given mirrors and insecure registries to the
+// registry config, on top of any in the config file.
+//
+// Mirrors are formatted as [registry=]mirror, with the registry defaulting to
+// Docker Hub.
+func setRegistryConfig(cfg *config.Config, mirrors []string, insecureRegistries []string) error {
+	if len(mirrors) == 0 && len(insecureRegistries) == 0 {
+		return nil
+	}
+	if cfg.Registries == nil {
+		cfg.Registries = make(map[string]resolverconfig.RegistryConfig)
+	}
+
+	for _, mirror := range mirrors {
+		registry, mirrorHost, found := strings.Cut(mirror, "=")
+		if !found {
+			registry, mirrorHost = defaultRegistry, mirror
+		}
+		if registry == "" || mirrorHost == "" {
+			return fmt.Errorf("invalid registry mirror %q", mirror)
+		}
+		regCfg := cfg.Registries[registry]
+		regCfg.Mirrors = append(regCfg.Mirrors, mirrorHost)
+		cfg.Registries[registry] = regCfg
+	}
+
+	for _, registry := range insecureRegistries {
+		if registry == "" {
+			return fmt.Errorf("invalid insecure registry %q", registry)
+		}
+		// try plain HTTP first, then HTTPS without verifying certificates
+		insecure := true
+		regCfg := cfg.Registries[registry]
+		regCfg.PlainHTTP = &insecure
+		regCfg.Insecure = &insecure
+		cfg.Registries[registry] = regCfg
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/moby/buildkit/cmd/buildkitd/config"
+	resolverconfig "github.com/moby/buildkit/util/resolver/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetRegistryConfig(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Registries: map[string]resolverconfig.RegistryConfig{
+			"docker.io": {Mirrors: []string{"mirror.gcr.io"}},
+		},
+	}
+	err := setRegistryConfig(cfg,
+		[]string{"registry.local:5000", "ghcr.io=ghcr.mirror.local"},
+		[]string{"registry.local:5000"},
+	)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"mirror.gcr.io", "registry.local:5000"}, cfg.Registries["docker.io"].Mirrors)
+	require.Equal(t, []string{"ghcr.mirror.local"}, cfg.Registries["ghcr.io"].Mirrors)
+
+	local := cfg.Registries["registry.local:5000"]
+	require.NotNil(t, local.PlainHTTP)
+	require.True(t, *local.PlainHTTP)
+	require.NotNil(t, local.Insecure)
+	require.True(t, *local.Insecure)
+
+	require.Error(t, setRegistryConfig(cfg, []string{"docker.io="}, nil))
+}
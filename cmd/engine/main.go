@@ -209,6 +209,14 @@ func main() { //nolint:gocyclo
 			Name:  "allow-insecure-entitlement",
 			Usage: "allows insecure entitlements e.g. network.host, security.insecure",
 		},
+		cli.StringSliceFlag{
+			Name:  "registry-mirror",
+			Usage: "mirror to pull images from, as [registry=]mirror (registry defaults to docker.io)",
+		},
+		cli.StringSliceFlag{
+			Name:  "insecure-registry",
+			Usage: "registry to access over plain HTTP or with unverified TLS",
+		},
 		cli.StringFlag{
 			Name:  "network-name",
 			Usage: "short name for the engine's container network; used for interface name",
@@ -259,6 +267,10 @@ func main() { //nolint:gocyclo
 			return err
 		}
 
+		if err := setRegistryConfig(&cfg, c.GlobalStringSlice("registry-mirror"), c.GlobalStringSlice("insecure-registry")); err != nil {
+			return err
+		}
+
 		logrus.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
 		if cfg.Debug {
 			logrus.SetLevel(logrus.DebugLevel)
@@ -808,6 +820,7 @@ func newController(ctx context.Context, c *cli.Context, cfg *config.Config) (*se
 		TraceCollector:         tc,
 		UpstreamCacheExporters: remoteCacheExporterFuncs,
 		UpstreamCacheImporters: remoteCacheImporterFuncs,
+		RegistryHosts:          resolverFn,
 	})
 	if err != nil {
 		return nil, nil, err
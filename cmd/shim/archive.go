@@ -0,0 +1,418 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// archive writes the contents of a directory to an archive file.
+func archive(args []string) error {
+	if len(args) != 3 {
+		return fmt.Errorf("usage: archive <tar|tar.gz|tar.zst|zip> <src> <dest>")
+	}
+
+	format, src, dest := args[0], args[1], args[2]
+
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch format {
+	case "tar":
+		err = writeTar(f, src)
+	case "tar.gz":
+		gz := gzip.NewWriter(f)
+		err = writeTar(gz, src)
+		if err == nil {
+			err = gz.Close()
+		}
+	case "tar.zst":
+		var zw *zstd.Encoder
+		zw, err = zstd.NewWriter(f)
+		if err != nil {
+			return err
+		}
+		err = writeTar(zw, src)
+		if err == nil {
+			err = zw.Close()
+		}
+	case "zip":
+		err = writeZip(f, src)
+	default:
+		return fmt.Errorf("unknown archive format: %s", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+func writeTar(w io.Writer, src string) error {
+	tw := tar.NewWriter(w)
+
+	err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		var link string
+		if info.Mode()&fs.ModeSymlink != 0 {
+			link, err = os.Readlink(p)
+			if err != nil {
+				return err
+			}
+		}
+
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		// there's no user database to look names up in, and access and change
+		// times would only make the archive less reproducible
+		hdr.Uname, hdr.Gname = "", ""
+		hdr.AccessTime, hdr.ChangeTime = time.Time{}, time.Time{}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		if info.Mode().IsRegular() {
+			return copyFileTo(tw, p)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
+
+func writeZip(w io.Writer, src string) error {
+	zw := zip.NewWriter(w)
+
+	err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		mode := info.Mode()
+		if !mode.IsRegular() && !mode.IsDir() && mode&fs.ModeSymlink == 0 {
+			return fmt.Errorf("cannot add %s to zip: unsupported file type %s", rel, mode.Type())
+		}
+
+		hdr, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if mode.IsDir() {
+			hdr.Name += "/"
+		} else if mode.IsRegular() {
+			hdr.Method = zip.Deflate
+		}
+
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case mode.IsRegular():
+			return copyFileTo(fw, p)
+		case mode&fs.ModeSymlink != 0:
+			// zip stores a symlink's target as its contents
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(fw, link)
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func copyFileTo(w io.Writer, p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// unpack extracts an archive file into a directory. The archive can be a
+// tarball, optionally compressed with gzip or zstd, or a zip archive.
+func unpack(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: unpack <src> <dest>")
+	}
+
+	src, dest := args[0], args[1]
+
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	magic, err := br.Peek(4)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		return extractTar(gz, dest)
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		return extractTar(zr, dest)
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")),
+		bytes.HasPrefix(magic, []byte("PK\x05\x06")): // empty archive
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		return extractZip(f, info.Size(), dest)
+	default:
+		return extractTar(br, dest)
+	}
+}
+
+func extractTar(r io.Reader, dest string) error {
+	type dirTime struct {
+		path  string
+		mtime time.Time
+	}
+	var dirs []dirTime
+
+	tr := tar.NewReader(r)
+	for first := true; ; first = false {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if first {
+				// anything that isn't gzip, zstd or zip is assumed to be a tarball
+				return fmt.Errorf("unrecognized archive format: %w", err)
+			}
+			return err
+		}
+
+		target := safeJoin(dest, hdr.Name)
+		perm := hdr.FileInfo().Mode().Perm()
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := mkdirPerm(target, perm); err != nil {
+				return err
+			}
+			dirs = append(dirs, dirTime{target, hdr.ModTime})
+		case tar.TypeReg:
+			if err := writeFile(target, tr, perm); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			_ = os.Remove(target)
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			_ = os.Remove(target)
+			if err := os.Link(safeJoin(dest, hdr.Linkname), target); err != nil {
+				return err
+			}
+		case tar.TypeXGlobalHeader:
+			continue
+		default:
+			return fmt.Errorf("cannot unpack %s: unsupported entry type %q", hdr.Name, hdr.Typeflag)
+		}
+
+		if os.Geteuid() == 0 {
+			if err := os.Lchown(target, hdr.Uid, hdr.Gid); err != nil {
+				return err
+			}
+		}
+
+		if hdr.Typeflag != tar.TypeSymlink && hdr.Typeflag != tar.TypeDir {
+			if err := os.Chtimes(target, hdr.ModTime, hdr.ModTime); err != nil {
+				return err
+			}
+		}
+	}
+
+	// set directory times last, since creating their contents changes them
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Chtimes(dirs[i].path, dirs[i].mtime, dirs[i].mtime); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func extractZip(r io.ReaderAt, size int64, dest string) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+
+	for _, zf := range zr.File {
+		target := safeJoin(dest, zf.Name)
+		mode := zf.Mode()
+
+		perm := mode.Perm()
+		if perm == 0 {
+			// archives created on Windows don't record permissions
+			perm = 0o644
+			if mode.IsDir() {
+				perm = 0o755
+			}
+		}
+
+		if mode.IsDir() {
+			if err := mkdirPerm(target, perm); err != nil {
+				return err
+			}
+			continue
+		}
+
+		rc, err := zf.Open()
+		if err != nil {
+			return err
+		}
+
+		if mode&fs.ModeSymlink != 0 {
+			var link []byte
+			link, err = io.ReadAll(rc)
+			if err == nil {
+				err = os.MkdirAll(filepath.Dir(target), 0o755)
+			}
+			if err == nil {
+				_ = os.Remove(target)
+				err = os.Symlink(string(link), target)
+			}
+		} else {
+			err = writeFile(target, rc, perm)
+			if err == nil {
+				err = os.Chtimes(target, zf.Modified, zf.Modified)
+			}
+		}
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// safeJoin joins an archive entry's name onto dest such that it can't escape
+// it.
+//
+// NB: entries can still be written through symlinks that point elsewhere,
+// but this only ever runs in an otherwise empty container.
+func safeJoin(dest, name string) string {
+	return filepath.Join(dest, filepath.Clean("/"+name))
+}
+
+func mkdirPerm(p string, perm fs.FileMode) error {
+	if err := os.MkdirAll(p, perm); err != nil {
+		return err
+	}
+	// MkdirAll doesn't change existing directories and is subject to umask
+	return os.Chmod(p, perm)
+}
+
+func writeFile(p string, r io.Reader, perm fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		return err
+	}
+
+	if err := f.Chmod(perm); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
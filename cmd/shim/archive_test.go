@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveUnpack(t *testing.T) {
+	t.Parallel()
+
+	src := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "sub", "empty"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "a.txt"), []byte("a"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "sub", "run.sh"), []byte("#!/bin/sh\n"), 0o755))
+	require.NoError(t, os.Symlink("../a.txt", filepath.Join(src, "sub", "link")))
+
+	mtime := time.Date(1985, 10, 26, 8, 15, 0, 0, time.UTC)
+	require.NoError(t, os.Chtimes(filepath.Join(src, "a.txt"), mtime, mtime))
+
+	for _, format := range []string{"tar", "tar.gz", "tar.zst", "zip"} {
+		format := format
+		t.Run(format, func(t *testing.T) {
+			t.Parallel()
+
+			archivePath := filepath.Join(t.TempDir(), "archive."+format)
+			require.NoError(t, archive([]string{format, src, archivePath}))
+
+			dest := t.TempDir()
+			require.NoError(t, unpack([]string{archivePath, dest}))
+
+			contents, err := os.ReadFile(filepath.Join(dest, "a.txt"))
+			require.NoError(t, err)
+			require.Equal(t, "a", string(contents))
+
+			info, err := os.Stat(filepath.Join(dest, "a.txt"))
+			require.NoError(t, err)
+			require.True(t, info.ModTime().Equal(mtime), "mtime %s", info.ModTime())
+
+			info, err = os.Stat(filepath.Join(dest, "sub", "run.sh"))
+			require.NoError(t, err)
+			require.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+
+			link, err := os.Readlink(filepath.Join(dest, "sub", "link"))
+			require.NoError(t, err)
+			require.Equal(t, "../a.txt", link)
+
+			info, err = os.Stat(filepath.Join(dest, "sub", "empty"))
+			require.NoError(t, err)
+			require.True(t, info.IsDir())
+		})
+	}
+}
+
+func TestUnpackContained(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "/out/etc/passwd", safeJoin("/out", "../../etc/passwd"))
+	require.Equal(t, "/out/etc/passwd", safeJoin("/out", "/etc/passwd"))
+}
+
+func TestUnpackUnrecognized(t *testing.T) {
+	t.Parallel()
+
+	archivePath := filepath.Join(t.TempDir(), "not-an-archive")
+	require.NoError(t, os.WriteFile(archivePath, []byte("hello, world! this is not a tarball, nor is it a zip."), 0o644))
+
+	err := unpack([]string{archivePath, t.TempDir()})
+	require.ErrorContains(t, err, "unrecognized archive format")
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+)
+
+// replaceContents replaces every occurrence of a string in a file's contents
+// in place.
+func replaceContents(args []string) error {
+	if len(args) != 3 {
+		return fmt.Errorf("usage: replace <file> <search> <replacement>")
+	}
+
+	file, search, replacement := args[0], args[1], args[2]
+
+	return transformContents(file, func(contents []byte) ([]byte, error) {
+		return bytes.ReplaceAll(contents, []byte(search), []byte(replacement)), nil
+	})
+}
+
+// renderTemplate renders a file's contents as a Go template in place, with the
+// given variables, which must all be set.
+func renderTemplate(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: template <file> <vars>")
+	}
+
+	file := args[0]
+
+	var data map[string]string
+	if err := json.Unmarshal([]byte(args[1]), &data); err != nil {
+		return fmt.Errorf("parse template variables: %w", err)
+	}
+
+	return transformContents(file, func(contents []byte) ([]byte, error) {
+		tmpl, err := template.New(filepath.Base(file)).
+			Option("missingkey=error").
+			Parse(string(contents))
+		if err != nil {
+			return nil, fmt.Errorf("parse template: %w", err)
+		}
+
+		buf := new(bytes.Buffer)
+		if err := tmpl.Execute(buf, data); err != nil {
+			return nil, fmt.Errorf("render template: %w", err)
+		}
+
+		return buf.Bytes(), nil
+	})
+}
+
+// transformContents rewrites a file with its contents transformed by fn. The
+// file is written in place, which keeps its permissions and ownership.
+func transformContents(file string, fn func([]byte) ([]byte, error)) error {
+	contents, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	contents, err = fn(contents)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, contents, 0)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplaceContents(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "greeting.txt")
+	require.NoError(t, os.WriteFile(file, []byte("hello world, hello!\n"), 0o600))
+
+	require.NoError(t, replaceContents([]string{file, "hello", "bye"}))
+
+	contents, err := os.ReadFile(file)
+	require.NoError(t, err)
+	require.Equal(t, "bye world, bye!\n", string(contents))
+
+	info, err := os.Stat(file)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestRenderTemplate(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "greeting.txt")
+	require.NoError(t, os.WriteFile(file, []byte("hello {{.name}}\n"), 0o644))
+
+	require.ErrorContains(t, renderTemplate([]string{file, `{}`}), `map has no entry for key "name"`)
+
+	require.NoError(t, renderTemplate([]string{file, `{"name":"dagger"}`}))
+
+	contents, err := os.ReadFile(file)
+	require.NoError(t, err)
+	require.Equal(t, "hello dagger\n", string(contents))
+}
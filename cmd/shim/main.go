@@ -17,11 +17,13 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
 	"github.com/dagger/dagger/core"
+	"github.com/dagger/dagger/engine/buildkit"
 	"github.com/dagger/dagger/engine/client"
 	"github.com/dagger/dagger/network"
 	"github.com/google/uuid"
@@ -34,6 +36,7 @@ const (
 	metaMountPath = "/.dagger_meta_mount"
 	stdinPath     = metaMountPath + "/stdin"
 	exitCodePath  = metaMountPath + "/exitCode"
+	limitPath     = metaMountPath + "/limitExceeded"
 	runcPath      = "/usr/local/bin/runc"
 	shimPath      = "/_shim"
 )
@@ -41,6 +44,7 @@ const (
 var (
 	stdoutPath = metaMountPath + "/stdout"
 	stderrPath = metaMountPath + "/stderr"
+	outputPath = metaMountPath + "/" + buildkit.ExecOutputMetaFile
 	pipeWg     sync.WaitGroup
 )
 
@@ -84,6 +88,48 @@ func internalCommand() int {
 			return 1
 		}
 		return 0
+	case "archive":
+		if err := archive(args); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	case "unpack":
+		if err := unpack(args); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	case "patch":
+		if err := patch(args); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	case "replace":
+		if err := replaceContents(args); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	case "template":
+		if err := renderTemplate(args); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	case "probe":
+		if err := probe(args); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	case "tunnel":
+		if err := tunnel(args); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
 	default:
 		fmt.Fprintf(os.Stderr, "unknown command: %s\n", cmd)
 		return 1
@@ -184,6 +230,18 @@ func shim() int {
 		}
 	}
 
+	expect := core.ReturnSuccess
+	if val, found := internalEnv("_DAGGER_EXPECT"); found {
+		expect = core.ReturnType(val)
+	}
+
+	var limits core.ResourceLimits
+	if val, found := internalEnv(core.ResourceLimitsEnv); found {
+		if _, err := limits.FromEnv(core.ResourceLimitsEnv + "=" + val); err != nil {
+			panic(err)
+		}
+	}
+
 	currentDirPath := "/"
 	shimFS := os.DirFS(currentDirPath)
 
@@ -219,8 +277,15 @@ func shim() int {
 		stderrRedirect = stderrRedirectFile
 	}
 
-	outWriter := io.MultiWriter(stdoutFile, stdoutRedirect, os.Stdout)
-	errWriter := io.MultiWriter(stderrFile, stderrRedirect, os.Stderr)
+	outputFile, err := os.Create(outputPath)
+	if err != nil {
+		panic(err)
+	}
+	defer outputFile.Close()
+	output := buildkit.NewExecOutputWriter(outputFile)
+
+	outWriter := io.MultiWriter(stdoutFile, stdoutRedirect, output.Stream(progrock.LogStream_STDOUT), os.Stdout)
+	errWriter := io.MultiWriter(stderrFile, stderrRedirect, output.Stream(progrock.LogStream_STDERR), os.Stderr)
 
 	if len(secretsToScrub.Envs) == 0 && len(secretsToScrub.Files) == 0 {
 		cmd.Stdout = outWriter
@@ -258,6 +323,22 @@ func shim() int {
 		}()
 	}
 
+	var timedOut atomic.Bool
+	if limits.Timeout > 0 {
+		timer := time.AfterFunc(time.Duration(limits.Timeout)*time.Second, func() {
+			timedOut.Store(true)
+			if os.Getpid() == 1 {
+				// we're the container's init process, so this kills every
+				// other process in the container, including any that are
+				// holding on to the command's output
+				syscall.Kill(-1, syscall.SIGKILL)
+			} else if cmd.Process != nil {
+				cmd.Process.Kill()
+			}
+		})
+		defer timer.Stop()
+	}
+
 	exitCode := 0
 	if err := runWithNesting(ctx, cmd); err != nil {
 		exitCode = 1
@@ -268,11 +349,79 @@ func shim() int {
 		}
 	}
 
+	var limitExceeded string
+	switch {
+	case timedOut.Load():
+		limitExceeded = core.LimitTimeout
+		fmt.Fprintf(errWriter, "command timed out after %ds\n", limits.Timeout)
+	case exitCode != 0 && limits.Memory != "" && cgroupEventCount("memory.events", "oom_kill") > 0:
+		limitExceeded = core.LimitMemory
+		fmt.Fprintf(errWriter, "command ran out of memory (limit %s)\n", limits.Memory)
+	case exitCode != 0 && limits.Pids > 0 && cgroupEventCount("pids.events", "max") > 0:
+		limitExceeded = core.LimitPids
+		fmt.Fprintf(errWriter, "command reached its limit of %d processes\n", limits.Pids)
+	}
+	if limitExceeded != "" {
+		if err := os.WriteFile(limitPath, []byte(limitExceeded), 0o600); err != nil {
+			panic(err)
+		}
+	}
+
+	status := execStatus(exitCode, expect, limitExceeded != "", errWriter)
+
+	// the exit code file is read for Container.exitCode when the exec
+	// succeeds, and for the exec error when it fails, so when it fails despite
+	// the command succeeding it must hold the status the exec failed with
+	if status != 0 {
+		exitCode = status
+	}
 	if err := os.WriteFile(exitCodePath, []byte(fmt.Sprintf("%d", exitCode)), 0o600); err != nil {
 		panic(err)
 	}
 
-	return exitCode
+	return status
+}
+
+// execStatus returns the status the exec exits with for a command that exited
+// with the given code, given what was expected of it.
+func execStatus(exitCode int, expect core.ReturnType, limitExceeded bool, errWriter io.Writer) int {
+	if limitExceeded {
+		// exceeding a limit always fails, whatever's expected
+		if exitCode == 0 {
+			return 1
+		}
+		return exitCode
+	}
+
+	switch expect {
+	case core.ReturnAny:
+		return 0
+	case core.ReturnFailure:
+		if exitCode == 0 {
+			fmt.Fprintln(errWriter, "expected command to fail, but it succeeded")
+			return 1
+		}
+		return 0
+	default:
+		return exitCode
+	}
+}
+
+// cgroupEventCount returns how many times an event has occurred according to
+// one of the container's cgroup v2 event files, or 0 if it can't be read.
+func cgroupEventCount(file, event string) int {
+	content, err := os.ReadFile(filepath.Join("/sys/fs/cgroup", file))
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		name, val, ok := strings.Cut(line, " ")
+		if ok && name == event {
+			count, _ := strconv.Atoi(val)
+			return count
+		}
+	}
+	return 0
 }
 
 func setupBundle() int {
@@ -362,6 +511,22 @@ func setupBundle() int {
 		}
 	}
 
+	limits := new(core.ResourceLimits)
+	for _, env := range spec.Process.Env {
+		found, err := limits.FromEnv(env)
+		if err != nil {
+			fmt.Printf("Error parsing env: %v\n", err)
+			return 1
+		}
+		if found {
+			break
+		}
+	}
+	if err := limits.Apply(&spec); err != nil {
+		fmt.Printf("Error applying resource limits: %v\n", err)
+		return 1
+	}
+
 	var searchDomains []string
 	for _, parentClientID := range execMetadata.ParentClientIDs {
 		searchDomains = append(searchDomains, network.ClientDomain(parentClientID))
@@ -431,6 +596,11 @@ func setupBundle() int {
 				Source:      execMetadata.ProgSockPath,
 			})
 		case strings.HasPrefix(env, "_DAGGER_SERVER_ID="):
+		case strings.HasPrefix(env, core.ResourceLimitsEnv+"="):
+			// the shim enforces the timeout, so keep it if there is one
+			if isDaggerExec {
+				keepEnv = append(keepEnv, env)
+			}
 		case strings.HasPrefix(env, aliasPrefix):
 			// NB: don't keep this env var, it's only for the bundling step
 			// keepEnv = append(keepEnv, env)
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagger/dagger/core"
+)
+
+func TestExecStatus(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name          string
+		exitCode      int
+		expect        core.ReturnType
+		limitExceeded bool
+		status        int
+		stderr        string
+	}{
+		{name: "success", exitCode: 0, expect: core.ReturnSuccess, status: 0},
+		{name: "unexpected failure", exitCode: 3, expect: core.ReturnSuccess, status: 3},
+		{name: "expected failure", exitCode: 3, expect: core.ReturnFailure, status: 0},
+		{name: "unexpected success", exitCode: 0, expect: core.ReturnFailure, status: 1, stderr: "expected command to fail"},
+		{name: "any", exitCode: 42, expect: core.ReturnAny, status: 0},
+		{name: "limit exceeded", exitCode: 0, expect: core.ReturnAny, limitExceeded: true, status: 1},
+		{name: "limit exceeded with failure", exitCode: 137, expect: core.ReturnAny, limitExceeded: true, status: 137},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			stderr := new(bytes.Buffer)
+			require.Equal(t, tc.status, execStatus(tc.exitCode, tc.expect, tc.limitExceeded, stderr))
+			if tc.stderr != "" {
+				require.Contains(t, stderr.String(), tc.stderr)
+			} else {
+				require.Empty(t, stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dagger/dagger/core"
+)
+
+// patch applies a unified diff to a directory in place. Each change applies to
+// the result of the ones before it, so a patch can change the same file more
+// than once, or rename a file and then change it.
+func patch(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: patch <patch> <dir>")
+	}
+
+	patchPath, dir := args[0], args[1]
+
+	contents, err := os.ReadFile(patchPath)
+	if err != nil {
+		return err
+	}
+
+	files, err := core.ParsePatch(string(contents))
+	if err != nil {
+		return fmt.Errorf("parse patch: %w", err)
+	}
+
+	for _, fp := range files {
+		if err := applyFilePatch(dir, fp); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func applyFilePatch(dir string, fp *core.FilePatch) error {
+	// paths are cleaned as if absolute so they can't escape the directory
+	oldPath := patchTarget(dir, fp.OldPath)
+	newPath := patchTarget(dir, fp.NewPath)
+
+	var orig []byte
+	if fp.OldPath != "" {
+		var err error
+		orig, err = os.ReadFile(oldPath)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("patch %s: file not found", fp.OldPath)
+			}
+			return fmt.Errorf("patch %s: %w", fp.OldPath, err)
+		}
+	}
+
+	if fp.NewPath == "" {
+		return os.Remove(oldPath)
+	}
+
+	patched, err := fp.Apply(string(orig))
+	if err != nil {
+		return err
+	}
+
+	if fp.OldPath == "" {
+		if err := os.MkdirAll(filepath.Dir(newPath), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(newPath, []byte(patched), 0o644); err != nil {
+			return err
+		}
+		// don't let the umask change the permissions
+		return os.Chmod(newPath, 0o644)
+	}
+
+	// writing to the existing file keeps its permissions and ownership, which
+	// renaming it keeps too
+	if err := os.WriteFile(oldPath, []byte(patched), 0); err != nil {
+		return err
+	}
+	if newPath != oldPath {
+		if err := os.MkdirAll(filepath.Dir(newPath), 0o755); err != nil {
+			return err
+		}
+		if err := os.Rename(oldPath, newPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func patchTarget(dir, p string) string {
+	if p == "" {
+		return ""
+	}
+	return filepath.Join(dir, filepath.Clean("/"+p))
+}
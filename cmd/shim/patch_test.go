@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatch(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "numbers.txt"), []byte("one\ntwo\nthree\n"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "old.txt"), []byte("bye\n"), 0o644))
+
+	patchPath := filepath.Join(t.TempDir(), "changes.patch")
+	require.NoError(t, os.WriteFile(patchPath, []byte(`--- a/numbers.txt
++++ b/numbers.txt
+@@ -1,3 +1,3 @@
+ one
+-two
++dos
+ three
+--- a/numbers.txt
++++ b/numbers.txt
+@@ -1,3 +1,3 @@
+-one
++uno
+ dos
+ three
+--- a/numbers.txt
++++ b/sub/numeros.txt
+@@ -1,3 +1,3 @@
+ uno
+ dos
+-three
++tres
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1 @@
++hello
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+`), 0o644))
+
+	require.NoError(t, patch([]string{patchPath, dir}))
+
+	contents, err := os.ReadFile(filepath.Join(dir, "sub", "numeros.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "uno\ndos\ntres\n", string(contents))
+
+	info, err := os.Stat(filepath.Join(dir, "sub", "numeros.txt"))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+
+	contents, err = os.ReadFile(filepath.Join(dir, "new.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "hello\n", string(contents))
+
+	info, err = os.Stat(filepath.Join(dir, "new.txt"))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o644), info.Mode().Perm())
+
+	for _, name := range []string{"numbers.txt", "old.txt"} {
+		_, err = os.Stat(filepath.Join(dir, name))
+		require.ErrorIs(t, err, os.ErrNotExist)
+	}
+}
+
+func TestPatchEscape(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	dir := filepath.Join(root, "dir")
+	require.NoError(t, os.Mkdir(dir, 0o755))
+
+	patchPath := filepath.Join(root, "escape.patch")
+	require.NoError(t, os.WriteFile(patchPath, []byte(`--- /dev/null
++++ b/../escaped.txt
+@@ -0,0 +1 @@
++hello
+`), 0o644))
+
+	require.NoError(t, patch([]string{patchPath, dir}))
+
+	_, err := os.Stat(filepath.Join(root, "escaped.txt"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+	_, err = os.Stat(filepath.Join(dir, "escaped.txt"))
+	require.NoError(t, err)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// probe checks once whether a service is healthy, exiting non-zero if not.
+// Unlike check, it does not retry; the engine decides when to probe again.
+func probe(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: probe tcp <host:port> | probe http <url> <status>")
+	}
+
+	switch kind, target := args[0], args[1]; kind {
+	case "tcp":
+		conn, err := net.Dial("tcp", target)
+		if err != nil {
+			return err
+		}
+		fmt.Println("connected to", conn.RemoteAddr())
+		return conn.Close()
+	case "http":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: probe http <url> <status>")
+		}
+		status, err := strconv.Atoi(args[2])
+		if err != nil {
+			return fmt.Errorf("invalid status %q: %w", args[2], err)
+		}
+		return probeHTTP(target, status)
+	default:
+		return fmt.Errorf("unknown probe: %s", kind)
+	}
+}
+
+func probeHTTP(url string, status int) error {
+	res, err := http.Get(url) //nolint:gosec // the URL is the service's
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != status {
+		return fmt.Errorf("GET %s: expected status %d, got %s", url, status, res.Status)
+	}
+
+	fmt.Printf("GET %s: %s\n", url, res.Status)
+	return nil
+}
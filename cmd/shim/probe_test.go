@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbe(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	require.NoError(t, probe([]string{"tcp", srv.Listener.Addr().String()}))
+	require.NoError(t, probe([]string{"http", srv.URL + "/healthz", "200"}))
+	require.Error(t, probe([]string{"http", srv.URL + "/", "200"}))
+	require.NoError(t, probe([]string{"http", srv.URL + "/", "404"}))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddr := l.Addr().String()
+	require.NoError(t, l.Close())
+	require.Error(t, probe([]string{"tcp", closedAddr}))
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// tunnelForward forwards a port of the container to a Unix socket, which the
+// engine proxies to the host.
+type tunnelForward struct {
+	network string
+	port    int
+	socket  string
+}
+
+// parseTunnelForward parses a forward given as <port>/<tcp|udp>=<socket>.
+func parseTunnelForward(arg string) (tunnelForward, error) {
+	var fwd tunnelForward
+
+	port, sock, ok := strings.Cut(arg, "=")
+	if !ok || sock == "" {
+		return fwd, fmt.Errorf("invalid forward %q: missing socket", arg)
+	}
+	fwd.socket = sock
+
+	port, fwd.network, ok = strings.Cut(port, "/")
+	if !ok {
+		fwd.network = "tcp"
+	}
+	if fwd.network != "tcp" && fwd.network != "udp" {
+		return fwd, fmt.Errorf("invalid forward %q: unknown network %q", arg, fwd.network)
+	}
+
+	var err error
+	fwd.port, err = strconv.Atoi(port)
+	if err != nil {
+		return fwd, fmt.Errorf("invalid forward %q: %w", arg, err)
+	}
+
+	return fwd, nil
+}
+
+// tunnel listens on each of the given ports and forwards what it receives to
+// the corresponding socket, until it's killed.
+func tunnel(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: tunnel <port>/<tcp|udp>=<socket> [...]")
+	}
+
+	eg := new(errgroup.Group)
+	for _, arg := range args {
+		fwd, err := parseTunnelForward(arg)
+		if err != nil {
+			return err
+		}
+
+		addr := fmt.Sprintf(":%d", fwd.port)
+		switch fwd.network {
+		case "tcp":
+			l, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			eg.Go(func() error {
+				return serveTCPTunnel(l, fwd.socket)
+			})
+		case "udp":
+			pc, err := net.ListenPacket("udp", addr)
+			if err != nil {
+				return err
+			}
+			eg.Go(func() error {
+				return serveUDPTunnel(pc, fwd.socket)
+			})
+		}
+
+		fmt.Printf("forwarding %d/%s to %s\n", fwd.port, fwd.network, fwd.socket)
+	}
+
+	return eg.Wait()
+}
+
+func serveTCPTunnel(l net.Listener, sockPath string) error {
+	defer l.Close()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		go func() {
+			defer conn.Close()
+
+			upstream, err := net.Dial("unix", sockPath)
+			if err != nil {
+				fmt.Printf("dial %s: %s\n", sockPath, err)
+				return
+			}
+			defer upstream.Close()
+
+			go func() {
+				io.Copy(upstream, conn)
+				upstream.(*net.UnixConn).CloseWrite()
+			}()
+
+			io.Copy(conn, upstream)
+		}()
+	}
+}
+
+// serveUDPTunnel forwards the packets of each peer over its own connection to
+// the socket. Since the socket is a stream, packets sent in quick succession
+// may arrive merged.
+func serveUDPTunnel(pc net.PacketConn, sockPath string) error {
+	defer pc.Close()
+
+	peers := map[string]net.Conn{}
+	var peersL sync.Mutex
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, peer, err := pc.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		peersL.Lock()
+		upstream, found := peers[peer.String()]
+		if !found {
+			upstream, err = net.Dial("unix", sockPath)
+			if err != nil {
+				peersL.Unlock()
+				fmt.Printf("dial %s: %s\n", sockPath, err)
+				continue
+			}
+			peers[peer.String()] = upstream
+
+			go func() {
+				defer func() {
+					peersL.Lock()
+					delete(peers, peer.String())
+					peersL.Unlock()
+					upstream.Close()
+				}()
+
+				buf := make([]byte, 64*1024)
+				for {
+					n, err := upstream.Read(buf)
+					if err != nil {
+						return
+					}
+					if _, err := pc.WriteTo(buf[:n], peer); err != nil {
+						return
+					}
+				}
+			}()
+		}
+		peersL.Unlock()
+
+		if _, err := upstream.Write(buf[:n]); err != nil {
+			fmt.Printf("forward packet from %s: %s\n", peer, err)
+		}
+	}
+}
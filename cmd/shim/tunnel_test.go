@@ -0,0 +1,76 @@
+package main
+
+import (
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTunnelForward(t *testing.T) {
+	t.Parallel()
+
+	fwd, err := parseTunnelForward("5432=/tunnels/0.sock")
+	require.NoError(t, err)
+	require.Equal(t, tunnelForward{network: "tcp", port: 5432, socket: "/tunnels/0.sock"}, fwd)
+
+	fwd, err = parseTunnelForward("53/udp=/tunnels/1.sock")
+	require.NoError(t, err)
+	require.Equal(t, tunnelForward{network: "udp", port: 53, socket: "/tunnels/1.sock"}, fwd)
+
+	_, err = parseTunnelForward("5432")
+	require.Error(t, err)
+
+	_, err = parseTunnelForward("53/sctp=/tunnels/1.sock")
+	require.Error(t, err)
+
+	_, err = parseTunnelForward("http=/tunnels/0.sock")
+	require.Error(t, err)
+}
+
+func TestServeTCPTunnel(t *testing.T) {
+	t.Parallel()
+
+	sockPath := filepath.Join(t.TempDir(), "echo.sock")
+	sockL, err := net.Listen("unix", sockPath)
+	require.NoError(t, err)
+	defer sockL.Close()
+
+	go func() {
+		for {
+			conn, err := sockL.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- serveTCPTunnel(l, sockPath)
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.NoError(t, conn.(*net.TCPConn).CloseWrite())
+
+	echoed, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(echoed))
+
+	require.NoError(t, l.Close())
+	require.NoError(t, <-served)
+}
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/containerd/containerd/platforms"
+	"github.com/moby/buildkit/client/llb"
+)
+
+// Paths that the shim's internal commands read from and write to, which they
+// take as arguments.
+const (
+	internalSrcMount = "/src"
+	internalOutMount = "/out"
+)
+
+// AsTarball returns a tarball of the directory's contents, compressed with
+// the given compression, which defaults to none.
+func (dir *Directory) AsTarball(ctx context.Context, compression ImageLayerCompression) (*File, error) {
+	var format string
+	switch compression {
+	case "", CompressionUncompressed:
+		format = "tar"
+	case CompressionGzip:
+		format = "tar.gz"
+	case CompressionZstd:
+		format = "tar.zst"
+	default:
+		return nil, fmt.Errorf("unsupported tarball compression: %s", compression)
+	}
+
+	return dir.archive(ctx, format)
+}
+
+// AsZip returns a zip archive of the directory's contents.
+func (dir *Directory) AsZip(ctx context.Context) (*File, error) {
+	return dir.archive(ctx, "zip")
+}
+
+func (dir *Directory) archive(ctx context.Context, format string) (*File, error) {
+	st, err := dir.State()
+	if err != nil {
+		return nil, err
+	}
+
+	name := "directory." + format
+
+	outSt := internalExec(&st, llb.Scratch(), "archive "+name,
+		"archive", format, path.Join(internalSrcMount, dir.Dir), path.Join(internalOutMount, name))
+
+	return NewFileSt(ctx, outSt, name, dir.Pipeline, dir.Platform, dir.Services)
+}
+
+// Unpack returns the contents of the file, which must be a tarball,
+// optionally compressed with gzip or zstd, or a zip archive. The format is
+// detected from the file's contents.
+func (file *File) Unpack(ctx context.Context) (*Directory, error) {
+	st, err := file.State()
+	if err != nil {
+		return nil, err
+	}
+
+	outSt := internalExec(&st, llb.Scratch(), "unpack "+path.Base(file.File),
+		"unpack", path.Join(internalSrcMount, file.File), internalOutMount)
+
+	return NewDirectorySt(ctx, outSt, "/", file.Pipeline, file.Platform, file.Services)
+}
+
+// internalExec runs one of the shim's internal commands in an otherwise empty
+// container with src, if any, mounted read-only and out mounted read-write,
+// returning the state of out after the command. Unlike a container exec, it
+// needs no image, and it runs on the engine's own platform since it's the
+// shim that runs.
+func internalExec(src *llb.State, out llb.State, name string, args ...string) llb.State {
+	opts := []llb.RunOption{
+		llb.Args(args),
+		llb.AddEnv("_DAGGER_INTERNAL_COMMAND", ""),
+		llb.Network(llb.NetModeNone),
+		llb.Platform(platforms.DefaultSpec()),
+		llb.WithCustomName(name),
+	}
+	if src != nil {
+		opts = append(opts, llb.AddMount(internalSrcMount, *src, llb.Readonly))
+	}
+
+	return llb.Scratch().Run(opts...).AddMount(internalOutMount, out)
+}
@@ -0,0 +1,166 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/containerd/containerd/platforms"
+	"github.com/docker/distribution/reference"
+	"github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	slsa02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+	"github.com/moby/buildkit/client/llb"
+	"github.com/moby/buildkit/frontend/attestations/sbom"
+	"github.com/moby/buildkit/solver/result"
+
+	"github.com/dagger/dagger/core/pipeline"
+	"github.com/dagger/dagger/engine"
+	"github.com/dagger/dagger/engine/buildkit"
+)
+
+// SBOMScannerImage is the image that generates SBOMs for published and
+// exported images. It implements buildkit's scanner protocol, so it's the same
+// one that docker buildx uses by default.
+const SBOMScannerImage = "docker/buildkit-syft-scanner:stable-1"
+
+// ProvenanceBuildType identifies the format of the parameters in the
+// provenance attached to published and exported images.
+const ProvenanceBuildType = "https://dagger.io/provenance/container@v1"
+
+// ContainerAttestation is a user-supplied in-toto attestation to attach to a
+// container's image.
+type ContainerAttestation struct {
+	PredicateType string `json:"predicateType"`
+	Predicate     FileID `json:"predicate"`
+}
+
+// WithAttestation returns the container with an attestation to attach to
+// its image when it's published or exported.
+func (container *Container) WithAttestation(ctx context.Context, predicateType string, predicate FileID) (*Container, error) {
+	if predicateType == "" {
+		return nil, fmt.Errorf("attestation predicate type must not be empty")
+	}
+
+	container = container.Clone()
+	container.Attestations = append(container.Attestations, ContainerAttestation{
+		PredicateType: predicateType,
+		Predicate:     predicate,
+	})
+	return container, nil
+}
+
+// imageAttestations returns the attestations to attach to the container's
+// image: any supplied with WithAttestation, and optionally a provenance and an
+// SBOM generated for it.
+func (container *Container) imageAttestations(ctx context.Context, bk *buildkit.Client, provenance, withSBOM bool) ([]buildkit.ContainerAttestation, error) {
+	var atts []buildkit.ContainerAttestation
+
+	for _, att := range container.Attestations {
+		file, err := att.Predicate.ToFile()
+		if err != nil {
+			return nil, err
+		}
+		atts = append(atts, buildkit.ContainerAttestation{
+			PredicateType: att.PredicateType,
+			Definition:    file.LLB,
+			Path:          file.File,
+		})
+	}
+
+	if provenance {
+		content, err := container.provenance()
+		if err != nil {
+			return nil, err
+		}
+		atts = append(atts, buildkit.ContainerAttestation{
+			PredicateType: slsa02.PredicateSLSAProvenance,
+			Content:       content,
+			Metadata: map[string][]byte{
+				result.AttestationReasonKey: []byte(result.AttestationReasonProvenance),
+			},
+		})
+	}
+
+	if withSBOM {
+		// the scanner runs on the engine's platform, whatever the image's is
+		enginePlatform := platforms.DefaultSpec()
+
+		scanner, err := sbom.CreateSBOMScanner(ctx, bk, SBOMScannerImage, llb.ResolveImageConfigOpt{
+			Platform:    &enginePlatform,
+			ResolveMode: llb.ResolveModeDefault.String(),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("resolve SBOM scanner: %w", err)
+		}
+
+		st, err := container.FSState()
+		if err != nil {
+			return nil, err
+		}
+
+		scan, err := scanner(ctx, platforms.Format(container.Platform), st, nil, llb.Platform(enginePlatform))
+		if err != nil {
+			return nil, err
+		}
+
+		def, err := scan.Ref.Marshal(ctx, llb.Platform(enginePlatform))
+		if err != nil {
+			return nil, err
+		}
+
+		atts = append(atts, buildkit.ContainerAttestation{
+			PredicateType: scan.InToto.PredicateType,
+			Definition:    def.ToPB(),
+			Bundle:        true,
+			Metadata:      scan.Metadata,
+		})
+	}
+
+	return atts, nil
+}
+
+type provenanceParameters struct {
+	Pipeline  pipeline.Path `json:"pipeline"`
+	Platform  string        `json:"platform"`
+	BuildArgs []BuildArg    `json:"buildArgs,omitempty"`
+}
+
+// provenance returns an SLSA provenance predicate describing the pipeline
+// that built the container and the image it's based on.
+func (container *Container) provenance() ([]byte, error) {
+	var materials []common.ProvenanceMaterial
+	if container.ImageRef != "" {
+		ref, err := reference.ParseNormalizedNamed(container.ImageRef)
+		if err != nil {
+			return nil, err
+		}
+		if digested, ok := ref.(reference.Canonical); ok {
+			materials = append(materials, common.ProvenanceMaterial{
+				URI: container.ImageRef,
+				Digest: common.DigestSet{
+					digested.Digest().Algorithm().String(): digested.Digest().Encoded(),
+				},
+			})
+		}
+	}
+
+	return json.Marshal(slsa02.ProvenancePredicate{
+		Builder: common.ProvenanceBuilder{
+			ID: "https://dagger.io/engine@" + engine.Version,
+		},
+		BuildType: ProvenanceBuildType,
+		Invocation: slsa02.ProvenanceInvocation{
+			Parameters: provenanceParameters{
+				Pipeline:  container.Pipeline,
+				Platform:  platforms.Format(container.Platform),
+				BuildArgs: container.BuildArgs,
+			},
+		},
+		Materials: materials,
+		Metadata: &slsa02.ProvenanceMetadata{
+			Completeness: slsa02.ProvenanceComplete{
+				Parameters: true,
+			},
+		},
+	})
+}
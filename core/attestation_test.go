@@ -0,0 +1,41 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	slsa02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainerProvenance(t *testing.T) {
+	t.Parallel()
+
+	ctr := &Container{
+		ImageRef: "docker.io/library/alpine:3.17@sha256:124c7d2707904eea7431fffe91522a01e5a861a624ee31d03372cc1d138a3126",
+		Platform: specs.Platform{OS: "linux", Architecture: "amd64"},
+		BuildArgs: []BuildArg{
+			{Name: "FOO", Value: "bar"},
+		},
+	}
+
+	content, err := ctr.provenance()
+	require.NoError(t, err)
+
+	var pred struct {
+		slsa02.ProvenancePredicate
+		Invocation struct {
+			Parameters provenanceParameters `json:"parameters"`
+		} `json:"invocation"`
+	}
+	require.NoError(t, json.Unmarshal(content, &pred))
+
+	require.Equal(t, ProvenanceBuildType, pred.BuildType)
+	require.Equal(t, "linux/amd64", pred.Invocation.Parameters.Platform)
+	require.Equal(t, ctr.BuildArgs, pred.Invocation.Parameters.BuildArgs)
+
+	require.Len(t, pred.Materials, 1)
+	require.Equal(t, ctr.ImageRef, pred.Materials[0].URI)
+	require.Equal(t, "124c7d2707904eea7431fffe91522a01e5a861a624ee31d03372cc1d138a3126", pred.Materials[0].Digest["sha256"])
+}
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"sort"
+
+	"github.com/opencontainers/go-digest"
+)
+
+// Change is a change to a file or subdirectory between two directories.
+type Change struct {
+	Path string     `json:"path"`
+	Kind ChangeKind `json:"kind"`
+
+	// BeforeDigest is the digest of the file or directory before the change,
+	// as reported by its digest ignoring timestamps, or nil if it was added.
+	BeforeDigest *string `json:"beforeDigest"`
+
+	// AfterDigest is the digest of the file or directory after the change, as
+	// reported by its digest ignoring timestamps, or nil if it was deleted.
+	AfterDigest *string `json:"afterDigest"`
+}
+
+// ChangeKind is a string deriving from ChangeKind enum
+type ChangeKind string
+
+const (
+	ChangeKindAdded    ChangeKind = "ADDED"
+	ChangeKindModified ChangeKind = "MODIFIED"
+	ChangeKindDeleted  ChangeKind = "DELETED"
+)
+
+// changeEntry is what's compared of a file or directory to find changes.
+type changeEntry struct {
+	// compared changes when the entry does. A directory's only covers its
+	// own mode and ownership, since changes to what's in it are reported on
+	// their own.
+	compared digest.Digest
+
+	// reported is the entry's digest as reported by File.digest or
+	// Directory.digest, ignoring timestamps.
+	reported digest.Digest
+}
+
+// diffEntries compares the entries of two directories, keyed by path, and
+// returns the changes from before to after, sorted by path.
+func diffEntries(before, after map[string]changeEntry) []Change {
+	changes := []Change{}
+
+	for p, beforeEntry := range before {
+		beforeStr := beforeEntry.reported.String()
+
+		afterEntry, found := after[p]
+		if !found {
+			changes = append(changes, Change{
+				Path:         p,
+				Kind:         ChangeKindDeleted,
+				BeforeDigest: &beforeStr,
+			})
+			continue
+		}
+
+		if afterEntry.compared != beforeEntry.compared {
+			afterStr := afterEntry.reported.String()
+			changes = append(changes, Change{
+				Path:         p,
+				Kind:         ChangeKindModified,
+				BeforeDigest: &beforeStr,
+				AfterDigest:  &afterStr,
+			})
+		}
+	}
+
+	for p, afterEntry := range after {
+		if _, found := before[p]; found {
+			continue
+		}
+
+		afterStr := afterEntry.reported.String()
+		changes = append(changes, Change{
+			Path:        p,
+			Kind:        ChangeKindAdded,
+			AfterDigest: &afterStr,
+		})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes
+}
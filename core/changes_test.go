@@ -0,0 +1,53 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffEntries(t *testing.T) {
+	t.Parallel()
+
+	one := digest.FromString("one")
+	two := digest.FromString("two")
+	file := func(d digest.Digest) changeEntry {
+		return changeEntry{compared: d, reported: d}
+	}
+	strp := func(d digest.Digest) *string {
+		s := d.String()
+		return &s
+	}
+
+	before := map[string]changeEntry{
+		"same.txt":      file(one),
+		"changed.txt":   file(one),
+		"sub/":          {compared: one, reported: one},
+		"sub/gone.txt":  file(two),
+		"sub/stays.txt": file(two),
+		"chmod/":        {compared: one, reported: one},
+		"gone/":         {compared: one, reported: two},
+	}
+	after := map[string]changeEntry{
+		"same.txt":    file(one),
+		"changed.txt": file(two),
+		// the directory's contents changed, which is reported on its own
+		"sub/":          {compared: one, reported: two},
+		"sub/stays.txt": file(two),
+		"added.txt":     file(one),
+		"chmod/":        {compared: two, reported: two},
+		"empty/":        {compared: one, reported: two},
+	}
+
+	require.Equal(t, []Change{
+		{Path: "added.txt", Kind: ChangeKindAdded, AfterDigest: strp(one)},
+		{Path: "changed.txt", Kind: ChangeKindModified, BeforeDigest: strp(one), AfterDigest: strp(two)},
+		{Path: "chmod/", Kind: ChangeKindModified, BeforeDigest: strp(one), AfterDigest: strp(two)},
+		{Path: "empty/", Kind: ChangeKindAdded, AfterDigest: strp(two)},
+		{Path: "gone/", Kind: ChangeKindDeleted, BeforeDigest: strp(two)},
+		{Path: "sub/gone.txt", Kind: ChangeKindDeleted, BeforeDigest: strp(two)},
+	}, diffEntries(before, after))
+
+	require.Empty(t, diffEntries(before, before))
+}
@@ -2,12 +2,14 @@ package core
 
 import (
 	"context"
+	"crypto"
 	"encoding/json"
 	"fmt"
 	"io/fs"
 	"os"
 	"path"
 	"path/filepath"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
@@ -21,6 +23,7 @@ import (
 	"github.com/docker/distribution/reference"
 	"github.com/moby/buildkit/client/llb"
 	"github.com/moby/buildkit/exporter/containerimage/exptypes"
+	dockerspec "github.com/moby/buildkit/exporter/containerimage/image"
 	"github.com/moby/buildkit/frontend/dockerui"
 	bkgw "github.com/moby/buildkit/frontend/gateway/client"
 	"github.com/moby/buildkit/solver/pb"
@@ -44,8 +47,19 @@ type Container struct {
 	// The container's root filesystem.
 	FS *pb.Definition `json:"fs"`
 
+	// The root filesystem as of the last layer boundary, which later changes
+	// are grouped into one layer on top of when a new boundary is set.
+	LayerBase *pb.Definition `json:"layer_base,omitempty"`
+
 	// Image configuration (env, workdir, etc)
-	Config specs.ImageConfig `json:"cfg"`
+	Config dockerspec.ImageConfig `json:"cfg"`
+
+	// History of the image's layers, as recorded in its config.
+	History []specs.History `json:"history,omitempty"`
+
+	// Layers of the image the container was imported from, if it hasn't
+	// changed since.
+	Layers []ImageLayer `json:"layers,omitempty"`
 
 	// Pipeline
 	Pipeline pipeline.Path `json:"pipeline"`
@@ -74,6 +88,15 @@ type Container struct {
 	// Services to start before running the container.
 	Services ServiceBindings `json:"services,omitempty"`
 
+	// Resource limits applied to every exec in the container.
+	Limits *ResourceLimits `json:"limits,omitempty"`
+
+	// Build arguments the container was built with, recorded for provenance.
+	BuildArgs []BuildArg `json:"build_args,omitempty"`
+
+	// Attestations to attach to the image when it's published or exported.
+	Attestations []ContainerAttestation `json:"attestations,omitempty"`
+
 	// Focused indicates whether subsequent operations will be
 	// focused, i.e. shown more prominently in the UI.
 	Focused bool `json:"focused"`
@@ -101,12 +124,27 @@ func (container *Container) Clone() *Container {
 	cp.Config.Cmd = cloneSlice(cp.Config.Cmd)
 	cp.Config.Volumes = cloneMap(cp.Config.Volumes)
 	cp.Config.Labels = cloneMap(cp.Config.Labels)
+	cp.Config.OnBuild = cloneSlice(cp.Config.OnBuild)
+	cp.Config.Shell = cloneSlice(cp.Config.Shell)
+	if cp.Config.Healthcheck != nil {
+		healthcheck := *cp.Config.Healthcheck
+		healthcheck.Test = cloneSlice(healthcheck.Test)
+		cp.Config.Healthcheck = &healthcheck
+	}
+	cp.History = cloneSlice(cp.History)
+	cp.Layers = cloneSlice(cp.Layers)
 	cp.Mounts = cloneSlice(cp.Mounts)
 	cp.Secrets = cloneSlice(cp.Secrets)
 	cp.Sockets = cloneSlice(cp.Sockets)
 	cp.Ports = cloneSlice(cp.Ports)
 	cp.Services = cloneSlice(cp.Services)
 	cp.Pipeline = cloneSlice(cp.Pipeline)
+	cp.BuildArgs = cloneSlice(cp.BuildArgs)
+	cp.Attestations = cloneSlice(cp.Attestations)
+	if cp.Limits != nil {
+		limits := *cp.Limits
+		cp.Limits = &limits
+	}
 	return &cp
 }
 
@@ -272,7 +310,7 @@ func (mnts ContainerMounts) With(newMnt ContainerMount) ContainerMounts {
 	return mntsCp
 }
 
-func (container *Container) From(ctx context.Context, bk *buildkit.Client, addr string) (*Container, error) {
+func (container *Container) From(ctx context.Context, bk *buildkit.Client, addr string, pullPolicy ImagePullPolicy) (*Container, error) {
 	container = container.Clone()
 
 	platform := container.Platform
@@ -288,20 +326,43 @@ func (container *Container) From(ctx context.Context, bk *buildkit.Client, addr
 
 	ref := reference.TagNameOnly(refName).String()
 
-	_, digest, cfgBytes, err := bk.ResolveImageConfig(ctx, ref, llb.ResolveImageConfigOpt{
-		Platform:    &platform,
-		ResolveMode: llb.ResolveModeDefault.String(),
-	})
+	digest, cfgBytes, found, err := bk.PulledImageConfig(ctx, ref, platform)
 	if err != nil {
 		return nil, err
 	}
+	switch pullPolicy {
+	case PullNever:
+		if !found {
+			return nil, fmt.Errorf("image %s has not been pulled by the engine, and the pull policy is %s", ref, pullPolicy)
+		}
+	case PullIfNotPresent:
+	case PullAlways, "":
+		found = false
+	default:
+		return nil, fmt.Errorf("unknown pull policy %q", pullPolicy)
+	}
+
+	resolveMode := llb.ResolveModeDefault
+	if pullPolicy == PullAlways {
+		resolveMode = llb.ResolveModeForcePull
+	}
+
+	if !found {
+		_, digest, cfgBytes, err = bk.ResolveImageConfig(ctx, ref, llb.ResolveImageConfigOpt{
+			Platform:    &platform,
+			ResolveMode: resolveMode.String(),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	digested, err := reference.WithDigest(refName, digest)
 	if err != nil {
 		return nil, err
 	}
 
-	var imgSpec specs.Image
+	var imgSpec dockerspec.Image
 	if err := json.Unmarshal(cfgBytes, &imgSpec); err != nil {
 		return nil, err
 	}
@@ -309,6 +370,7 @@ func (container *Container) From(ctx context.Context, bk *buildkit.Client, addr
 	fsSt := llb.Image(
 		digested.String(),
 		llb.WithCustomNamef("pull %s", ref),
+		resolveMode,
 	)
 
 	def, err := fsSt.Marshal(ctx, llb.Platform(container.Platform))
@@ -317,12 +379,16 @@ func (container *Container) From(ctx context.Context, bk *buildkit.Client, addr
 	}
 
 	container.FS = def.ToPB()
+	container.LayerBase = container.FS
 
 	// associate vertexes to the 'from' sub-pipeline
 	buildkit.RecordVertexes(subRecorder, container.FS)
 
 	container.Config = mergeImageConfig(container.Config, imgSpec.Config)
+	container.History = imgSpec.History
 	container.ImageRef = digested.String()
+	// fetched from the registry when asked for
+	container.Layers = nil
 
 	return container, nil
 }
@@ -336,6 +402,10 @@ func (container *Container) Build(
 	buildArgs []BuildArg,
 	target string,
 	secrets []SecretID,
+	buildContexts []BuildContext,
+	sshSocket socket.ID,
+	cacheFrom []string,
+	network string,
 	bk *buildkit.Client,
 	svcs *Services,
 	buildCache *CacheMap[uint64, *Container],
@@ -353,13 +423,17 @@ func (container *Container) Build(
 			buildArgs,
 			target,
 			secrets,
+			buildContexts,
+			sshSocket,
+			cacheFrom,
+			network,
 			// scope cache per-client to avoid sharing caches across builds that are
 			// structurally similar but use different client-specific inputs (i.e.
 			// local dir with same path but different content)
 			clientMetadata.ClientID,
 		),
 		func() (*Container, error) {
-			return container.buildUncached(ctx, bk, context, dockerfile, buildArgs, target, secrets, svcs)
+			return container.buildUncached(ctx, bk, context, dockerfile, buildArgs, target, secrets, buildContexts, sshSocket, cacheFrom, network, svcs)
 		},
 	)
 }
@@ -372,6 +446,10 @@ func (container *Container) buildUncached(
 	buildArgs []BuildArg,
 	target string,
 	secrets []SecretID,
+	buildContexts []BuildContext,
+	sshSocket socket.ID,
+	cacheFrom []string,
+	network string,
 	svcs *Services,
 ) (*Container, error) {
 	container = container.Clone()
@@ -392,16 +470,13 @@ func (container *Container) buildUncached(
 
 	// set image ref to empty string
 	container.ImageRef = ""
+	container.Layers = nil
+
+	container.BuildArgs = cloneSlice(buildArgs)
 
 	// add a weak group for the docker build vertices
 	ctx, subRecorder := progrock.WithGroup(ctx, "docker build", progrock.Weak())
 
-	detach, _, err := svcs.StartBindings(ctx, bk, container.Services)
-	if err != nil {
-		return nil, err
-	}
-	defer detach()
-
 	platform := container.Platform
 
 	opts := map[string]string{
@@ -423,11 +498,81 @@ func (container *Container) buildUncached(
 		opts["build-arg:"+buildArg.Name] = buildArg.Value
 	}
 
+	switch network {
+	case "", "default":
+	case "none", "host":
+		opts["force-network-mode"] = network
+	default:
+		return nil, fmt.Errorf("unknown build network mode %q (must be default, none or host)", network)
+	}
+
+	if len(cacheFrom) > 0 {
+		cacheImports := make([]bkgw.CacheOptionsEntry, len(cacheFrom))
+		for i, ref := range cacheFrom {
+			cacheImports[i] = bkgw.CacheOptionsEntry{
+				Type:  "registry",
+				Attrs: map[string]string{"ref": ref},
+			}
+		}
+		cacheImportsJSON, err := json.Marshal(cacheImports)
+		if err != nil {
+			return nil, err
+		}
+		opts["cache-imports"] = string(cacheImportsJSON)
+	}
+
 	inputs := map[string]*pb.Definition{
 		dockerui.DefaultLocalNameContext:    context.LLB,
 		dockerui.DefaultLocalNameDockerfile: context.LLB,
 	}
 
+	for _, buildContext := range buildContexts {
+		if buildContext.Name == "" {
+			return nil, errors.New("build context name must not be empty")
+		}
+		key := "context:" + buildContext.Name
+		if _, ok := opts[key]; ok {
+			return nil, fmt.Errorf("duplicate build context %q", buildContext.Name)
+		}
+
+		switch {
+		case buildContext.Directory != "" && buildContext.Image != "":
+			return nil, fmt.Errorf("build context %q must have either a directory or an image, not both", buildContext.Name)
+		case buildContext.Directory != "":
+			dir, err := buildContext.Directory.ToDirectory()
+			if err != nil {
+				return nil, err
+			}
+			dirSt, err := dir.StateWithSourcePath()
+			if err != nil {
+				return nil, err
+			}
+			dirDef, err := dirSt.Marshal(ctx, llb.Platform(dir.Platform))
+			if err != nil {
+				return nil, err
+			}
+			inputName := "dagger-context-" + buildContext.Name
+			inputs[inputName] = dirDef.ToPB()
+			opts[key] = "input:" + inputName
+			container.Services.Merge(dir.Services)
+		case buildContext.Image != "":
+			ref, err := lockedImageRef(ctx, bk, buildContext.Image, platform)
+			if err != nil {
+				return nil, err
+			}
+			opts[key] = "docker-image://" + ref
+		default:
+			return nil, fmt.Errorf("build context %q must have a directory or an image", buildContext.Name)
+		}
+	}
+
+	// start the services once the build contexts have added theirs
+	detach, _, err := svcs.StartBindings(ctx, bk, container.Services)
+	if err != nil {
+		return nil, err
+	}
+	defer detach()
+
 	res, err := bk.Solve(ctx, bkgw.SolveRequest{
 		Frontend:       "dockerfile.v0",
 		FrontendOpt:    opts,
@@ -457,25 +602,96 @@ func (container *Container) buildUncached(
 		return nil, err
 	}
 
+	fsDef := def.ToPB()
+	if sshSocket != "" {
+		fsDef, err = forwardSSHMounts(fsDef, sshSocket)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// associate vertexes to the 'docker build' sub-pipeline
-	buildkit.RecordVertexes(subRecorder, def.ToPB())
+	buildkit.RecordVertexes(subRecorder, fsDef)
 
-	container.FS = def.ToPB()
+	container.FS = fsDef
 	container.FS.Source = nil
+	container.LayerBase = container.FS
 
 	cfgBytes, found := res.Metadata[exptypes.ExporterImageConfigKey]
 	if found {
-		var imgSpec specs.Image
+		var imgSpec dockerspec.Image
 		if err := json.Unmarshal(cfgBytes, &imgSpec); err != nil {
 			return nil, err
 		}
 
 		container.Config = mergeImageConfig(container.Config, imgSpec.Config)
+		container.History = imgSpec.History
 	}
 
 	return container, nil
 }
 
+// lockedImageRef pins an image reference to the digest the session's image
+// lock pins it to, recording the digest it resolves to if it isn't pinned
+// yet. The Dockerfile frontend resolves images itself, so without this, image
+// build contexts would bypass the lock.
+func lockedImageRef(ctx context.Context, bk *buildkit.Client, ref string, platform specs.Platform) (string, error) {
+	if bk.ImageLock == nil {
+		return ref, nil
+	}
+
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := named.(reference.Canonical); ok {
+		// already pinned
+		return ref, nil
+	}
+	named = reference.TagNameOnly(named)
+
+	_, dgst, _, err := bk.ResolveImageConfig(ctx, named.String(), llb.ResolveImageConfigOpt{
+		Platform:    &platform,
+		ResolveMode: llb.ResolveModeDefault.String(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	digested, err := reference.WithDigest(named, dgst)
+	if err != nil {
+		return "", err
+	}
+	return digested.String(), nil
+}
+
+// forwardSSHMounts points every SSH mount in the definition, such as those of
+// a Dockerfile's RUN --mount=type=ssh instructions, at the given socket.
+func forwardSSHMounts(def *pb.Definition, sshSocket socket.ID) (*pb.Definition, error) {
+	dag, err := defToDAG(def)
+	if err != nil {
+		return nil, err
+	}
+
+	err = dag.Walk(func(dag *opDAG) error {
+		exec, ok := dag.AsExec()
+		if !ok {
+			return nil
+		}
+		for _, mnt := range exec.Mounts {
+			if mnt.MountType == pb.MountType_SSH && mnt.SSHOpt != nil {
+				mnt.SSHOpt.ID = string(sshSocket)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dag.Marshal()
+}
+
 func (container *Container) RootFS(ctx context.Context) (*Directory, error) {
 	return &Directory{
 		LLB:      container.FS,
@@ -487,6 +703,19 @@ func (container *Container) RootFS(ctx context.Context) (*Directory, error) {
 }
 
 func (container *Container) WithRootFS(ctx context.Context, dir *Directory) (*Container, error) {
+	container, err := container.withRootFS(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// an explicitly set root filesystem starts a new layer, unlike the writes
+	// to it made by withFile, withDirectory and the like
+	container.LayerBase = container.FS
+
+	return container, nil
+}
+
+func (container *Container) withRootFS(ctx context.Context, dir *Directory) (*Container, error) {
 	container = container.Clone()
 
 	dirSt, err := dir.StateWithSourcePath()
@@ -505,10 +734,109 @@ func (container *Container) WithRootFS(ctx context.Context, dir *Directory) (*Co
 
 	// set image ref to empty string
 	container.ImageRef = ""
+	container.Layers = nil
+
+	return container, nil
+}
+
+// Squash collapses the container's root filesystem into a single layer.
+func (container *Container) Squash(ctx context.Context) (*Container, error) {
+	container = container.Clone()
+
+	if container.FS == nil {
+		// nothing to squash
+		return container, nil
+	}
+
+	fsSt, err := container.FSState()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := container.setLayeredFS(ctx, squashState(fsSt)); err != nil {
+		return nil, err
+	}
+
+	// the image's history must account for each of its layers
+	container.History = []specs.History{layerHistory("squash")}
+
+	return container, nil
+}
+
+// WithLayerBoundary groups the changes to the container's root filesystem
+// since the last boundary, or since the image it was created from, into a
+// single layer.
+func (container *Container) WithLayerBoundary(ctx context.Context) (*Container, error) {
+	if container.LayerBase == nil {
+		return container.Squash(ctx)
+	}
+
+	container = container.Clone()
+
+	if reflect.DeepEqual(container.FS, container.LayerBase) {
+		// nothing changed since the last boundary
+		return container, nil
+	}
+
+	fsSt, err := container.FSState()
+	if err != nil {
+		return nil, err
+	}
+
+	baseSt, err := defToState(container.LayerBase)
+	if err != nil {
+		return nil, err
+	}
+
+	// Diff against a copy of the base rather than the base itself: Buildkit
+	// reuses the layers in between when the lower state is an ancestor of the
+	// upper one, but computes a single layer otherwise.
+	changes := llb.Diff(squashState(baseSt), fsSt, llb.WithCustomName(buildkit.InternalPrefix+"diff"))
+
+	st := llb.Merge([]llb.State{baseSt, changes}, llb.WithCustomName(buildkit.InternalPrefix+"merge"))
+	if err := container.setLayeredFS(ctx, st); err != nil {
+		return nil, err
+	}
+
+	container.History = append(container.History, layerHistory("layer boundary"))
 
 	return container, nil
 }
 
+// setLayeredFS sets the container's root filesystem to a state whose layers
+// were just regrouped, marking it as the next layer boundary.
+func (container *Container) setLayeredFS(ctx context.Context, st llb.State) error {
+	def, err := st.Marshal(ctx, llb.Platform(container.Platform))
+	if err != nil {
+		return err
+	}
+
+	container.FS = def.ToPB()
+	container.LayerBase = container.FS
+
+	// set image ref to empty string
+	container.ImageRef = ""
+	container.Layers = nil
+
+	return nil
+}
+
+// layerHistory returns the history entry of a layer regrouped by the given
+// operation.
+func layerHistory(op string) specs.History {
+	return specs.History{
+		CreatedBy: "dagger " + op,
+	}
+}
+
+// squashState copies the state's contents into a single layer.
+func squashState(st llb.State) llb.State {
+	return llb.Scratch().File(
+		llb.Copy(st, "/", "/", &llb.CopyInfo{CopyDirContentsOnly: true}),
+		llb.WithCustomName(buildkit.InternalPrefix+"squash"),
+	)
+}
+
 func (container *Container) WithDirectory(ctx context.Context, bk *buildkit.Client, subdir string, src *Directory, filter CopyFilter, owner string) (*Container, error) {
 	container = container.Clone()
 
@@ -608,6 +936,7 @@ func (container *Container) WithMountedCache(ctx context.Context, bk *buildkit.C
 
 	// set image ref to empty string
 	container.ImageRef = ""
+	container.Layers = nil
 
 	SeenCacheKeys.Store(cache.Keys[0], struct{}{})
 
@@ -626,6 +955,7 @@ func (container *Container) WithMountedTemp(ctx context.Context, target string)
 
 	// set image ref to empty string
 	container.ImageRef = ""
+	container.Layers = nil
 
 	return container, nil
 }
@@ -654,6 +984,7 @@ func (container *Container) WithMountedSecret(ctx context.Context, bk *buildkit.
 
 	// set image ref to empty string
 	container.ImageRef = ""
+	container.Layers = nil
 
 	return container, nil
 }
@@ -679,6 +1010,7 @@ func (container *Container) WithoutMount(ctx context.Context, target string) (*C
 
 	// set image ref to empty string
 	container.ImageRef = ""
+	container.Layers = nil
 
 	return container, nil
 }
@@ -728,6 +1060,7 @@ func (container *Container) WithUnixSocket(ctx context.Context, bk *buildkit.Cli
 
 	// set image ref to empty string
 	container.ImageRef = ""
+	container.Layers = nil
 
 	return container, nil
 }
@@ -746,6 +1079,7 @@ func (container *Container) WithoutUnixSocket(ctx context.Context, target string
 
 	// set image ref to empty string
 	container.ImageRef = ""
+	container.Layers = nil
 
 	return container, nil
 }
@@ -765,6 +1099,7 @@ func (container *Container) WithSecretVariable(ctx context.Context, name string,
 
 	// set image ref to empty string
 	container.ImageRef = ""
+	container.Layers = nil
 
 	return container, nil
 }
@@ -890,6 +1225,7 @@ func (container *Container) withMounted(
 
 	// set image ref to empty string
 	container.ImageRef = ""
+	container.Layers = nil
 
 	return container, nil
 }
@@ -993,17 +1329,17 @@ func (container *Container) writeToPath(ctx context.Context, bk *buildkit.Client
 			return nil, err
 		}
 
-		return container.WithRootFS(ctx, root)
+		return container.withRootFS(ctx, root)
 	}
 
 	return container.withMounted(ctx, bk, mount.Target, dir.LLB, mount.SourcePath, nil, "")
 }
 
-func (container *Container) ImageConfig(ctx context.Context) (specs.ImageConfig, error) {
+func (container *Container) ImageConfig(ctx context.Context) (dockerspec.ImageConfig, error) {
 	return container.Config, nil
 }
 
-func (container *Container) UpdateImageConfig(ctx context.Context, updateFn func(specs.ImageConfig) specs.ImageConfig) (*Container, error) {
+func (container *Container) UpdateImageConfig(ctx context.Context, updateFn func(dockerspec.ImageConfig) dockerspec.ImageConfig) (*Container, error) {
 	container = container.Clone()
 	container.Config = updateFn(container.Config)
 	return container, nil
@@ -1021,6 +1357,25 @@ func (container *Container) WithPipeline(ctx context.Context, name, description
 	return container, nil
 }
 
+// WithResourceLimits sets the resource limits of every subsequent exec in the
+// container, including when it's run as a service. Limits that aren't set are
+// left as they were.
+func (container *Container) WithResourceLimits(limits ResourceLimits) (*Container, error) {
+	container = container.Clone()
+
+	merged := limits
+	if container.Limits != nil {
+		merged = container.Limits.Merge(limits)
+	}
+	if err := merged.Validate(); err != nil {
+		return nil, err
+	}
+
+	container.Limits = &merged
+
+	return container, nil
+}
+
 func (container *Container) WithExec(ctx context.Context, bk *buildkit.Client, progSock string, defaultPlatform specs.Platform, opts ContainerExecOpts) (*Container, error) { //nolint:gocyclo
 	container = container.Clone()
 
@@ -1074,6 +1429,32 @@ func (container *Container) WithExec(ctx context.Context, bk *buildkit.Client, p
 	runOpts = append(runOpts,
 		llb.AddMount(buildkit.MetaMountDestPath, metaSt, llb.SourcePath(metaSourcePath)))
 
+	var limits ResourceLimits
+	if container.Limits != nil {
+		limits = *container.Limits
+	}
+	if opts.Limits != nil {
+		limits = limits.Merge(*opts.Limits)
+	}
+	if !limits.IsZero() {
+		if err := limits.Validate(); err != nil {
+			return nil, err
+		}
+		limitsOpt, err := limits.ToLLBRunOpt()
+		if err != nil {
+			return nil, err
+		}
+		runOpts = append(runOpts, limitsOpt)
+	}
+
+	switch opts.Expect {
+	case "", ReturnSuccess:
+	case ReturnFailure, ReturnAny:
+		runOpts = append(runOpts, llb.AddEnv("_DAGGER_EXPECT", string(opts.Expect)))
+	default:
+		return nil, fmt.Errorf("unknown expected return type %q", opts.Expect)
+	}
+
 	if opts.RedirectStdout != "" {
 		runOpts = append(runOpts, llb.AddEnv("_DAGGER_REDIRECT_STDOUT", opts.RedirectStdout))
 	}
@@ -1260,6 +1641,7 @@ func (container *Container) WithExec(ctx context.Context, bk *buildkit.Client, p
 
 	// set image ref to empty string
 	container.ImageRef = ""
+	container.Layers = nil
 
 	return container, nil
 }
@@ -1292,6 +1674,27 @@ func (container *Container) Evaluate(ctx context.Context, bk *buildkit.Client, s
 	return err
 }
 
+// ExecVertex returns the ID of the progress vertex that the output of the
+// container's last exec is logged under.
+func (container *Container) ExecVertex() (digest.Digest, error) {
+	if container.FS == nil || container.Meta == nil {
+		return "", ErrContainerNoExec
+	}
+
+	dag, err := defToDAG(container.FS)
+	if err != nil {
+		return "", err
+	}
+	if dag.GetOp() == nil && len(dag.inputs) == 1 {
+		dag = dag.inputs[0]
+	}
+	if _, ok := dag.AsExec(); !ok {
+		return "", fmt.Errorf("%w: expected exec op, got %T", ErrContainerNoExec, dag.GetOp())
+	}
+
+	return *dag.opDigest, nil
+}
+
 func (container *Container) MetaFileContents(ctx context.Context, bk *buildkit.Client, svcs *Services, progSock string, filePath string) (string, error) {
 	if container.Meta == nil {
 		ctr, err := container.WithExec(ctx, bk, progSock, container.Platform, ContainerExecOpts{})
@@ -1318,6 +1721,22 @@ func (container *Container) MetaFileContents(ctx context.Context, bk *buildkit.C
 	return string(content), nil
 }
 
+// ExitCode returns the exit code of the container's last exec, running the
+// container's default command first if nothing has been executed yet.
+func (container *Container) ExitCode(ctx context.Context, bk *buildkit.Client, svcs *Services, progSock string) (int, error) {
+	content, err := container.MetaFileContents(ctx, bk, svcs, progSock, "exitCode")
+	if err != nil {
+		return 0, err
+	}
+
+	exitCode, err := strconv.Atoi(strings.TrimSpace(content))
+	if err != nil {
+		return 0, fmt.Errorf("invalid exit code %q: %w", content, err)
+	}
+
+	return exitCode, nil
+}
+
 func (container *Container) Publish(
 	ctx context.Context,
 	bk *buildkit.Client,
@@ -1326,7 +1745,97 @@ func (container *Container) Publish(
 	platformVariants []ContainerID,
 	forcedCompression ImageLayerCompression,
 	mediaTypes ImageMediaTypes,
+	provenance bool,
+	sbom bool,
+	signer crypto.Signer,
 ) (string, error) {
+	published, err := container.publish(ctx, bk, svcs, []string{ref}, platformVariants, forcedCompression, mediaTypes, provenance, sbom, signer)
+	if err != nil {
+		return "", err
+	}
+	if published[0].err != nil {
+		return "", published[0].err
+	}
+	return published[0].ref, nil
+}
+
+// PublishResult is the outcome of publishing an image to one address.
+type PublishResult struct {
+	Address string `json:"address"`
+	// Digest is the digest of the published image, or empty if publishing
+	// failed.
+	Digest string `json:"digest"`
+	// Error is why publishing failed, if it did.
+	Error *string `json:"error"`
+}
+
+// PublishAll publishes the image to each of the given addresses, only
+// building it once, and returns the outcome for each address in order.
+//
+// The pushes aren't atomic: if pushing to some addresses fails, the image is
+// still pushed to the rest, and the failures are reported in their results.
+// An error is only returned if the image couldn't be published anywhere, and
+// summarizes the failures.
+func (container *Container) PublishAll(
+	ctx context.Context,
+	bk *buildkit.Client,
+	svcs *Services,
+	addresses []string,
+	platformVariants []ContainerID,
+	forcedCompression ImageLayerCompression,
+	mediaTypes ImageMediaTypes,
+	provenance bool,
+	sbom bool,
+	signer crypto.Signer,
+) ([]PublishResult, error) {
+	if len(addresses) == 0 {
+		return nil, errors.New("no addresses to publish to")
+	}
+
+	published, err := container.publish(ctx, bk, svcs, addresses, platformVariants, forcedCompression, mediaTypes, provenance, sbom, signer)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]PublishResult, len(published))
+	var failed []string
+	for i, p := range published {
+		results[i] = PublishResult{Address: p.address}
+		if p.err != nil {
+			errStr := p.err.Error()
+			results[i].Error = &errStr
+			failed = append(failed, fmt.Sprintf("%s: %s", p.address, p.err))
+			continue
+		}
+		results[i].Digest = p.digest.String()
+	}
+	if len(failed) == len(published) {
+		return nil, fmt.Errorf("failed to publish to all %d addresses:\n%s", len(addresses), strings.Join(failed, "\n"))
+	}
+
+	return results, nil
+}
+
+type publishedImage struct {
+	address string
+	// ref is the address with the digest of the published image, if any
+	ref    string
+	digest digest.Digest
+	err    error
+}
+
+func (container *Container) publish(
+	ctx context.Context,
+	bk *buildkit.Client,
+	svcs *Services,
+	addresses []string,
+	platformVariants []ContainerID,
+	forcedCompression ImageLayerCompression,
+	mediaTypes ImageMediaTypes,
+	provenance bool,
+	sbom bool,
+	signer crypto.Signer,
+) ([]publishedImage, error) {
 	if mediaTypes == "" {
 		// Modern registry implementations support oci types and docker daemons
 		// have been capable of pulling them since 2018:
@@ -1335,46 +1844,60 @@ func (container *Container) Publish(
 		mediaTypes = OCIMediaTypes
 	}
 
+	refNames := make([]reference.Named, len(addresses))
+	for i, address := range addresses {
+		refName, err := reference.ParseNormalizedNamed(address)
+		if err != nil {
+			return nil, err
+		}
+		refNames[i] = refName
+	}
+
 	inputByPlatform := map[string]buildkit.ContainerExport{}
 	id, err := container.ID()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	services := ServiceBindings{}
 	for _, variantID := range append([]ContainerID{id}, platformVariants...) {
 		variant, err := variantID.ToContainer()
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		if variant.FS == nil {
 			continue
 		}
 		st, err := variant.FSState()
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		def, err := st.Marshal(ctx, llb.Platform(variant.Platform))
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 
 		platformString := platforms.Format(variant.Platform)
 		if _, ok := inputByPlatform[platformString]; ok {
-			return "", fmt.Errorf("duplicate platform %q", platformString)
+			return nil, fmt.Errorf("duplicate platform %q", platformString)
+		}
+		attestations, err := variant.imageAttestations(ctx, bk, provenance, sbom)
+		if err != nil {
+			return nil, err
 		}
 		inputByPlatform[platforms.Format(variant.Platform)] = buildkit.ContainerExport{
-			Definition: def.ToPB(),
-			Config:     variant.Config,
+			Definition:   def.ToPB(),
+			Config:       variant.Config,
+			History:      variant.History,
+			Attestations: attestations,
 		}
 		services.Merge(variant.Services)
 	}
 	if len(inputByPlatform) == 0 {
 		// Could also just ignore and do nothing, airing on side of error until proven otherwise.
-		return "", errors.New("no containers to export")
+		return nil, errors.New("no containers to export")
 	}
 
 	opts := map[string]string{
-		string(exptypes.OptKeyName):     ref,
 		string(exptypes.OptKeyPush):     strconv.FormatBool(true),
 		string(exptypes.OptKeyOCITypes): strconv.FormatBool(mediaTypes == OCIMediaTypes),
 	}
@@ -1385,36 +1908,62 @@ func (container *Container) Publish(
 
 	detach, _, err := svcs.StartBindings(ctx, bk, services)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer detach()
 
-	resp, err := bk.PublishContainerImage(ctx, inputByPlatform, opts)
+	resps, err := bk.PublishContainerImages(ctx, inputByPlatform, addresses, opts)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	refName, err := reference.ParseNormalizedNamed(ref)
-	if err != nil {
-		return "", err
-	}
+	// signatures are stored per repository, so tags of the same one share them
+	signed := map[string]bool{}
+
+	published := make([]publishedImage, len(resps))
+	for i, resp := range resps {
+		p := &published[i]
+		p.address = resp.Name
+		p.ref = resp.Name
+		if resp.Err != nil {
+			p.err = resp.Err
+			continue
+		}
+
+		refName := refNames[i]
+
+		imageDigest, found := resp.Response[exptypes.ExporterImageDigestKey]
+		if !found {
+			if signer != nil {
+				p.err = fmt.Errorf("cannot sign %s: no digest returned by push", resp.Name)
+			}
+			continue
+		}
 
-	imageDigest, found := resp[exptypes.ExporterImageDigestKey]
-	if found {
 		dig, err := digest.Parse(imageDigest)
 		if err != nil {
-			return "", fmt.Errorf("parse digest: %w", err)
+			p.err = fmt.Errorf("parse digest: %w", err)
+			continue
 		}
+		p.digest = dig
 
 		withDig, err := reference.WithDigest(refName, dig)
 		if err != nil {
-			return "", fmt.Errorf("with digest: %w", err)
+			p.err = fmt.Errorf("with digest: %w", err)
+			continue
 		}
+		p.ref = withDig.String()
 
-		return withDig.String(), nil
+		if signer != nil && !signed[refName.Name()] {
+			if err := signImage(ctx, bk, refName, dig, signer); err != nil {
+				p.err = err
+				continue
+			}
+			signed[refName.Name()] = true
+		}
 	}
 
-	return ref, nil
+	return published, nil
 }
 
 func (container *Container) Export(
@@ -1425,7 +1974,36 @@ func (container *Container) Export(
 	platformVariants []ContainerID,
 	forcedCompression ImageLayerCompression,
 	mediaTypes ImageMediaTypes,
+	provenance bool,
+	sbom bool,
 ) error {
+	inputByPlatform, services, opts, err := container.exportInputs(ctx, bk, platformVariants, forcedCompression, mediaTypes, provenance, sbom)
+	if err != nil {
+		return err
+	}
+
+	detach, _, err := svcs.StartBindings(ctx, bk, services)
+	if err != nil {
+		return err
+	}
+	defer detach()
+
+	_, err = bk.ExportContainerImage(ctx, inputByPlatform, dest, opts)
+	return err
+}
+
+// exportInputs returns the inputs and exporter options for exporting the
+// container and its platform variants as a single image, along with the
+// services they need.
+func (container *Container) exportInputs(
+	ctx context.Context,
+	bk *buildkit.Client,
+	platformVariants []ContainerID,
+	forcedCompression ImageLayerCompression,
+	mediaTypes ImageMediaTypes,
+	provenance bool,
+	sbom bool,
+) (map[string]buildkit.ContainerExport, ServiceBindings, map[string]string, error) {
 	if mediaTypes == "" {
 		// Modern registry implementations support oci types and docker daemons
 		// have been capable of pulling them since 2018:
@@ -1437,40 +2015,46 @@ func (container *Container) Export(
 	inputByPlatform := map[string]buildkit.ContainerExport{}
 	id, err := container.ID()
 	if err != nil {
-		return err
+		return nil, nil, nil, err
 	}
 	services := ServiceBindings{}
 	for _, variantID := range append([]ContainerID{id}, platformVariants...) {
 		variant, err := variantID.ToContainer()
 		if err != nil {
-			return err
+			return nil, nil, nil, err
 		}
 		if variant.FS == nil {
 			continue
 		}
 		st, err := variant.FSState()
 		if err != nil {
-			return err
+			return nil, nil, nil, err
 		}
 
 		def, err := st.Marshal(ctx, llb.Platform(variant.Platform))
 		if err != nil {
-			return err
+			return nil, nil, nil, err
 		}
 
 		platformString := platforms.Format(variant.Platform)
 		if _, ok := inputByPlatform[platformString]; ok {
-			return fmt.Errorf("duplicate platform %q", platformString)
+			return nil, nil, nil, fmt.Errorf("duplicate platform %q", platformString)
+		}
+		attestations, err := variant.imageAttestations(ctx, bk, provenance, sbom)
+		if err != nil {
+			return nil, nil, nil, err
 		}
 		inputByPlatform[platforms.Format(variant.Platform)] = buildkit.ContainerExport{
-			Definition: def.ToPB(),
-			Config:     variant.Config,
+			Definition:   def.ToPB(),
+			Config:       variant.Config,
+			History:      variant.History,
+			Attestations: attestations,
 		}
 		services.Merge(variant.Services)
 	}
 	if len(inputByPlatform) == 0 {
 		// Could also just ignore and do nothing, airing on side of error until proven otherwise.
-		return errors.New("no containers to export")
+		return nil, nil, nil, errors.New("no containers to export")
 	}
 
 	opts := map[string]string{
@@ -1482,14 +2066,7 @@ func (container *Container) Export(
 		opts[string(exptypes.OptKeyForceCompression)] = strconv.FormatBool(true)
 	}
 
-	detach, _, err := svcs.StartBindings(ctx, bk, services)
-	if err != nil {
-		return err
-	}
-	defer detach()
-
-	_, err = bk.ExportContainerImage(ctx, inputByPlatform, dest, opts)
-	return err
+	return inputByPlatform, services, opts, nil
 }
 
 func (container *Container) Import(
@@ -1580,6 +2157,7 @@ func (container *Container) Import(
 	}
 
 	container.FS = execDef.ToPB()
+	container.LayerBase = container.FS
 
 	if release != nil {
 		// eagerly evaluate the OCI reference so Buildkit sets up a long-term lease
@@ -1612,13 +2190,19 @@ func (container *Container) Import(
 		return nil, fmt.Errorf("image archive read image config blob %s: %w", man.Config.Digest, err)
 	}
 
-	var imgSpec specs.Image
+	var imgSpec dockerspec.Image
 	err = json.Unmarshal(configBlob, &imgSpec)
 	if err != nil {
 		return nil, fmt.Errorf("load image config: %w", err)
 	}
 
 	container.Config = imgSpec.Config
+	container.History = imgSpec.History
+	container.ImageRef = ""
+	container.Layers = make([]ImageLayer, len(man.Layers))
+	for i, layer := range man.Layers {
+		container.Layers[i] = newImageLayer(layer)
+	}
 
 	return container, nil
 }
@@ -1789,6 +2373,12 @@ type ContainerExecOpts struct {
 
 	// Grant the process all root capabilities
 	InsecureRootCapabilities bool
+
+	// Which exit codes the command may exit with without the exec failing
+	Expect ReturnType
+
+	// Resource limits for the command, overriding those of the container
+	Limits *ResourceLimits
 }
 
 type BuildArg struct {
@@ -1796,6 +2386,14 @@ type BuildArg struct {
 	Value string `json:"value"`
 }
 
+// BuildContext is a named context for a Dockerfile build, which the
+// Dockerfile can refer to like a stage, e.g. with FROM or COPY --from.
+type BuildContext struct {
+	Name      string      `json:"name"`
+	Directory DirectoryID `json:"directory,omitempty"`
+	Image     string      `json:"image,omitempty"`
+}
+
 // OCI manifest annotation that specifies an image's tag
 const ociTagAnnotation = "org.opencontainers.image.ref.name"
 
@@ -1862,6 +2460,32 @@ const (
 	CompressionUncompressed ImageLayerCompression = "Uncompressed"
 )
 
+// ReturnType is the kind of exit that an exec is expected to have.
+type ReturnType string
+
+const (
+	// ReturnSuccess expects the command to exit with code 0.
+	ReturnSuccess ReturnType = "SUCCESS"
+	// ReturnFailure expects the command to exit with a non-zero code.
+	ReturnFailure ReturnType = "FAILURE"
+	// ReturnAny allows the command to exit with any code.
+	ReturnAny ReturnType = "ANY"
+)
+
+// ImagePullPolicy determines when Container.from contacts the registry.
+type ImagePullPolicy string
+
+const (
+	// PullAlways resolves the image reference with the registry every time.
+	PullAlways ImagePullPolicy = "ALWAYS"
+	// PullIfNotPresent uses the image the reference last resolved to if the
+	// engine still has it, resolving it with the registry otherwise.
+	PullIfNotPresent ImagePullPolicy = "IF_NOT_PRESENT"
+	// PullNever uses the image the reference last resolved to if the engine
+	// still has it, and fails otherwise.
+	PullNever ImagePullPolicy = "NEVER"
+)
+
 type ImageMediaTypes string
 
 const (
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"reflect"
 	"sort"
@@ -19,6 +20,7 @@ import (
 	"github.com/moby/buildkit/identity"
 	"github.com/moby/buildkit/solver/pb"
 	"github.com/opencontainers/go-digest"
+	fstypes "github.com/tonistiigi/fsutil/types"
 )
 
 var debugDigest = false
@@ -265,3 +267,56 @@ func stabilizeOp(op *opDAG) error {
 
 	return nil
 }
+
+// contentDigest returns the digest of a file's contents. Symlinks are
+// digested by their target rather than followed, and other non-regular files
+// by their type.
+func contentDigest(fsys fs.FS, name string, info fs.FileInfo, buf []byte) (digest.Digest, error) {
+	mode := info.Mode()
+	switch {
+	case mode&fs.ModeSymlink != 0:
+		stat, ok := info.Sys().(*fstypes.Stat)
+		if !ok {
+			return "", fmt.Errorf("stat %s: unexpected type %T", name, info.Sys())
+		}
+		return digest.FromString(stat.Linkname), nil
+	case !mode.IsRegular():
+		// directories, devices, pipes and sockets have no contents to compare
+		return digest.FromString(mode.Type().String()), nil
+	}
+
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	digester := digest.Canonical.Digester()
+	if _, err := io.CopyBuffer(digester.Hash(), f, buf); err != nil {
+		return "", fmt.Errorf("digest %s: %w", name, err)
+	}
+
+	return digester.Digest(), nil
+}
+
+// writeEntryDigest writes a line to dest describing a file's mode, ownership,
+// contents and, unless ignoreTimestamps is true, modification time.
+func writeEntryDigest(dest io.Writer, fsys fs.FS, name string, info fs.FileInfo, ignoreTimestamps bool, buf []byte) error {
+	stat, ok := info.Sys().(*fstypes.Stat)
+	if !ok {
+		return fmt.Errorf("stat %s: unexpected type %T", name, info.Sys())
+	}
+
+	dgst, err := contentDigest(fsys, name, info, buf)
+	if err != nil {
+		return err
+	}
+
+	var modTime int64
+	if !ignoreTimestamps {
+		modTime = stat.ModTime
+	}
+
+	_, err = fmt.Fprintf(dest, "%o %d %d %d %s\n", stat.Mode, stat.Uid, stat.Gid, modTime, dgst)
+	return err
+}
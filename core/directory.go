@@ -1,6 +1,7 @@
 package core
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io/fs"
@@ -21,6 +22,7 @@ import (
 	"github.com/vito/progrock"
 
 	"github.com/dagger/dagger/core/pipeline"
+	"github.com/dagger/dagger/core/reffs"
 	"github.com/dagger/dagger/core/resourceid"
 	"github.com/dagger/dagger/engine/buildkit"
 )
@@ -271,6 +273,213 @@ func (dir *Directory) Entries(ctx context.Context, bk *buildkit.Client, svcs *Se
 	return paths, nil
 }
 
+// Glob returns the paths of the files and directories in the directory that
+// match pattern, relative to the directory. The pattern is matched against
+// each path segment using path.Match, and "**" matches any number of
+// directories.
+func (dir *Directory) Glob(ctx context.Context, bk *buildkit.Client, svcs *Services, pattern string) ([]string, error) {
+	segments, err := splitGlob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+
+	detach, _, err := svcs.StartBindings(ctx, bk, dir.Services)
+	if err != nil {
+		return nil, err
+	}
+	defer detach()
+
+	res, err := bk.Solve(ctx, bkgw.SolveRequest{
+		Definition: dir.LLB,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ref, err := res.SingleRef()
+	if err != nil {
+		return nil, err
+	}
+	// empty directory, i.e. llb.Scratch()
+	if ref == nil {
+		return []string{}, nil
+	}
+
+	root := path.Join("/", dir.Dir)
+
+	paths := []string{}
+	err = fs.WalkDir(reffs.ReferenceFS(ctx, ref), root, func(walkPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if walkPath == root {
+			return nil
+		}
+
+		rel := strings.TrimPrefix(strings.TrimPrefix(walkPath, root), "/")
+		name := strings.Split(rel, "/")
+
+		if globMatch(segments, name, false) {
+			paths = append(paths, rel)
+		}
+
+		if d.IsDir() && !globMatch(segments, name, true) {
+			// nothing further down can match
+			return fs.SkipDir
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
+
+// Changes returns the files and subdirectories added, modified or deleted in
+// other relative to this directory. Subdirectories' paths end with a slash.
+func (dir *Directory) Changes(ctx context.Context, bk *buildkit.Client, svcs *Services, other *Directory) ([]Change, error) {
+	before, err := dir.changeEntries(ctx, bk, svcs)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := other.changeEntries(ctx, bk, svcs)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffEntries(before, after), nil
+}
+
+// changeEntries returns what's compared of everything in the directory to
+// find changes, keyed by its path relative to the directory, with a trailing
+// slash for subdirectories.
+func (dir *Directory) changeEntries(ctx context.Context, bk *buildkit.Client, svcs *Services) (map[string]changeEntry, error) {
+	buf := make([]byte, buildkit.MaxFileContentsChunkSize)
+
+	// the subdirectories being walked, each digested like ContentDigest
+	// digests them
+	type walkedDir struct {
+		path     string
+		digester digest.Digester
+	}
+	var walked []walkedDir
+
+	entries := map[string]changeEntry{}
+	leave := func(rel string) {
+		for len(walked) > 0 {
+			last := walked[len(walked)-1]
+			if strings.HasPrefix(rel, last.path+"/") {
+				break
+			}
+			entry := entries[last.path+"/"]
+			entry.reported = last.digester.Digest()
+			entries[last.path+"/"] = entry
+			walked = walked[:len(walked)-1]
+		}
+	}
+
+	err := dir.walk(ctx, bk, svcs, func(fsys fs.FS, walkPath, rel string, info fs.FileInfo) error {
+		leave(rel)
+
+		line := new(bytes.Buffer)
+		if err := writeEntryDigest(line, fsys, walkPath, info, true, buf); err != nil {
+			return err
+		}
+		for _, parent := range walked {
+			fmt.Fprintf(parent.digester.Hash(), "%q ", strings.TrimPrefix(rel, parent.path+"/"))
+			parent.digester.Hash().Write(line.Bytes())
+		}
+
+		// the same as the file's ContentDigest, or the directory's own entry
+		dgst := digest.FromBytes(line.Bytes())
+		if info.IsDir() {
+			entries[rel+"/"] = changeEntry{compared: dgst}
+			walked = append(walked, walkedDir{path: rel, digester: digest.Canonical.Digester()})
+		} else {
+			entries[rel] = changeEntry{compared: dgst, reported: dgst}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	leave("")
+
+	return entries, nil
+}
+
+// ContentDigest returns a digest of everything in the directory: the path,
+// mode, ownership and contents of each file and subdirectory, and their
+// modification times unless ignoreTimestamps is true.
+//
+// Unlike Digest, it only depends on the directory's contents, not on how they
+// were produced.
+func (dir *Directory) ContentDigest(ctx context.Context, bk *buildkit.Client, svcs *Services, ignoreTimestamps bool) (digest.Digest, error) {
+	buf := make([]byte, buildkit.MaxFileContentsChunkSize)
+
+	digester := digest.Canonical.Digester()
+	err := dir.walk(ctx, bk, svcs, func(fsys fs.FS, walkPath, rel string, info fs.FileInfo) error {
+		fmt.Fprintf(digester.Hash(), "%q ", rel)
+		return writeEntryDigest(digester.Hash(), fsys, walkPath, info, ignoreTimestamps, buf)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return digester.Digest(), nil
+}
+
+// walk solves the directory and calls fn for everything beneath it in
+// lexical order, with its path in the solved ref and relative to the
+// directory.
+func (dir *Directory) walk(ctx context.Context, bk *buildkit.Client, svcs *Services, fn func(fsys fs.FS, walkPath, rel string, info fs.FileInfo) error) error {
+	detach, _, err := svcs.StartBindings(ctx, bk, dir.Services)
+	if err != nil {
+		return err
+	}
+	defer detach()
+
+	res, err := bk.Solve(ctx, bkgw.SolveRequest{
+		Definition: dir.LLB,
+	})
+	if err != nil {
+		return err
+	}
+
+	ref, err := res.SingleRef()
+	if err != nil {
+		return err
+	}
+	// empty directory, i.e. llb.Scratch()
+	if ref == nil {
+		return nil
+	}
+
+	fsys := reffs.ReferenceFS(ctx, ref)
+	root := path.Join("/", dir.Dir)
+
+	return fs.WalkDir(fsys, root, func(walkPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if walkPath == root {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		rel := strings.TrimPrefix(strings.TrimPrefix(walkPath, root), "/")
+		return fn(fsys, walkPath, rel, info)
+	})
+}
+
 func (dir *Directory) WithNewFile(ctx context.Context, dest string, content []byte, permissions fs.FileMode, ownership *Ownership) (*Directory, error) {
 	dir = dir.Clone()
 
@@ -587,6 +796,36 @@ func (dir *Directory) Without(ctx context.Context, path string) (*Directory, err
 	return dir, nil
 }
 
+// WithPatch applies a unified diff, as output by diff -u or git diff, to the
+// directory. Files the patch creates are written with 0644 permissions; files
+// it changes keep theirs. The patch is applied by the shim when the directory
+// is evaluated, each change against the result of the ones before it.
+func (dir *Directory) WithPatch(ctx context.Context, patch *File) (*Directory, error) {
+	dir = dir.Clone()
+
+	st, err := dir.State()
+	if err != nil {
+		return nil, err
+	}
+
+	patchSt, err := patch.State()
+	if err != nil {
+		return nil, err
+	}
+
+	st = internalExec(&patchSt, st, "patch "+path.Base(patch.File),
+		"patch", path.Join(internalSrcMount, patch.File), path.Join(internalOutMount, dir.Dir))
+
+	err = dir.SetState(ctx, st)
+	if err != nil {
+		return nil, err
+	}
+
+	dir.Services.Merge(patch.Services)
+
+	return dir, nil
+}
+
 func (dir *Directory) Export(
 	ctx context.Context,
 	bk *buildkit.Client,
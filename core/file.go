@@ -2,9 +2,10 @@ package core
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-
 	"io"
+	"io/fs"
 	"path"
 	"time"
 
@@ -219,6 +220,94 @@ func (file *File) WithTimestamps(ctx context.Context, unix int) (*File, error) {
 	return file, nil
 }
 
+// TemplateVariable is a variable available to a file template.
+type TemplateVariable struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// WithReplaced returns the file with every occurrence of search in its
+// contents replaced with replacement.
+func (file *File) WithReplaced(ctx context.Context, search, replacement string) (*File, error) {
+	if search == "" {
+		return nil, fmt.Errorf("search string must not be empty")
+	}
+
+	return file.withTransformedContents(ctx, "replace", search, replacement)
+}
+
+// WithTemplate returns the file with its contents rendered as a Go template,
+// with each variable available as a field, e.g. {{.name}}. Referencing a
+// variable that isn't set is an error.
+func (file *File) WithTemplate(ctx context.Context, vars []TemplateVariable) (*File, error) {
+	data := map[string]string{}
+	for _, v := range vars {
+		data[v.Name] = v.Value
+	}
+
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return file.withTransformedContents(ctx, "template", string(dataJSON))
+}
+
+// withTransformedContents returns the file with its contents transformed by
+// one of the shim's internal commands, keeping its permissions and ownership.
+// The command runs when the file is evaluated, with the file's path followed
+// by args.
+func (file *File) withTransformedContents(ctx context.Context, cmd string, args ...string) (*File, error) {
+	file = file.Clone()
+
+	st, err := file.State()
+	if err != nil {
+		return nil, err
+	}
+
+	st = internalExec(nil, st, cmd+" "+path.Base(file.File),
+		append([]string{cmd, path.Join(internalOutMount, file.File)}, args...)...)
+
+	def, err := st.Marshal(ctx, llb.Platform(file.Platform))
+	if err != nil {
+		return nil, err
+	}
+	file.LLB = def.ToPB()
+
+	return file, nil
+}
+
+// ContentDigest returns a digest of the file's mode, ownership and contents,
+// and its modification time unless ignoreTimestamps is true.
+//
+// Unlike Digest, it only depends on the file itself, not on how it was
+// produced.
+func (file *File) ContentDigest(ctx context.Context, bk *buildkit.Client, svcs *Services, ignoreTimestamps bool) (digest.Digest, error) {
+	detach, _, err := svcs.StartBindings(ctx, bk, file.Services)
+	if err != nil {
+		return "", err
+	}
+	defer detach()
+
+	fsys, err := reffs.OpenDef(ctx, bk, file.LLB)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := fs.Stat(fsys, file.File)
+	if err != nil {
+		return "", err
+	}
+
+	digester := digest.Canonical.Digester()
+	buf := make([]byte, buildkit.MaxFileContentsChunkSize)
+	if err := writeEntryDigest(digester.Hash(), fsys, file.File, info, ignoreTimestamps, buf); err != nil {
+		return "", err
+	}
+
+	return digester.Digest(), nil
+}
+
 func (file *File) Open(ctx context.Context, host *Host, bk *buildkit.Client, svcs *Services) (io.ReadCloser, error) {
 	detach, _, err := svcs.StartBindings(ctx, bk, file.Services)
 	if err != nil {
@@ -6,7 +6,7 @@ import (
 
 	"github.com/dagger/dagger/core/pipeline"
 	"github.com/dagger/dagger/engine/buildkit"
-	specs "github.com/opencontainers/image-spec/specs-go/v1"
+	dockerspec "github.com/moby/buildkit/exporter/containerimage/image"
 )
 
 func (p *Project) goRuntime(ctx context.Context, bk *buildkit.Client, progSock string, pipeline pipeline.Path) (*Container, error) {
@@ -14,13 +14,13 @@ func (p *Project) goRuntime(ctx context.Context, bk *buildkit.Client, progSock s
 	if err != nil {
 		return nil, err
 	}
-	ctr, err = ctr.From(ctx, bk, "golang:1.20-alpine")
+	ctr, err = ctr.From(ctx, bk, "golang:1.20-alpine", "")
 	if err != nil {
 		return nil, err
 	}
 
 	workdir := "/src"
-	ctr, err = ctr.UpdateImageConfig(ctx, func(cfg specs.ImageConfig) specs.ImageConfig {
+	ctr, err = ctr.UpdateImageConfig(ctx, func(cfg dockerspec.ImageConfig) dockerspec.ImageConfig {
 		cfg.WorkingDir = absPath(cfg.WorkingDir, workdir)
 		cfg.Cmd = nil
 		return cfg
@@ -52,7 +52,7 @@ func (p *Project) goRuntime(ctx context.Context, bk *buildkit.Client, progSock s
 		return nil, err
 	}
 
-	ctr, err = ctr.UpdateImageConfig(ctx, func(cfg specs.ImageConfig) specs.ImageConfig {
+	ctr, err = ctr.UpdateImageConfig(ctx, func(cfg dockerspec.ImageConfig) dockerspec.ImageConfig {
 		cfg.Entrypoint = []string{"/entrypoint"}
 		return cfg
 	})
@@ -3,11 +3,17 @@ package core
 import (
 	"context"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/dagger/dagger/engine/buildkit"
 	"github.com/moby/buildkit/client/llb"
+	dockerspec "github.com/moby/buildkit/exporter/containerimage/image"
 	bkgw "github.com/moby/buildkit/frontend/gateway/client"
 	"github.com/moby/buildkit/identity"
 	"github.com/moby/buildkit/solver/pb"
@@ -15,21 +21,154 @@ import (
 	"github.com/vito/progrock"
 )
 
-type portHealthChecker struct {
+// Defaults for the timing of health checks which don't configure it.
+const (
+	defaultHealthcheckInterval = time.Second
+	defaultHealthcheckTimeout  = 30 * time.Second
+)
+
+// Docker's defaults for image HEALTHCHECKs which don't configure them, which
+// unlike explicit health checks give up after a few failures, so that services
+// whose image checks can't pass in them don't hang.
+const (
+	defaultImageHealthcheckInterval = 30 * time.Second
+	defaultImageHealthcheckTimeout  = 30 * time.Second
+	defaultImageHealthcheckRetries  = 3
+)
+
+// HealthcheckTimings configures when a healthcheck runs. Durations that are
+// 0 take the defaults of whatever runs the check.
+type HealthcheckTimings struct {
+	Interval      time.Duration `json:"interval,omitempty"`
+	Timeout       time.Duration `json:"timeout,omitempty"`
+	StartPeriod   time.Duration `json:"start_period,omitempty"`
+	StartInterval time.Duration `json:"start_interval,omitempty"`
+}
+
+// ParseHealthcheckTimings parses the durations of a healthcheck, formatted
+// like "500ms" or "1m30s". Empty durations are left unset.
+func ParseHealthcheckTimings(interval, timeout, startPeriod, startInterval string) (HealthcheckTimings, error) {
+	var timings HealthcheckTimings
+	for _, d := range []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"interval", interval, &timings.Interval},
+		{"timeout", timeout, &timings.Timeout},
+		{"startPeriod", startPeriod, &timings.StartPeriod},
+		{"startInterval", startInterval, &timings.StartInterval},
+	} {
+		if d.value == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(d.value)
+		if err != nil {
+			return HealthcheckTimings{}, fmt.Errorf("healthcheck %s: %w", d.name, err)
+		}
+		if duration < 0 {
+			return HealthcheckTimings{}, fmt.Errorf("healthcheck %s must not be negative", d.name)
+		}
+		*d.dest = duration
+	}
+	return timings, nil
+}
+
+// ServiceHealthcheck configures how to check that a service is ready, in
+// place of waiting for its exposed ports to accept connections.
+//
+// Exactly one of Exec, HTTPPath or TCP is set.
+type ServiceHealthcheck struct {
+	// Exec is run in the service's container, which is healthy when it exits
+	// with 0.
+	Exec []string `json:"exec,omitempty"`
+
+	// HTTPPath is requested with a GET from the service, which is healthy when
+	// it responds with HTTPStatus.
+	HTTPPath   string `json:"http_path,omitempty"`
+	HTTPStatus int    `json:"http_status,omitempty"`
+
+	// TCP checks that the service accepts connections on Port.
+	TCP bool `json:"tcp,omitempty"`
+
+	// Port is the port to request or connect to, which defaults to the first
+	// exposed port.
+	Port int `json:"port,omitempty"`
+
+	HealthcheckTimings
+
+	// Retries is the number of consecutive failed checks after the start
+	// period after which the service fails to start. If 0, checks continue
+	// until the service exits.
+	Retries int `json:"retries,omitempty"`
+}
+
+// imageHealthcheck returns the health check of an image's HEALTHCHECK, or nil
+// if it has none or disables it. Unset options take Docker's defaults.
+func imageHealthcheck(cfg dockerspec.ImageConfig) *ServiceHealthcheck {
+	hc := cfg.Healthcheck
+	if hc == nil || len(hc.Test) == 0 {
+		return nil
+	}
+
+	var exec []string
+	switch hc.Test[0] {
+	case "CMD":
+		exec = hc.Test[1:]
+	case "CMD-SHELL":
+		shell := []string(cfg.Shell)
+		if len(shell) == 0 {
+			shell = []string{"/bin/sh", "-c"}
+		}
+		exec = append(append([]string{}, shell...), strings.Join(hc.Test[1:], " "))
+	default: // NONE
+		return nil
+	}
+	if len(exec) == 0 {
+		return nil
+	}
+
+	check := &ServiceHealthcheck{
+		Exec: exec,
+		HealthcheckTimings: HealthcheckTimings{
+			Interval:      hc.Interval,
+			Timeout:       hc.Timeout,
+			StartPeriod:   hc.StartPeriod,
+			StartInterval: hc.StartInterval,
+		},
+		Retries: hc.Retries,
+	}
+	if check.Interval == 0 {
+		check.Interval = defaultImageHealthcheckInterval
+	}
+	if check.Timeout == 0 {
+		check.Timeout = defaultImageHealthcheckTimeout
+	}
+	if check.Retries == 0 {
+		check.Retries = defaultImageHealthcheckRetries
+	}
+	return check
+}
+
+type healthChecker interface {
+	Check(ctx context.Context) error
+}
+
+type portHealthchecker struct {
 	bk    *buildkit.Client
 	host  string
 	ports []Port
 }
 
-func newHealth(bk *buildkit.Client, host string, ports []Port) *portHealthChecker {
-	return &portHealthChecker{
+func newHealth(bk *buildkit.Client, host string, ports []Port) *portHealthchecker {
+	return &portHealthchecker{
 		bk:    bk,
 		host:  host,
 		ports: ports,
 	}
 }
 
-func (d *portHealthChecker) Check(ctx context.Context) (err error) {
+func (d *portHealthchecker) Check(ctx context.Context) (err error) {
 	rec := progrock.FromContext(ctx)
 
 	args := []string{"check", d.host}
@@ -47,19 +186,154 @@ func (d *portHealthChecker) Check(ctx context.Context) (err error) {
 		vtx.Done(err)
 	}()
 
-	scratchDef, err := llb.Scratch().Marshal(ctx)
+	container, err := newShimContainer(ctx, d.bk)
 	if err != nil {
 		return err
 	}
 
-	scratchRes, err := d.bk.Solve(ctx, bkgw.SolveRequest{
+	// NB: use a different ctx than the one that'll be interrupted for anything
+	// that needs to run as part of post-interruption cleanup
+	defer container.Release(context.Background())
+
+	return runProcess(ctx, container, bkgw.StartRequest{
+		Args:   args,
+		Env:    []string{"_DAGGER_INTERNAL_COMMAND="},
+		Stdout: nopCloser{vtx.Stdout()},
+		Stderr: nopCloser{vtx.Stderr()},
+	})
+}
+
+// probeHealthchecker repeatedly probes a service until it's healthy, or until
+// it's failed too many times.
+type probeHealthchecker struct {
+	bk    *buildkit.Client
+	host  string
+	check *ServiceHealthcheck
+
+	// exec runs a command in the service's container, returning an error if
+	// it doesn't exit with 0.
+	exec func(ctx context.Context, args []string, stdout, stderr io.Writer) error
+}
+
+func (d *probeHealthchecker) Check(ctx context.Context) (err error) {
+	rec := progrock.FromContext(ctx)
+
+	check := d.check
+
+	var name string
+	var probe func(context.Context, *progrock.VertexRecorder) error
+	switch {
+	case len(check.Exec) > 0:
+		name = "health check " + strings.Join(check.Exec, " ")
+		probe = func(ctx context.Context, vtx *progrock.VertexRecorder) error {
+			return d.exec(ctx, check.Exec, vtx.Stdout(), vtx.Stderr())
+		}
+	case check.HTTPPath != "" || check.TCP:
+		addr := net.JoinHostPort(d.host, strconv.Itoa(check.Port))
+
+		args := []string{"probe", "tcp", addr}
+		if check.HTTPPath != "" {
+			status := check.HTTPStatus
+			if status == 0 {
+				status = http.StatusOK
+			}
+			url := "http://" + addr + "/" + strings.TrimPrefix(check.HTTPPath, "/")
+			args = []string{"probe", "http", url, strconv.Itoa(status)}
+		}
+		name = strings.Join(args, " ")
+
+		shim, err := newShimContainer(ctx, d.bk)
+		if err != nil {
+			return err
+		}
+		defer shim.Release(context.Background())
+
+		probe = func(ctx context.Context, vtx *progrock.VertexRecorder) error {
+			return runProcess(ctx, shim, bkgw.StartRequest{
+				Args:   args,
+				Env:    []string{"_DAGGER_INTERNAL_COMMAND="},
+				Stdout: nopCloser{vtx.Stdout()},
+				Stderr: nopCloser{vtx.Stderr()},
+			})
+		}
+	default:
+		return fmt.Errorf("health check has nothing to check")
+	}
+
+	// show health-check logs in a --debug vertex
+	vtx := rec.Vertex(
+		digest.Digest(identity.NewID()),
+		name,
+		progrock.Internal(),
+	)
+	defer func() {
+		vtx.Done(err)
+	}()
+
+	interval := check.Interval
+	if interval == 0 {
+		interval = defaultHealthcheckInterval
+	}
+	startInterval := check.StartInterval
+	if startInterval == 0 {
+		startInterval = interval
+	}
+	timeout := check.Timeout
+	if timeout == 0 {
+		timeout = defaultHealthcheckTimeout
+	}
+
+	started := time.Now()
+	failures := 0
+	for {
+		probeCtx, cancel := context.WithTimeout(ctx, timeout)
+		probeErr := probe(probeCtx, vtx)
+		cancel()
+		if probeErr == nil {
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		fmt.Fprintf(vtx.Stderr(), "unhealthy: %s\n", probeErr)
+
+		wait := interval
+		if time.Since(started) < check.StartPeriod {
+			// failures during the start period don't count
+			wait = startInterval
+		} else {
+			failures++
+			if check.Retries > 0 && failures >= check.Retries {
+				return fmt.Errorf("unhealthy after %d checks: %w", failures, probeErr)
+			}
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// newShimContainer returns an otherwise empty container in which to run the
+// shim's internal commands.
+func newShimContainer(ctx context.Context, bk *buildkit.Client) (bkgw.Container, error) {
+	scratchDef, err := llb.Scratch().Marshal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scratchRes, err := bk.Solve(ctx, bkgw.SolveRequest{
 		Definition: scratchDef.ToPB(),
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	container, err := d.bk.NewContainer(ctx, bkgw.NewContainerRequest{
+	return bk.NewContainer(ctx, bkgw.NewContainerRequest{
 		Mounts: []bkgw.Mount{
 			{
 				Dest:      "/",
@@ -68,22 +342,12 @@ func (d *portHealthChecker) Check(ctx context.Context) (err error) {
 			},
 		},
 	})
-	if err != nil {
-		return err
-	}
-
-	// NB: use a different ctx than the one that'll be interrupted for anything
-	// that needs to run as part of post-interruption cleanup
-	cleanupCtx := context.Background()
-
-	defer container.Release(cleanupCtx)
+}
 
-	proc, err := container.Start(ctx, bkgw.StartRequest{
-		Args:   args,
-		Env:    []string{"_DAGGER_INTERNAL_COMMAND="},
-		Stdout: nopCloser{vtx.Stdout()},
-		Stderr: nopCloser{vtx.Stderr()},
-	})
+// runProcess runs a process in the container, killing it if the context is
+// done first.
+func runProcess(ctx context.Context, container bkgw.Container, req bkgw.StartRequest) error {
+	proc, err := container.Start(ctx, req)
 	if err != nil {
 		return err
 	}
@@ -95,15 +359,12 @@ func (d *portHealthChecker) Check(ctx context.Context) (err error) {
 
 	select {
 	case err := <-exited:
-		if err != nil {
-			return err
-		}
-
-		return nil
+		return err
 	case <-ctx.Done():
-		err := proc.Signal(cleanupCtx, syscall.SIGKILL)
-		if err != nil {
-			return fmt.Errorf("interrupt check: %w", err)
+		// NB: use a different ctx than the one that'll be interrupted for anything
+		// that needs to run as part of post-interruption cleanup
+		if err := proc.Signal(context.Background(), syscall.SIGKILL); err != nil {
+			return fmt.Errorf("interrupt: %w", err)
 		}
 
 		<-exited
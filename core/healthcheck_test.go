@@ -0,0 +1,126 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	dockerspec "github.com/moby/buildkit/exporter/containerimage/image"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageHealthcheck(t *testing.T) {
+	t.Parallel()
+
+	require.Nil(t, imageHealthcheck(dockerspec.ImageConfig{}))
+	require.Nil(t, imageHealthcheck(dockerspec.ImageConfig{
+		Healthcheck: &dockerspec.HealthConfig{Test: []string{"NONE"}},
+	}))
+
+	check := imageHealthcheck(dockerspec.ImageConfig{
+		Healthcheck: &dockerspec.HealthConfig{
+			Test:     []string{"CMD", "pg_isready", "-U", "postgres"},
+			Interval: 2 * time.Second,
+			Retries:  5,
+		},
+	})
+	require.Equal(t, &ServiceHealthcheck{
+		Exec: []string{"pg_isready", "-U", "postgres"},
+		HealthcheckTimings: HealthcheckTimings{
+			Interval: 2 * time.Second,
+			Timeout:  30 * time.Second,
+		},
+		Retries: 5,
+	}, check)
+
+	check = imageHealthcheck(dockerspec.ImageConfig{
+		Healthcheck: &dockerspec.HealthConfig{
+			Test: []string{"CMD-SHELL", "curl -f http://localhost || exit 1"},
+		},
+	})
+	require.Equal(t, []string{"/bin/sh", "-c", "curl -f http://localhost || exit 1"}, check.Exec)
+	// Docker's defaults
+	require.Equal(t, 30*time.Second, check.Interval)
+	require.Equal(t, 30*time.Second, check.Timeout)
+	require.Equal(t, 3, check.Retries)
+
+	check = imageHealthcheck(dockerspec.ImageConfig{
+		Healthcheck: &dockerspec.HealthConfig{
+			Test: []string{"CMD-SHELL", "Test-Path C:\\ready"},
+		},
+		Shell: []string{"powershell", "-Command"},
+	})
+	require.Equal(t, []string{"powershell", "-Command", "Test-Path C:\\ready"}, check.Exec)
+}
+
+func TestServiceWithHealthcheck(t *testing.T) {
+	t.Parallel()
+
+	svc := NewContainerService(&Container{
+		Ports: []Port{{Port: 8080, Protocol: NetworkProtocolTCP}},
+	})
+
+	checked, err := svc.WithHealthcheck(ServiceHealthcheck{HTTPPath: "/healthz"}, false)
+	require.NoError(t, err)
+	require.Equal(t, 8080, checked.Healthcheck.Port)
+	require.Nil(t, svc.Healthcheck)
+
+	checked, err = svc.WithHealthcheck(ServiceHealthcheck{TCP: true, Port: 9090}, false)
+	require.NoError(t, err)
+	require.Equal(t, 9090, checked.Healthcheck.Port)
+
+	_, err = svc.WithHealthcheck(ServiceHealthcheck{}, false)
+	require.Error(t, err)
+
+	_, err = svc.WithHealthcheck(ServiceHealthcheck{TCP: true, HTTPPath: "/"}, false)
+	require.Error(t, err)
+
+	_, err = NewContainerService(&Container{}).WithHealthcheck(ServiceHealthcheck{TCP: true}, false)
+	require.Error(t, err)
+
+	_, err = NewHostService("localhost", []PortForward{{Backend: 80}}).WithHealthcheck(ServiceHealthcheck{TCP: true}, false)
+	require.Error(t, err)
+
+	t.Run("from image", func(t *testing.T) {
+		svc := NewContainerService(&Container{
+			Config: dockerspec.ImageConfig{
+				Healthcheck: &dockerspec.HealthConfig{
+					Test:     []string{"CMD", "pg_isready"},
+					Interval: 10 * time.Second,
+				},
+			},
+		})
+
+		checked, err := svc.WithHealthcheck(ServiceHealthcheck{
+			HealthcheckTimings: HealthcheckTimings{Interval: time.Second},
+		}, true)
+		require.NoError(t, err)
+		require.Equal(t, []string{"pg_isready"}, checked.Healthcheck.Exec)
+		// explicit timings override the image's, which take Docker's defaults
+		require.Equal(t, time.Second, checked.Healthcheck.Interval)
+		require.Equal(t, 30*time.Second, checked.Healthcheck.Timeout)
+		require.Equal(t, 3, checked.Healthcheck.Retries)
+
+		_, err = svc.WithHealthcheck(ServiceHealthcheck{TCP: true}, true)
+		require.Error(t, err)
+
+		_, err = NewContainerService(&Container{}).WithHealthcheck(ServiceHealthcheck{}, true)
+		require.ErrorContains(t, err, "no HEALTHCHECK")
+	})
+}
+
+func TestParseHealthcheckTimings(t *testing.T) {
+	t.Parallel()
+
+	timings, err := ParseHealthcheckTimings("500ms", "", "1m30s", "")
+	require.NoError(t, err)
+	require.Equal(t, HealthcheckTimings{
+		Interval:    500 * time.Millisecond,
+		StartPeriod: 90 * time.Second,
+	}, timings)
+
+	_, err = ParseHealthcheckTimings("", "soon", "", "")
+	require.ErrorContains(t, err, "healthcheck timeout")
+
+	_, err = ParseHealthcheckTimings("", "", "", "-1s")
+	require.ErrorContains(t, err, "healthcheck startInterval must not be negative")
+}
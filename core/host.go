@@ -107,3 +107,50 @@ func (host *Host) File(
 func (host *Host) Socket(ctx context.Context, sockPath string) (*socket.Socket, error) {
 	return socket.NewHostSocket(sockPath), nil
 }
+
+// Tunnel returns a service that forwards the given ports of the host to the
+// upstream service. If no ports are given, every port of the upstream is
+// forwarded, to the same port of the host if native is true, or to a random
+// port otherwise.
+func (host *Host) Tunnel(ctx context.Context, svcs *Services, upstream *Service, ports []PortForward, native bool) (*Service, error) {
+	if len(ports) == 0 {
+		upstreamPorts, err := upstream.Ports(ctx, svcs)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(upstreamPorts) == 0 {
+			return nil, fmt.Errorf("upstream service has no ports to tunnel")
+		}
+
+		for _, port := range upstreamPorts {
+			forward := PortForward{
+				Backend:  port.Port,
+				Protocol: port.Protocol,
+			}
+			if native {
+				forward.Frontend = port.Port
+			}
+			ports = append(ports, forward)
+		}
+	}
+
+	return NewTunnelService(upstream, ports), nil
+}
+
+// Service returns a service that forwards the given ports to the upstream
+// address of the host, so that containers bound to it reach the host.
+func (host *Host) Service(ctx context.Context, upstream string, ports []PortForward) (*Service, error) {
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("no ports given")
+	}
+
+	ports = cloneSlice(ports)
+	for i, port := range ports {
+		if port.Protocol == "" {
+			ports[i].Protocol = NetworkProtocolTCP
+		}
+	}
+
+	return NewHostService(upstream, ports), nil
+}
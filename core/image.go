@@ -0,0 +1,140 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	dockerspec "github.com/moby/buildkit/exporter/containerimage/image"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/dagger/dagger/engine/buildkit"
+)
+
+// ImageLayer is a layer of a container image.
+type ImageLayer struct {
+	Digest    string `json:"digest"`
+	MediaType string `json:"mediaType"`
+	Size      int64  `json:"size"`
+}
+
+func newImageLayer(desc specs.Descriptor) ImageLayer {
+	return ImageLayer{
+		Digest:    desc.Digest.String(),
+		MediaType: desc.MediaType,
+		Size:      desc.Size,
+	}
+}
+
+// ImageHistory is an entry in the history of a container image, recording
+// how one of its layers was created.
+type ImageHistory struct {
+	Created    *string `json:"created"`
+	CreatedBy  string  `json:"createdBy"`
+	Author     string  `json:"author"`
+	Comment    string  `json:"comment"`
+	EmptyLayer bool    `json:"emptyLayer"`
+}
+
+// Healthcheck is the command that checks whether a container is healthy, as
+// with Dockerfile's HEALTHCHECK.
+type Healthcheck struct {
+	Test          []string `json:"test"`
+	Interval      string   `json:"interval"`
+	Timeout       string   `json:"timeout"`
+	StartPeriod   string   `json:"startPeriod"`
+	StartInterval string   `json:"startInterval"`
+	Retries       int      `json:"retries"`
+}
+
+// ImageHistory returns the history of the container's image.
+func (container *Container) ImageHistory(ctx context.Context) ([]ImageHistory, error) {
+	history := make([]ImageHistory, len(container.History))
+	for i, h := range container.History {
+		history[i] = ImageHistory{
+			CreatedBy:  h.CreatedBy,
+			Author:     h.Author,
+			Comment:    h.Comment,
+			EmptyLayer: h.EmptyLayer,
+		}
+		if h.Created != nil {
+			created := h.Created.UTC().Format(time.RFC3339)
+			history[i].Created = &created
+		}
+	}
+	return history, nil
+}
+
+// ImageLayers returns the layers of the image the container was pulled or
+// imported from. It returns no layers if the container's filesystem has
+// changed since.
+func (container *Container) ImageLayers(ctx context.Context, bk *buildkit.Client) ([]ImageLayer, error) {
+	if container.Layers != nil || container.ImageRef == "" {
+		return container.Layers, nil
+	}
+
+	mfst, err := bk.ImageManifest(ctx, container.ImageRef, container.Platform)
+	if err != nil {
+		return nil, err
+	}
+
+	layers := make([]ImageLayer, len(mfst.Layers))
+	for i, layer := range mfst.Layers {
+		layers[i] = newImageLayer(layer)
+	}
+	return layers, nil
+}
+
+// Healthcheck returns the container's healthcheck, if it has one.
+func (container *Container) Healthcheck(ctx context.Context) (*Healthcheck, error) {
+	hc := container.Config.Healthcheck
+	if hc == nil {
+		return nil, nil
+	}
+
+	return &Healthcheck{
+		Test:          hc.Test,
+		Interval:      formatHealthcheckDuration(hc.Interval),
+		Timeout:       formatHealthcheckDuration(hc.Timeout),
+		StartPeriod:   formatHealthcheckDuration(hc.StartPeriod),
+		StartInterval: formatHealthcheckDuration(hc.StartInterval),
+		Retries:       hc.Retries,
+	}, nil
+}
+
+// WithHealthcheck sets the container's healthcheck. Durations are parsed
+// with time.ParseDuration, and an empty duration means the default.
+func (container *Container) WithHealthcheck(ctx context.Context, healthcheck Healthcheck) (*Container, error) {
+	if len(healthcheck.Test) == 0 {
+		return nil, fmt.Errorf("healthcheck test must not be empty")
+	}
+
+	timings, err := ParseHealthcheckTimings(healthcheck.Interval, healthcheck.Timeout, healthcheck.StartPeriod, healthcheck.StartInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	hc := &dockerspec.HealthConfig{
+		Test:          healthcheck.Test,
+		Interval:      timings.Interval,
+		Timeout:       timings.Timeout,
+		StartPeriod:   timings.StartPeriod,
+		StartInterval: timings.StartInterval,
+		Retries:       healthcheck.Retries,
+	}
+	if hc.Retries < 0 {
+		return nil, fmt.Errorf("healthcheck retries must not be negative")
+	}
+
+	return container.UpdateImageConfig(ctx, func(cfg dockerspec.ImageConfig) dockerspec.ImageConfig {
+		cfg.Healthcheck = hc
+		return cfg
+	})
+}
+
+func formatHealthcheckDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
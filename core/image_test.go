@@ -0,0 +1,45 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainerHealthcheck(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	ctr := &Container{}
+	hc, err := ctr.Healthcheck(ctx)
+	require.NoError(t, err)
+	require.Nil(t, hc)
+
+	ctr, err = ctr.WithHealthcheck(ctx, Healthcheck{
+		Test:     []string{"CMD", "true"},
+		Interval: "1m30s",
+		Timeout:  "5s",
+		Retries:  3,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 90*time.Second, ctr.Config.Healthcheck.Interval)
+	require.Zero(t, ctr.Config.Healthcheck.StartPeriod)
+
+	hc, err = ctr.Healthcheck(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &Healthcheck{
+		Test:     []string{"CMD", "true"},
+		Interval: "1m30s",
+		Timeout:  "5s",
+		Retries:  3,
+	}, hc)
+
+	_, err = ctr.WithHealthcheck(ctx, Healthcheck{})
+	require.ErrorContains(t, err, "must not be empty")
+
+	_, err = ctr.WithHealthcheck(ctx, Healthcheck{Test: []string{"NONE"}, Timeout: "soon"})
+	require.ErrorContains(t, err, "healthcheck timeout")
+}
@@ -3,9 +3,15 @@ package core
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
 	_ "embed"
 	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
@@ -24,6 +30,7 @@ import (
 	"github.com/moby/buildkit/identity"
 	ocispecs "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh/agent"
 	"gopkg.in/yaml.v3"
 
 	"dagger.io/dagger"
@@ -85,6 +92,58 @@ func TestContainerFrom(t *testing.T) {
 	require.Equal(t, res.Container.From.File.Contents, "3.18.2\n")
 }
 
+func TestContainerFromPullPolicy(t *testing.T) {
+	t.Parallel()
+	c, ctx := connect(t)
+
+	_, err := c.Container().From(alpineImage).Sync(ctx)
+	require.NoError(t, err)
+
+	for _, policy := range []dagger.ImagePullPolicy{dagger.Always, dagger.IfNotPresent, dagger.Never} {
+		policy := policy
+		t.Run(string(policy), func(t *testing.T) {
+			release, err := c.Container().
+				From(alpineImage, dagger.ContainerFromOpts{PullPolicy: policy}).
+				File("/etc/alpine-release").
+				Contents(ctx)
+			require.NoError(t, err)
+			require.Equal(t, "3.18.2\n", release)
+		})
+	}
+
+	t.Run("never pulled", func(t *testing.T) {
+		_, err := c.Container().
+			From(registryRef("never-pulled-"+identity.NewID()), dagger.ContainerFromOpts{PullPolicy: dagger.Never}).
+			Sync(ctx)
+		require.ErrorContains(t, err, "has not been pulled")
+	})
+
+	t.Run("pulled in another session", func(t *testing.T) {
+		// an image nothing else pulls, so it can only be present if the
+		// first session pulled it
+		ref := registryRef("pulled-in-another-session")
+		_, err := c.Container().
+			From(alpineImage).
+			WithNewFile("/marker", dagger.ContainerWithNewFileOpts{Contents: ref}).
+			Publish(ctx, ref)
+		require.NoError(t, err)
+
+		first, ctx := connect(t)
+		_, err = first.Container().From(ref).Sync(ctx)
+		require.NoError(t, err)
+
+		for _, policy := range []dagger.ImagePullPolicy{dagger.IfNotPresent, dagger.Never} {
+			second, ctx := connect(t)
+			marker, err := second.Container().
+				From(ref, dagger.ContainerFromOpts{PullPolicy: policy}).
+				File("/marker").
+				Contents(ctx)
+			require.NoError(t, err, policy)
+			require.Equal(t, ref, marker, policy)
+		}
+	})
+}
+
 func TestContainerBuild(t *testing.T) {
 	c, ctx := connect(t)
 
@@ -375,6 +434,191 @@ func TestContainerExecStdoutStderr(t *testing.T) {
 	require.Equal(t, res.Container.From.WithExec.Stderr, "goodbye\n")
 }
 
+func TestContainerLogs(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	ctr := c.Container().
+		From(alpineImage).
+		WithEnvVariable("BUST", identity.NewID()).
+		WithExec([]string{"sh", "-c", "echo hello; sleep 0.2; echo goodbye >/dev/stderr; sleep 0.2; echo done"})
+
+	collect := func() []dagger.LogLine {
+		var lines []dagger.LogLine
+		err := c.ContainerLogs(ctx, ctr, func(line dagger.LogLine) error {
+			lines = append(lines, line)
+			return nil
+		})
+		require.NoError(t, err)
+		return lines
+	}
+
+	expected := []dagger.LogLine{
+		{Stream: "stdout", Line: "hello"},
+		{Stream: "stderr", Line: "goodbye"},
+		{Stream: "stdout", Line: "done"},
+	}
+
+	t.Run("streams lines as they are written", func(t *testing.T) {
+		require.Equal(t, expected, collect())
+	})
+
+	t.Run("replays output in order when cached", func(t *testing.T) {
+		require.Equal(t, expected, collect())
+	})
+
+	t.Run("returns exec errors", func(t *testing.T) {
+		var lines []dagger.LogLine
+		err := c.ContainerLogs(ctx, ctr.WithExec([]string{"sh", "-c", "echo oh no; exit 3"}), func(line dagger.LogLine) error {
+			lines = append(lines, line)
+			return nil
+		})
+
+		var exErr *dagger.ExecError
+		require.ErrorAs(t, err, &exErr)
+		require.Equal(t, 3, exErr.ExitCode)
+		require.Contains(t, exErr.Stdout, "oh no")
+		require.Equal(t, []dagger.LogLine{{Stream: "stdout", Line: "oh no"}}, lines)
+	})
+}
+
+func TestContainerExecExpect(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	base := c.Container().From(alpineImage)
+
+	t.Run("exit code of successful exec", func(t *testing.T) {
+		code, err := base.WithExec([]string{"true"}).ExitCode(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 0, code)
+	})
+
+	t.Run("expect failure", func(t *testing.T) {
+		ctr := base.WithExec([]string{"sh", "-c", "echo failing; exit 3"}, dagger.ContainerWithExecOpts{
+			Expect: dagger.Failure,
+		})
+
+		code, err := ctr.ExitCode(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 3, code)
+
+		out, err := ctr.Stdout(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "failing\n", out)
+	})
+
+	t.Run("expect failure of successful exec", func(t *testing.T) {
+		_, err := base.WithExec([]string{"true"}, dagger.ContainerWithExecOpts{
+			Expect: dagger.Failure,
+		}).Sync(ctx)
+
+		var exErr *dagger.ExecError
+		require.ErrorAs(t, err, &exErr)
+		require.Contains(t, exErr.Stderr, "expected command to fail")
+		// the exit code is the one the exec failed with, not the command's
+		require.Equal(t, 1, exErr.ExitCode)
+	})
+
+	t.Run("expect any", func(t *testing.T) {
+		for _, want := range []int{0, 1, 42} {
+			code, err := base.WithExec([]string{"sh", "-c", fmt.Sprintf("exit %d", want)}, dagger.ContainerWithExecOpts{
+				Expect: dagger.Any,
+			}).ExitCode(ctx)
+			require.NoError(t, err)
+			require.Equal(t, want, code)
+		}
+	})
+
+	t.Run("unexpected failure still errors", func(t *testing.T) {
+		_, err := base.WithExec([]string{"sh", "-c", "exit 3"}).ExitCode(ctx)
+
+		var exErr *dagger.ExecError
+		require.ErrorAs(t, err, &exErr)
+		require.Equal(t, 3, exErr.ExitCode)
+	})
+}
+
+func TestContainerResourceLimits(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	base := c.Container().
+		From(alpineImage).
+		WithEnvVariable("BUST", identity.NewID()).
+		WithResourceLimits(dagger.ResourceLimits{
+			MilliCPUs: 500,
+			Pids:      64,
+		})
+
+	t.Run("applies container limits to cgroup", func(t *testing.T) {
+		out, err := base.
+			WithExec([]string{"cat", "/sys/fs/cgroup/cpu.max", "/sys/fs/cgroup/pids.max"}).
+			Stdout(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "50000 100000\n64\n", out)
+	})
+
+	t.Run("exec limits override container limits", func(t *testing.T) {
+		out, err := base.
+			WithExec([]string{"cat", "/sys/fs/cgroup/cpu.max", "/sys/fs/cgroup/pids.max"}, dagger.ContainerWithExecOpts{
+				Limits: dagger.ResourceLimits{Pids: 32},
+			}).
+			Stdout(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "50000 100000\n32\n", out)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		_, err := base.
+			WithExec([]string{"sh", "-c", "echo started; sleep 60"}, dagger.ContainerWithExecOpts{
+				Limits: dagger.ResourceLimits{Timeout: 1},
+			}).
+			Sync(ctx)
+
+		var exErr *dagger.ExecError
+		require.ErrorAs(t, err, &exErr)
+		require.Equal(t, "timeout", exErr.LimitExceeded)
+		require.Equal(t, "started", exErr.Stdout)
+		require.Contains(t, exErr.Stderr, "timed out")
+	})
+
+	t.Run("timeout fails even if failure is expected", func(t *testing.T) {
+		_, err := base.
+			WithExec([]string{"sleep", "60"}, dagger.ContainerWithExecOpts{
+				Limits: dagger.ResourceLimits{Timeout: 1},
+				Expect: dagger.Any,
+			}).
+			Sync(ctx)
+
+		var exErr *dagger.ExecError
+		require.ErrorAs(t, err, &exErr)
+		require.Equal(t, "timeout", exErr.LimitExceeded)
+	})
+
+	t.Run("memory", func(t *testing.T) {
+		_, err := base.
+			WithExec([]string{"sh", "-c", "head -c 256m /dev/zero | tail"}, dagger.ContainerWithExecOpts{
+				Limits: dagger.ResourceLimits{Memory: "32MiB"},
+			}).
+			Sync(ctx)
+
+		var exErr *dagger.ExecError
+		require.ErrorAs(t, err, &exErr)
+		require.Equal(t, "memory", exErr.LimitExceeded)
+	})
+
+	t.Run("invalid limits", func(t *testing.T) {
+		_, err := base.
+			WithResourceLimits(dagger.ResourceLimits{Memory: "lots"}).
+			Sync(ctx)
+		require.ErrorContains(t, err, "invalid memory limit")
+	})
+}
+
 func TestContainerExecStdin(t *testing.T) {
 	t.Parallel()
 
@@ -2517,6 +2761,372 @@ func TestContainerPublish(t *testing.T) {
 	require.Equal(t, "im-a-entrypoint\n", output)
 }
 
+func TestContainerPublishSigned(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	newKey := func() (*dagger.Secret, *dagger.File) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		require.NoError(t, err)
+		pubDER, err := x509.MarshalPKIXPublicKey(key.Public())
+		require.NoError(t, err)
+
+		privPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+		pubPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+
+		return c.SetSecret("signing-key-"+identity.NewID(), string(privPEM)),
+			c.Directory().WithNewFile("key.pub", string(pubPEM)).File("key.pub")
+	}
+
+	signingKey, publicKey := newKey()
+	_, otherPublicKey := newKey()
+
+	ctr := c.Container().From(alpineImage).
+		WithNewFile("/signed", dagger.ContainerWithNewFileOpts{Contents: identity.NewID()})
+
+	pushedRef, err := ctr.Publish(ctx, registryRef("container-publish-signed"), dagger.ContainerPublishOpts{
+		SigningKey: signingKey,
+	})
+	require.NoError(t, err)
+
+	t.Run("stored with cosign's convention", func(t *testing.T) {
+		ref, err := name.ParseReference(pushedRef, name.Insecure)
+		require.NoError(t, err)
+		digested, ok := ref.(name.Digest)
+		require.True(t, ok)
+
+		sigTag := digested.Context().Tag(strings.Replace(digested.DigestStr(), ":", "-", 1) + ".sig")
+		desc, err := remote.Get(sigTag, remote.WithTransport(http.DefaultTransport))
+		require.NoError(t, err)
+
+		var manifest ocispecs.Manifest
+		require.NoError(t, json.Unmarshal(desc.Manifest, &manifest))
+		require.Len(t, manifest.Layers, 1)
+		require.Equal(t, buildkit.SignatureMediaType, manifest.Layers[0].MediaType)
+		require.NotEmpty(t, manifest.Layers[0].Annotations[buildkit.SignatureAnnotation])
+	})
+
+	t.Run("verifies with public key", func(t *testing.T) {
+		out, err := c.Container().From(pushedRef).
+			VerifySignature(publicKey).
+			File("/signed").
+			Contents(ctx)
+		require.NoError(t, err)
+		require.NotEmpty(t, out)
+	})
+
+	t.Run("fails with other key", func(t *testing.T) {
+		_, err := c.Container().From(pushedRef).
+			VerifySignature(otherPublicKey).
+			ID(ctx)
+		require.ErrorContains(t, err, "matches the public key")
+	})
+
+	t.Run("fails unsigned", func(t *testing.T) {
+		unsignedRef, err := ctr.Publish(ctx, registryRef("container-publish-unsigned"))
+		require.NoError(t, err)
+
+		_, err = c.Container().From(unsignedRef).
+			VerifySignature(publicKey).
+			ID(ctx)
+		require.ErrorContains(t, err, "no signatures found")
+	})
+}
+
+func TestContainerPublishAll(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	ctr := c.Container().From(alpineImage).
+		WithNewFile("/published", dagger.ContainerWithNewFileOpts{Contents: identity.NewID()})
+
+	t.Run("to every address", func(t *testing.T) {
+		addresses := []string{
+			registryRef("container-publish-all"),
+			registryRef("container-publish-all"),
+			registryRef("container-publish-all-mirror"),
+		}
+
+		results, err := ctr.PublishAll(ctx, addresses)
+		require.NoError(t, err)
+		require.Len(t, results, len(addresses))
+
+		var digests []string
+		for i, res := range results {
+			address, err := res.Address(ctx)
+			require.NoError(t, err)
+			require.Equal(t, addresses[i], address)
+
+			dgst, err := res.Digest(ctx)
+			require.NoError(t, err)
+			require.Contains(t, dgst, "sha256:")
+			digests = append(digests, dgst)
+
+			out, err := c.Container().From(address + "@" + dgst).File("/published").Contents(ctx)
+			require.NoError(t, err)
+			require.NotEmpty(t, out)
+		}
+
+		// it's the same image everywhere
+		require.Equal(t, digests[0], digests[1])
+		require.Equal(t, digests[0], digests[2])
+	})
+
+	t.Run("reports each failed address", func(t *testing.T) {
+		good := registryRef("container-publish-all-partial")
+		// no credentials for the private registry
+		denied := privateRegistryRef("container-publish-all-partial")
+		unreachable := "registry.invalid/container-publish-all-partial:latest"
+
+		results, err := ctr.PublishAll(ctx, []string{good, denied, unreachable})
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+
+		goodDigest, err := results[0].Digest(ctx)
+		require.NoError(t, err)
+		require.Contains(t, goodDigest, "sha256:")
+		goodErr, err := results[0].Error(ctx)
+		require.NoError(t, err)
+		require.Empty(t, goodErr)
+
+		for i, address := range []string{denied, unreachable} {
+			res := results[i+1]
+
+			resAddress, err := res.Address(ctx)
+			require.NoError(t, err)
+			require.Equal(t, address, resAddress)
+
+			dgst, err := res.Digest(ctx)
+			require.NoError(t, err)
+			require.Empty(t, dgst)
+
+			resErr, err := res.Error(ctx)
+			require.NoError(t, err)
+			require.NotEmpty(t, resErr)
+		}
+
+		// the pushes aren't atomic, so the image was still published to the
+		// address that worked
+		_, err = c.Container().From(good + "@" + goodDigest).ID(ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("fails if no address works", func(t *testing.T) {
+		unreachable := "registry.invalid/container-publish-all-none:latest"
+
+		_, err := ctr.PublishAll(ctx, []string{unreachable})
+		require.ErrorContains(t, err, "failed to publish to all 1 addresses")
+		require.ErrorContains(t, err, unreachable)
+	})
+}
+
+func TestContainerImageConfig(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	ctr := c.Container().From(alpineImage).
+		WithWorkdir("/app").
+		WithHealthcheck([]string{"CMD-SHELL", "true"}, dagger.ContainerWithHealthcheckOpts{
+			Interval: "10s",
+			Retries:  2,
+		}).
+		WithStopSignal("SIGINT").
+		WithVolume("data").
+		WithVolume("/cache").
+		WithOnBuild([]string{"RUN echo triggered"}).
+		WithShell([]string{"/bin/sh", "-ec"})
+
+	// round-trip the config through a registry
+	ref, err := ctr.Publish(ctx, registryRef("container-image-config"))
+	require.NoError(t, err)
+	pulled := c.Container().From(ref)
+
+	hc := pulled.Healthcheck()
+	test, err := hc.Test(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"CMD-SHELL", "true"}, test)
+	interval, err := hc.Interval(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "10s", interval)
+	timeout, err := hc.Timeout(ctx)
+	require.NoError(t, err)
+	require.Empty(t, timeout)
+	retries, err := hc.Retries(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, retries)
+
+	stopSignal, err := pulled.StopSignal(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "SIGINT", stopSignal)
+
+	volumes, err := pulled.Volumes(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"/app/data", "/cache"}, volumes)
+
+	onBuild, err := pulled.OnBuild(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"RUN echo triggered"}, onBuild)
+
+	shell, err := pulled.Shell(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"/bin/sh", "-ec"}, shell)
+
+	t.Run("history and layers", func(t *testing.T) {
+		base := c.Container().From(alpineImage)
+
+		history, err := base.History(ctx)
+		require.NoError(t, err)
+		require.NotEmpty(t, history)
+		createdBy, err := history[0].CreatedBy(ctx)
+		require.NoError(t, err)
+		require.Contains(t, createdBy, "ADD")
+
+		layers, err := base.Layers(ctx)
+		require.NoError(t, err)
+		require.Len(t, layers, 1)
+		size, err := layers[0].Size(ctx)
+		require.NoError(t, err)
+		require.Positive(t, size)
+
+		// the published image keeps the base image's history
+		pulledHistory, err := pulled.History(ctx)
+		require.NoError(t, err)
+		require.Len(t, pulledHistory, len(history))
+
+		// layers are unknown once the filesystem changes
+		layers, err = base.WithNewFile("/foo").Layers(ctx)
+		require.NoError(t, err)
+		require.Empty(t, layers)
+	})
+
+	t.Run("from Dockerfile", func(t *testing.T) {
+		src := c.Directory().WithNewFile("Dockerfile", `FROM `+alpineImage+`
+HEALTHCHECK --timeout=3s CMD ["true"]
+STOPSIGNAL SIGQUIT
+SHELL ["/bin/ash", "-c"]
+`)
+
+		built := c.Container().Build(src)
+
+		timeout, err := built.Healthcheck().Timeout(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "3s", timeout)
+
+		stopSignal, err := built.StopSignal(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "SIGQUIT", stopSignal)
+
+		shell, err := built.Shell(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"/bin/ash", "-c"}, shell)
+	})
+}
+
+func TestContainerLayerBoundaries(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	ctr := c.Container().From(alpineImage).
+		WithExec([]string{"sh", "-c", "echo one > /one"}).
+		WithExec([]string{"sh", "-c", "echo two > /two"}).
+		WithExec([]string{"rm", "/etc/motd"})
+
+	publishedLayers := func(t *testing.T, ctr *dagger.Container, name string) (*dagger.Container, int) {
+		t.Helper()
+
+		ref, err := ctr.Publish(ctx, registryRef(name))
+		require.NoError(t, err)
+
+		pulled := c.Container().From(ref)
+		layers, err := pulled.Layers(ctx)
+		require.NoError(t, err)
+
+		// the exported history must account for each layer
+		history, err := pulled.History(ctx)
+		require.NoError(t, err)
+		var historyLayers int
+		for _, h := range history {
+			emptyLayer, err := h.EmptyLayer(ctx)
+			require.NoError(t, err)
+			if !emptyLayer {
+				historyLayers++
+			}
+		}
+		require.Equal(t, len(layers), historyLayers)
+
+		return pulled, len(layers)
+	}
+
+	requireContents := func(t *testing.T, ctr *dagger.Container) {
+		t.Helper()
+
+		out, err := ctr.WithExec([]string{"sh", "-c", "cat /one /two; test ! -e /etc/motd"}).Stdout(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "one\ntwo\n", out)
+	}
+
+	t.Run("every operation is a layer", func(t *testing.T) {
+		_, n := publishedLayers(t, ctr, "container-layers")
+		require.Equal(t, 4, n)
+	})
+
+	t.Run("squash", func(t *testing.T) {
+		pulled, n := publishedLayers(t, ctr.Squash(), "container-layers-squash")
+		require.Equal(t, 1, n)
+		requireContents(t, pulled)
+
+		history, err := pulled.History(ctx)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		createdBy, err := history[0].CreatedBy(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "dagger squash", createdBy)
+	})
+
+	t.Run("withLayerBoundary", func(t *testing.T) {
+		pulled, n := publishedLayers(t, ctr.WithLayerBoundary(), "container-layers-boundary")
+		// the base image's layer plus one for the execs
+		require.Equal(t, 2, n)
+		requireContents(t, pulled)
+
+		grouped := ctr.WithLayerBoundary().
+			WithExec([]string{"sh", "-c", "echo three > /three"}).
+			WithExec([]string{"sh", "-c", "echo four > /four"}).
+			WithLayerBoundary()
+		pulled, n = publishedLayers(t, grouped, "container-layers-boundaries")
+		require.Equal(t, 3, n)
+		requireContents(t, pulled)
+
+		out, err := pulled.WithExec([]string{"cat", "/three", "/four"}).Stdout(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "three\nfour\n", out)
+	})
+
+	t.Run("withLayerBoundary after file writes", func(t *testing.T) {
+		mixed := c.Container().From(alpineImage).
+			WithExec([]string{"sh", "-c", "echo one > /one"}).
+			WithNewFile("/two", dagger.ContainerWithNewFileOpts{Contents: "two\n"}).
+			WithDirectory("/dir", c.Directory().WithNewFile("three", "three\n")).
+			WithExec([]string{"rm", "/etc/motd"}).
+			WithLayerBoundary()
+
+		pulled, n := publishedLayers(t, mixed, "container-layers-boundary-writes")
+		// the base image's layer plus one for the execs and writes
+		require.Equal(t, 2, n)
+		requireContents(t, pulled)
+
+		out, err := pulled.WithExec([]string{"cat", "/dir/three"}).Stdout(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "three\n", out)
+	})
+}
+
 func TestExecFromScratch(t *testing.T) {
 	c, ctx := connect(t)
 
@@ -2638,6 +3248,51 @@ func TestContainerExport(t *testing.T) {
 	})
 }
 
+func TestContainerExportAttestations(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	const customPredicateType = "https://example.com/custom/v1"
+
+	dest := filepath.Join(t.TempDir(), "image.tar")
+
+	ok, err := c.Container().From(alpineImage).
+		WithAttestation(customPredicateType, c.Directory().
+			WithNewFile("predicate.json", `{"hello":"world"}`).
+			File("predicate.json")).
+		Export(ctx, dest, dagger.ContainerExportOpts{
+			Provenance: true,
+		})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	var index ocispecs.Index
+	require.NoError(t, json.Unmarshal(readTarFile(t, dest, "index.json"), &index))
+	// attestations are stored in a nested index alongside the image
+	nestedIndexDigest := index.Manifests[0].Digest
+	index = ocispecs.Index{}
+	require.NoError(t, json.Unmarshal(readTarFile(t, dest, "blobs/sha256/"+nestedIndexDigest.Encoded()), &index))
+
+	predicates := map[string][]byte{}
+	for _, desc := range index.Manifests {
+		if desc.Annotations["vnd.docker.reference.type"] != "attestation-manifest" {
+			continue
+		}
+		var manifest ocispecs.Manifest
+		require.NoError(t, json.Unmarshal(readTarFile(t, dest, "blobs/sha256/"+desc.Digest.Encoded()), &manifest))
+		for _, layer := range manifest.Layers {
+			predicates[layer.Annotations["in-toto.io/predicate-type"]] = readTarFile(t, dest, "blobs/sha256/"+layer.Digest.Encoded())
+		}
+	}
+
+	require.Contains(t, predicates, customPredicateType)
+	require.Contains(t, string(predicates[customPredicateType]), `"hello":"world"`)
+
+	require.Contains(t, predicates, "https://slsa.dev/provenance/v0.2")
+	require.Contains(t, string(predicates["https://slsa.dev/provenance/v0.2"]), "https://dagger.io/provenance/container@v1")
+}
+
 func TestContainerImport(t *testing.T) {
 	t.Parallel()
 
@@ -2711,6 +3366,50 @@ func TestContainerImport(t *testing.T) {
 	})
 }
 
+func TestContainerOCILayout(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	ctr := c.Container().
+		From(alpineImage).
+		WithEnvVariable("FOO", "bar").
+		WithNewFile("/marker", dagger.ContainerWithNewFileOpts{Contents: "hello"})
+
+	layout := ctr.AsOCILayout()
+
+	entries, err := layout.Entries(ctx)
+	require.NoError(t, err)
+	require.Contains(t, entries, "oci-layout")
+	require.Contains(t, entries, "index.json")
+	require.Contains(t, entries, "blobs")
+
+	imported := c.ContainerFromOCILayout(layout)
+
+	out, err := imported.WithExec([]string{"sh", "-c", "echo $FOO; cat /marker"}).Stdout(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "bar\nhello", out)
+
+	t.Run("multi-platform", func(t *testing.T) {
+		variants := make([]*dagger.Container, 0, len(platformToUname))
+		for platform := range platformToUname {
+			variants = append(variants, c.Container(dagger.ContainerOpts{Platform: platform}).From(alpineImage))
+		}
+
+		layout := c.Container().AsOCILayout(dagger.ContainerAsOCILayoutOpts{
+			PlatformVariants: variants,
+		})
+
+		for platform, uname := range platformToUname {
+			out, err := c.ContainerFromOCILayout(layout, dagger.ContainerFromOCILayoutOpts{
+				Platform: platform,
+			}).WithExec([]string{"uname", "-m"}).Stdout(ctx)
+			require.NoError(t, err)
+			require.Equal(t, uname+"\n", out)
+		}
+	})
+}
+
 func TestContainerMultiPlatformExport(t *testing.T) {
 	c, ctx := connect(t)
 
@@ -3148,6 +3847,152 @@ func TestContainerBuildNilContextError(t *testing.T) {
 	require.ErrorContains(t, err, "invalid nil input definition to definition op")
 }
 
+func TestContainerBuildContexts(t *testing.T) {
+	t.Parallel()
+	c, ctx := connect(t)
+
+	extra := c.Directory().WithNewFile("hello.txt", "hello from extra")
+
+	src := c.Directory().
+		WithNewFile("Dockerfile", `FROM base
+COPY --from=extra hello.txt /hello.txt
+RUN cat /etc/alpine-release > /release
+`)
+
+	ctr := c.Container().Build(src, dagger.ContainerBuildOpts{
+		BuildContexts: []dagger.BuildContext{
+			{Name: "base", Image: alpineImage},
+			{Name: "extra", Directory: extra},
+		},
+	})
+
+	hello, err := ctr.File("/hello.txt").Contents(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "hello from extra", hello)
+
+	release, err := ctr.File("/release").Contents(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, release)
+
+	t.Run("context from a service", func(t *testing.T) {
+		httpSrv, httpURL := httpService(ctx, t, c, "hello from a service")
+
+		fromSvc := c.Directory().
+			WithFile("index.html", c.HTTP(httpURL, dagger.HTTPOpts{ExperimentalServiceHost: httpSrv}))
+
+		src := c.Directory().
+			WithNewFile("Dockerfile", `FROM `+alpineImage+`
+COPY --from=web index.html /index.html
+`)
+
+		hello, err := c.Container().Build(src, dagger.ContainerBuildOpts{
+			BuildContexts: []dagger.BuildContext{
+				{Name: "web", Directory: fromSvc},
+			},
+		}).File("/index.html").Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "hello from a service", hello)
+	})
+
+	t.Run("no network", func(t *testing.T) {
+		src := c.Directory().
+			WithNewFile("Dockerfile", `FROM `+alpineImage+`
+RUN wget -T 5 -q -O- https://dagger.io
+`)
+
+		_, err := c.Container().Build(src, dagger.ContainerBuildOpts{
+			Network: "none",
+		}).Sync(ctx)
+		require.Error(t, err)
+	})
+
+	t.Run("unknown network", func(t *testing.T) {
+		_, err := c.Container().Build(src, dagger.ContainerBuildOpts{
+			Network: "bogus",
+		}).Sync(ctx)
+		require.ErrorContains(t, err, "unknown build network mode")
+	})
+}
+
+func TestContainerBuildSSH(t *testing.T) {
+	t.Parallel()
+	c, ctx := connect(t)
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	sshAgent := agent.NewKeyring()
+	err = sshAgent.Add(agent.AddedKey{
+		PrivateKey: key,
+		Comment:    "dagger-build-ssh",
+	})
+	require.NoError(t, err)
+
+	sock := filepath.Join(t.TempDir(), "agent.sock")
+	l, err := net.Listen("unix", sock)
+	require.NoError(t, err)
+	defer l.Close()
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					t.Logf("accept: %s", err)
+				}
+				return
+			}
+			go func() {
+				defer conn.Close()
+				err := agent.ServeAgent(sshAgent, conn)
+				if err != nil && !errors.Is(err, io.EOF) {
+					t.Logf("serve agent: %s", err)
+				}
+			}()
+		}
+	}()
+
+	src := c.Directory().
+		WithNewFile("Dockerfile", `FROM `+alpineImage+`
+RUN apk add --no-cache openssh-client
+RUN --mount=type=ssh ssh-add -l > /keys
+`)
+
+	keys, err := c.Container().Build(src, dagger.ContainerBuildOpts{
+		SSH: c.Host().UnixSocket(sock),
+	}).File("/keys").Contents(ctx)
+	require.NoError(t, err)
+	require.Contains(t, keys, "dagger-build-ssh")
+
+	t.Run("without a socket", func(t *testing.T) {
+		_, err := c.Container().Build(src).Sync(ctx)
+		require.Error(t, err)
+	})
+}
+
+func TestContainerBuildCacheFrom(t *testing.T) {
+	t.Parallel()
+	c, ctx := connect(t)
+
+	src := c.Directory().
+		WithNewFile("Dockerfile", `FROM `+alpineImage+`
+RUN echo hello > /hello
+`)
+
+	cacheRef, err := c.Container().Build(src).Publish(ctx, registryRef("container-build-cache-from"))
+	require.NoError(t, err)
+
+	// cache sources are only hints, so ones that don't exist are skipped
+	hello, err := c.Container().Build(src, dagger.ContainerBuildOpts{
+		CacheFrom: []string{
+			cacheRef,
+			registryRef("container-build-cache-from-missing"),
+		},
+	}).File("/hello").Contents(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "hello\n", hello)
+}
+
 func TestContainerInsecureRootCapabilites(t *testing.T) {
 	c, ctx := connect(t)
 
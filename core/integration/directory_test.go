@@ -97,6 +97,103 @@ func TestDirectoryEntries(t *testing.T) {
 	require.ElementsMatch(t, []string{"some-file", "some-dir"}, res.Directory.WithNewFile.WithNewFile.Entries)
 }
 
+func TestDirectoryGlob(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	dir := c.Directory().
+		WithNewFile("main.go", "package main").
+		WithNewFile("README.md", "# hello").
+		WithNewFile("pkg/util/util.go", "package util").
+		WithNewFile("pkg/util/util_test.go", "package util").
+		WithNewFile("web/index.ts", "export {}")
+
+	for _, tc := range []struct {
+		pattern string
+		want    []string
+	}{
+		{"*.go", []string{"main.go"}},
+		{"**/*.go", []string{"main.go", "pkg/util/util.go", "pkg/util/util_test.go"}},
+		{"pkg/**", []string{"pkg", "pkg/util", "pkg/util/util.go", "pkg/util/util_test.go"}},
+		{"pkg/*/*_test.go", []string{"pkg/util/util_test.go"}},
+		{"*", []string{"README.md", "main.go", "pkg", "web"}},
+		{"**/*.rs", []string{}},
+	} {
+		tc := tc
+		t.Run(tc.pattern, func(t *testing.T) {
+			paths, err := dir.Glob(ctx, tc.pattern)
+			require.NoError(t, err)
+			require.ElementsMatch(t, tc.want, paths)
+		})
+	}
+
+	t.Run("relative to subdirectory", func(t *testing.T) {
+		paths, err := dir.Directory("pkg").Glob(ctx, "**/*.go")
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"util/util.go", "util/util_test.go"}, paths)
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		_, err := dir.Glob(ctx, "[")
+		require.ErrorContains(t, err, "invalid pattern")
+	})
+}
+
+func TestDirectoryStat(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	dir := c.Container().
+		From(alpineImage).
+		WithExec([]string{"sh", "-c", "mkdir -p /out/sub && echo hi > /out/file && chmod 0640 /out/file && chown 1000:1001 /out/file && ln -s file /out/link && touch -d @1672531199 /out/file"}).
+		Directory("/out")
+
+	stat := dir.Stat("file")
+
+	typ, err := stat.FileType(ctx)
+	require.NoError(t, err)
+	require.Equal(t, dagger.Regular, typ)
+
+	size, err := stat.Size(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 3, size)
+
+	perms, err := stat.Permissions(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 0o640, perms)
+
+	uid, err := stat.UID(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1000, uid)
+
+	gid, err := stat.Gid(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1001, gid)
+
+	mtime, err := stat.ModifiedAt(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1672531199, mtime)
+
+	typ, err = dir.Stat("sub").FileType(ctx)
+	require.NoError(t, err)
+	require.Equal(t, dagger.Dir, typ)
+
+	link := dir.Stat("link")
+	typ, err = link.FileType(ctx)
+	require.NoError(t, err)
+	require.Equal(t, dagger.Symlink, typ)
+
+	target, err := link.SymlinkTarget(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "file", target)
+
+	fileType, err := dir.File("file").Stat().FileType(ctx)
+	require.NoError(t, err)
+	require.Equal(t, dagger.Regular, fileType)
+}
+
 func TestDirectoryEntriesOfPath(t *testing.T) {
 	t.Parallel()
 
@@ -926,3 +1023,270 @@ func TestDirectorySync(t *testing.T) {
 		require.Equal(t, []string{"foo"}, entries)
 	})
 }
+
+func TestDirectoryWithPatch(t *testing.T) {
+	t.Parallel()
+	c, ctx := connect(t)
+
+	dir := c.Directory().
+		WithNewFile("src/main.txt", "one\ntwo\nthree\n").
+		WithNewFile("old.txt", "bye\n")
+
+	patch := c.Directory().WithNewFile("fix.patch", `diff --git a/src/main.txt b/src/main.txt
+--- a/src/main.txt
++++ b/src/main.txt
+@@ -1,3 +1,3 @@
+ one
+-two
++dos
+ three
+diff --git a/new.txt b/new.txt
+new file mode 100644
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1 @@
++hello
+diff --git a/old.txt b/old.txt
+deleted file mode 100644
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+`).File("fix.patch")
+
+	t.Run("applies changes", func(t *testing.T) {
+		patched := dir.WithPatch(patch)
+
+		contents, err := patched.File("src/main.txt").Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "one\ndos\nthree\n", contents)
+
+		contents, err = patched.File("new.txt").Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "hello\n", contents)
+
+		entries, err := patched.Entries(ctx)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"src", "new.txt"}, entries)
+	})
+
+	t.Run("applies changes in order", func(t *testing.T) {
+		renamed := c.Directory().WithNewFile("rename.patch", `diff --git a/src/main.txt b/src/renamed.txt
+similarity index 66%
+rename from src/main.txt
+rename to src/renamed.txt
+--- a/src/main.txt
++++ b/src/renamed.txt
+@@ -1,3 +1,3 @@
+ one
+-two
++dos
+ three
+diff --git a/src/renamed.txt b/src/renamed.txt
+--- a/src/renamed.txt
++++ b/src/renamed.txt
+@@ -1,3 +1,3 @@
+-one
++uno
+ dos
+ three
+`).File("rename.patch")
+
+		patched := dir.WithPatch(renamed)
+
+		contents, err := patched.File("src/renamed.txt").Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "uno\ndos\nthree\n", contents)
+
+		entries, err := patched.Entries(ctx, dagger.DirectoryEntriesOpts{Path: "src"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"renamed.txt"}, entries)
+	})
+
+	t.Run("fails on mismatched context", func(t *testing.T) {
+		_, err := dir.WithNewFile("src/main.txt", "uno\ndos\ntres\n").
+			WithPatch(patch).
+			Entries(ctx)
+		require.ErrorContains(t, err, "hunk #1 does not apply to src/main.txt")
+	})
+}
+
+func TestDirectoryChanges(t *testing.T) {
+	t.Parallel()
+	c, ctx := connect(t)
+
+	before := c.Directory().
+		WithNewFile("same.txt", "same").
+		WithNewFile("changed.txt", "before").
+		WithNewFile("chmod.sh", "chmod").
+		WithNewFile("sub/gone.txt", "gone").
+		WithNewDirectory("gone-empty")
+
+	after := before.
+		WithNewFile("changed.txt", "after").
+		WithNewFile("chmod.sh", "chmod", dagger.DirectoryWithNewFileOpts{Permissions: 0o755}).
+		WithoutFile("sub/gone.txt").
+		WithNewFile("sub/added.txt", "added").
+		WithoutDirectory("gone-empty").
+		WithNewDirectory("added-empty")
+
+	changes, err := before.Changes(ctx, after)
+	require.NoError(t, err)
+
+	type change struct {
+		path, before, after string
+		kind                dagger.ChangeKind
+	}
+	var got []change
+	for _, ch := range changes {
+		path, err := ch.Path(ctx)
+		require.NoError(t, err)
+		kind, err := ch.Kind(ctx)
+		require.NoError(t, err)
+		beforeDigest, err := ch.BeforeDigest(ctx)
+		require.NoError(t, err)
+		afterDigest, err := ch.AfterDigest(ctx)
+		require.NoError(t, err)
+		got = append(got, change{path, beforeDigest, afterDigest, kind})
+	}
+
+	// the digests are the same as the API's own
+	fileDigest := func(dir *dagger.Directory, path string) string {
+		dgst, err := dir.File(path).Digest(ctx, dagger.FileDigestOpts{IgnoreTimestamps: true})
+		require.NoError(t, err)
+		return dgst
+	}
+	dirDigest := func(dir *dagger.Directory, path string) string {
+		dgst, err := dir.Directory(path).Digest(ctx, dagger.DirectoryDigestOpts{IgnoreTimestamps: true})
+		require.NoError(t, err)
+		return dgst
+	}
+
+	require.Equal(t, []change{
+		{"added-empty/", "", dirDigest(after, "added-empty"), dagger.Added},
+		{"changed.txt", fileDigest(before, "changed.txt"), fileDigest(after, "changed.txt"), dagger.Modified},
+		{"chmod.sh", fileDigest(before, "chmod.sh"), fileDigest(after, "chmod.sh"), dagger.Modified},
+		{"gone-empty/", dirDigest(before, "gone-empty"), "", dagger.Deleted},
+		{"sub/added.txt", "", fileDigest(after, "sub/added.txt"), dagger.Added},
+		{"sub/gone.txt", fileDigest(before, "sub/gone.txt"), "", dagger.Deleted},
+	}, got)
+
+	changes, err = after.Changes(ctx, after)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+}
+
+func TestDirectoryDigest(t *testing.T) {
+	t.Parallel()
+	c, ctx := connect(t)
+
+	opts := dagger.DirectoryDigestOpts{IgnoreTimestamps: true}
+
+	dir := c.Directory().
+		WithNewFile("a.txt", "a").
+		WithNewFile("sub/b.txt", "b")
+
+	// same contents, built differently
+	sameDir := c.Directory().
+		WithNewFile("sub/b.txt", "b").
+		WithNewFile("extra.txt", "extra").
+		WithNewFile("a.txt", "a").
+		WithoutFile("extra.txt")
+
+	dgst, err := dir.Digest(ctx, opts)
+	require.NoError(t, err)
+	require.Regexp(t, `^sha256:[0-9a-f]{64}$`, dgst)
+
+	sameDgst, err := sameDir.Digest(ctx, opts)
+	require.NoError(t, err)
+	require.Equal(t, dgst, sameDgst)
+
+	otherDgst, err := dir.WithNewFile("sub/b.txt", "B").Digest(ctx, opts)
+	require.NoError(t, err)
+	require.NotEqual(t, dgst, otherDgst)
+
+	permsDgst, err := dir.WithNewFile("a.txt", "a", dagger.DirectoryWithNewFileOpts{
+		Permissions: 0o755,
+	}).Digest(ctx, opts)
+	require.NoError(t, err)
+	require.NotEqual(t, dgst, permsDgst)
+
+	stamped := dir.WithTimestamps(0)
+	stampedDgst, err := stamped.Digest(ctx)
+	require.NoError(t, err)
+	restampedDgst, err := sameDir.WithTimestamps(0).Digest(ctx)
+	require.NoError(t, err)
+	require.Equal(t, stampedDgst, restampedDgst)
+
+	laterDgst, err := dir.WithTimestamps(1672531199).Digest(ctx)
+	require.NoError(t, err)
+	require.NotEqual(t, stampedDgst, laterDgst)
+}
+
+func TestDirectoryAsTarball(t *testing.T) {
+	t.Parallel()
+	c, ctx := connect(t)
+
+	dir := c.Directory().
+		WithNewFile("a.txt", "a").
+		WithNewFile("sub/run.sh", "#!/bin/sh\n", dagger.DirectoryWithNewFileOpts{
+			Permissions: 0o755,
+		})
+
+	for _, compression := range []dagger.ImageLayerCompression{
+		dagger.Uncompressed,
+		dagger.Gzip,
+		dagger.Zstd,
+	} {
+		compression := compression
+		t.Run(string(compression), func(t *testing.T) {
+			tarball := dir.AsTarball(dagger.DirectoryAsTarballOpts{
+				Compression: compression,
+			})
+
+			ls, err := c.Container().
+				From(alpineImage).
+				// GNU tar detects the compression
+				WithExec([]string{"apk", "add", "tar", "gzip", "zstd"}).
+				WithMountedFile("/archive", tarball).
+				WithExec([]string{"tar", "-tvf", "/archive"}).
+				Stdout(ctx)
+			require.NoError(t, err)
+			require.Contains(t, ls, "a.txt")
+			require.Regexp(t, `-rwxr-xr-x .* sub/run.sh`, ls)
+
+			entries, err := tarball.Unpack().Entries(ctx, dagger.DirectoryEntriesOpts{Path: "sub"})
+			require.NoError(t, err)
+			require.Equal(t, []string{"run.sh"}, entries)
+		})
+	}
+
+	t.Run("EStarGZ", func(t *testing.T) {
+		_, err := dir.AsTarball(dagger.DirectoryAsTarballOpts{
+			Compression: dagger.Estargz,
+		}).Contents(ctx)
+		require.ErrorContains(t, err, "unsupported tarball compression")
+	})
+}
+
+func TestDirectoryAsZip(t *testing.T) {
+	t.Parallel()
+	c, ctx := connect(t)
+
+	zip := c.Directory().
+		WithNewFile("a.txt", "a").
+		WithNewFile("sub/b.txt", "b").
+		AsZip()
+
+	out, err := c.Container().
+		From(alpineImage).
+		WithMountedFile("/archive.zip", zip).
+		WithExec([]string{"unzip", "-p", "/archive.zip", "sub/b.txt"}).
+		Stdout(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "b", out)
+
+	contents, err := zip.Unpack().File("a.txt").Contents(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "a", contents)
+}
@@ -319,3 +319,97 @@ func TestFileSync(t *testing.T) {
 		require.Equal(t, "bar", contents)
 	})
 }
+
+func TestFileWithReplaced(t *testing.T) {
+	t.Parallel()
+	c, ctx := connect(t)
+
+	file := c.Directory().
+		WithNewFile("version.txt", "version={{VERSION}}\nrelease={{VERSION}}\n", dagger.DirectoryWithNewFileOpts{
+			Permissions: 0o755,
+		}).
+		File("version.txt").
+		WithReplaced("{{VERSION}}", "v1.2.3")
+
+	contents, err := file.Contents(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "version=v1.2.3\nrelease=v1.2.3\n", contents)
+
+	stat, err := file.Stat().Permissions(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 0o755, stat)
+
+	_, err = file.WithReplaced("", "nope").Contents(ctx)
+	require.ErrorContains(t, err, "search string must not be empty")
+}
+
+func TestFileWithTemplate(t *testing.T) {
+	t.Parallel()
+	c, ctx := connect(t)
+
+	file := c.Directory().
+		WithNewFile("greeting.txt", "Hello, {{.name}}! You are {{.age}}.\n").
+		File("greeting.txt")
+
+	contents, err := file.WithTemplate([]dagger.TemplateVariable{
+		{Name: "name", Value: "Marty"},
+		{Name: "age", Value: "17"},
+	}).Contents(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "Hello, Marty! You are 17.\n", contents)
+
+	_, err = file.WithTemplate([]dagger.TemplateVariable{
+		{Name: "name", Value: "Doc"},
+	}).Contents(ctx)
+	require.ErrorContains(t, err, `map has no entry for key "age"`)
+}
+
+func TestFileDigest(t *testing.T) {
+	t.Parallel()
+	c, ctx := connect(t)
+
+	opts := dagger.FileDigestOpts{IgnoreTimestamps: true}
+
+	dgst, err := c.Directory().
+		WithNewFile("a.txt", "hello").
+		File("a.txt").
+		Digest(ctx, opts)
+	require.NoError(t, err)
+
+	// same contents at a different path and from a different pipeline
+	sameDgst, err := c.Container().
+		From(alpineImage).
+		WithExec([]string{"sh", "-c", "printf hello > /b.txt && chmod 644 /b.txt"}).
+		File("/b.txt").
+		Digest(ctx, opts)
+	require.NoError(t, err)
+	require.Equal(t, dgst, sameDgst)
+
+	otherDgst, err := c.Directory().
+		WithNewFile("a.txt", "goodbye").
+		File("a.txt").
+		Digest(ctx, opts)
+	require.NoError(t, err)
+	require.NotEqual(t, dgst, otherDgst)
+}
+
+func TestFileUnpack(t *testing.T) {
+	t.Parallel()
+	c, ctx := connect(t)
+
+	tarball := c.Container().
+		From(alpineImage).
+		WithExec([]string{"sh", "-c", "mkdir -p /src/sub && echo hello > /src/sub/hello.txt && tar -czf /src.tar.gz -C /src ."}).
+		File("/src.tar.gz")
+
+	contents, err := tarball.Unpack().File("sub/hello.txt").Contents(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "hello\n", contents)
+
+	_, err = c.Directory().
+		WithNewFile("nope.txt", "this is not an archive, just a file with enough text to fill a header").
+		File("nope.txt").
+		Unpack().
+		Entries(ctx)
+	require.ErrorContains(t, err, "unrecognized archive format")
+}
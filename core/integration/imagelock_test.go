@@ -0,0 +1,135 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dagger.io/dagger"
+	"github.com/dagger/dagger/engine"
+	"github.com/dagger/dagger/engine/client"
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+)
+
+func connectEngineWithLock(t *testing.T, mode engine.ImageLockMode, lockPath string) (*client.Client, context.Context) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c, ctx, err := client.Connect(ctx, client.Params{
+		RunnerHost:    engine.RunnerHost(),
+		ImageLockMode: mode,
+		ImageLockPath: lockPath,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+
+	return c, ctx
+}
+
+func writeImageLock(t *testing.T, images map[string]digest.Digest) string {
+	t.Helper()
+	content, err := (&engine.ImageLock{Images: images}).MarshalFile()
+	require.NoError(t, err)
+	lockPath := filepath.Join(t.TempDir(), engine.ImageLockFile)
+	require.NoError(t, os.WriteFile(lockPath, content, 0o600))
+	return lockPath
+}
+
+// publishMarked publishes an image to ref with a /marker file holding the
+// given contents, returning the digest it was published with.
+func publishMarked(ctx context.Context, t *testing.T, c *dagger.Client, ref, marker string) digest.Digest {
+	t.Helper()
+	published, err := c.Container().
+		From(alpineImage).
+		WithNewFile("/marker", dagger.ContainerWithNewFileOpts{Contents: marker}).
+		Publish(ctx, ref)
+	require.NoError(t, err)
+	_, dgst, found := strings.Cut(published, "@")
+	require.True(t, found, published)
+	return digest.Digest(dgst)
+}
+
+func TestImageLock(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	type fromRes struct {
+		Container struct {
+			From struct {
+				ImageRef string
+				File     struct {
+					Contents string
+				}
+			}
+		}
+	}
+	const fromQuery = `query From($ref: String!) {
+		container {
+			from(address: $ref) {
+				imageRef
+				file(path: "/marker") {
+					contents
+				}
+			}
+		}
+	}`
+
+	t.Run("from resolves to the locked digest", func(t *testing.T) {
+		ref := registryRef("image-lock-pinned")
+		lockedDigest := publishMarked(ctx, t, c, ref, "locked")
+		// move the tag on, so that resolving it with the registry would
+		// give another image
+		publishMarked(ctx, t, c, ref, "latest")
+
+		lockPath := writeImageLock(t, map[string]digest.Digest{ref: lockedDigest})
+
+		for _, mode := range []engine.ImageLockMode{engine.ImageLockModeRecord, engine.ImageLockModeStrict} {
+			engineClient, ctx := connectEngineWithLock(t, mode, lockPath)
+
+			var res fromRes
+			err := engineClient.Do(ctx, fromQuery, "From", map[string]any{"ref": ref}, &res)
+			require.NoError(t, err, mode)
+			require.True(t, strings.HasSuffix(res.Container.From.ImageRef, "@"+lockedDigest.String()), res.Container.From.ImageRef)
+			require.Equal(t, "locked", res.Container.From.File.Contents, mode)
+		}
+	})
+
+	t.Run("strict mode refuses an unlocked ref", func(t *testing.T) {
+		lockedRef := registryRef("image-lock-locked")
+		lockedDigest := publishMarked(ctx, t, c, lockedRef, "locked")
+
+		unlockedRef := registryRef("image-lock-unlocked")
+		publishMarked(ctx, t, c, unlockedRef, "unlocked")
+
+		lockPath := writeImageLock(t, map[string]digest.Digest{lockedRef: lockedDigest})
+		engineClient, ctx := connectEngineWithLock(t, engine.ImageLockModeStrict, lockPath)
+
+		var res fromRes
+		err := engineClient.Do(ctx, fromQuery, "From", map[string]any{"ref": unlockedRef}, &res)
+		require.ErrorContains(t, err, "is not in the lockfile")
+	})
+
+	t.Run("record mode writes the resolved digest", func(t *testing.T) {
+		ref := registryRef("image-lock-record")
+		publishedDigest := publishMarked(ctx, t, c, ref, "recorded")
+
+		lockPath := filepath.Join(t.TempDir(), engine.ImageLockFile)
+		engineClient, ctx := connectEngineWithLock(t, engine.ImageLockModeRecord, lockPath)
+
+		var res fromRes
+		err := engineClient.Do(ctx, fromQuery, "From", map[string]any{"ref": ref}, &res)
+		require.NoError(t, err)
+		require.NoError(t, engineClient.Close())
+
+		lock, err := engine.ReadImageLock(lockPath, engine.ImageLockModeStrict)
+		require.NoError(t, err)
+		pinned, found, err := lock.Pinned(ref)
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, publishedDigest, pinned)
+	})
+}
@@ -16,6 +16,9 @@ import (
 	"context"
 	_ "embed"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -398,6 +401,323 @@ func TestContainerExecServicesSimple(t *testing.T) {
 	})
 }
 
+func TestServiceStartStop(t *testing.T) {
+	t.Parallel()
+	c, ctx := connect(t)
+
+	// serve a value that changes every time the service starts
+	srvCtr := c.Container().
+		From("python").
+		WithWorkdir("/srv/www").
+		WithExposedPort(8000).
+		WithExec([]string{"sh", "-c", "cat /proc/sys/kernel/random/uuid > index.html && python -m http.server"})
+
+	srv := srvCtr.AsService()
+
+	hostname, err := srv.Hostname(ctx)
+	require.NoError(t, err)
+
+	ports, err := srv.Ports(ctx)
+	require.NoError(t, err)
+	require.Len(t, ports, 1)
+	port, err := ports[0].Port(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 8000, port)
+
+	url, err := srv.Endpoint(ctx, dagger.ServiceEndpointOpts{Scheme: "http"})
+	require.NoError(t, err)
+	require.Equal(t, "http://"+hostname+":8000", url)
+
+	fetch := func() string {
+		t.Helper()
+		out, err := c.Container().
+			From(alpineImage).
+			WithServiceBinding("www", srvCtr).
+			WithEnvVariable("BUST", identity.NewID()).
+			WithExec([]string{"wget", "-q", "-O-", "http://www:8000"}).
+			Stdout(ctx)
+		require.NoError(t, err)
+		return out
+	}
+
+	_, err = srv.Start(ctx)
+	require.NoError(t, err)
+
+	// starting again is a no-op
+	_, err = srv.Start(ctx)
+	require.NoError(t, err)
+
+	first := fetch()
+	require.NotEmpty(t, first)
+	require.Equal(t, first, fetch())
+
+	_, err = srv.Stop(ctx)
+	require.NoError(t, err)
+
+	_, err = srv.Start(ctx)
+	require.NoError(t, err)
+
+	require.NotEqual(t, first, fetch())
+
+	_, err = srv.Stop(ctx)
+	require.NoError(t, err)
+}
+
+func TestServiceHealthcheck(t *testing.T) {
+	t.Parallel()
+	c, ctx := connect(t)
+
+	// accepts connections right away, but only serves /ready once it exists
+	srvCtr := c.Container().
+		From("python").
+		WithWorkdir("/srv/www").
+		WithExposedPort(8000).
+		WithExec([]string{"sh", "-c", "python -m http.server & sleep 3 && echo ok > ready && wait"})
+
+	fetch := func(svc *dagger.Service) (string, error) {
+		return c.Container().
+			From(alpineImage).
+			WithBoundService("www", svc).
+			WithEnvVariable("BUST", identity.NewID()).
+			WithExec([]string{"wget", "-q", "-O-", "http://www:8000/ready"}).
+			Stdout(ctx)
+	}
+
+	t.Run("http", func(t *testing.T) {
+		svc := srvCtr.AsService().WithHealthcheck(dagger.ServiceWithHealthcheckOpts{
+			HTTPPath: "/ready",
+			Interval: "100ms",
+		})
+
+		out, err := fetch(svc)
+		require.NoError(t, err)
+		require.Equal(t, "ok\n", out)
+	})
+
+	t.Run("exec", func(t *testing.T) {
+		svc := srvCtr.
+			WithEnvVariable("BUST", identity.NewID()).
+			AsService().
+			WithHealthcheck(dagger.ServiceWithHealthcheckOpts{
+				Exec:     []string{"test", "-f", "ready"},
+				Interval: "100ms",
+			})
+
+		out, err := fetch(svc)
+		require.NoError(t, err)
+		require.Equal(t, "ok\n", out)
+	})
+
+	t.Run("image healthcheck", func(t *testing.T) {
+		svc := srvCtr.
+			WithEnvVariable("BUST", identity.NewID()).
+			WithHealthcheck([]string{"CMD-SHELL", "test -f ready"}, dagger.ContainerWithHealthcheckOpts{
+				Interval: "100ms",
+			}).
+			AsService().
+			WithHealthcheck(dagger.ServiceWithHealthcheckOpts{Image: true})
+
+		out, err := fetch(svc)
+		require.NoError(t, err)
+		require.Equal(t, "ok\n", out)
+	})
+
+	t.Run("image healthcheck is opt-in", func(t *testing.T) {
+		// the image's check can never pass, but the service only waits for
+		// its port by default
+		svc := srvCtr.
+			WithEnvVariable("BUST", identity.NewID()).
+			WithHealthcheck([]string{"CMD", "false"}).
+			AsService()
+
+		_, err := svc.Start(ctx)
+		require.NoError(t, err)
+		_, err = svc.Stop(ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("failing image healthcheck", func(t *testing.T) {
+		// alpine has no curl, so the check can never pass; it gives up after
+		// Docker's default of 3 retries rather than waiting for the service
+		// to exit
+		svc := c.Container().
+			From(alpineImage).
+			WithEnvVariable("BUST", identity.NewID()).
+			WithExec([]string{"sleep", "300"}).
+			WithHealthcheck([]string{"CMD", "curl", "-f", "http://localhost:8000"}, dagger.ContainerWithHealthcheckOpts{
+				Interval: "100ms",
+			}).
+			AsService().
+			WithHealthcheck(dagger.ServiceWithHealthcheckOpts{Image: true})
+
+		_, err := svc.Start(ctx)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unhealthy after 3 checks")
+	})
+
+	t.Run("retries", func(t *testing.T) {
+		svc := srvCtr.
+			WithEnvVariable("BUST", identity.NewID()).
+			AsService().
+			WithHealthcheck(dagger.ServiceWithHealthcheckOpts{
+				HTTPPath: "/never",
+				Interval: "100ms",
+				Retries:  3,
+			})
+
+		_, err := svc.Start(ctx)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unhealthy after 3 checks")
+	})
+}
+
+func TestServiceLogs(t *testing.T) {
+	t.Parallel()
+	c, ctx := connect(t)
+
+	// passes its health check, then crashes
+	svc := c.Container().
+		From("python").
+		WithEnvVariable("BUST", identity.NewID()).
+		WithExposedPort(8000).
+		WithExec([]string{"sh", "-c", `echo starting; echo warming up >&2; python -m http.server >/dev/null 2>&1 & sleep 3; echo crashing >&2; exit 3`}).
+		AsService()
+
+	started := time.Now().Add(-time.Second).UTC().Format(time.RFC3339)
+
+	_, err := svc.Start(ctx)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		code, err := svc.ExitCode(ctx)
+		require.NoError(t, err)
+		return code == 3
+	}, time.Minute, 100*time.Millisecond)
+
+	// stdout and stderr are interleaved in the order they're received
+	logs, err := svc.Logs(ctx)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"starting", "warming up", "crashing"}, strings.Split(strings.TrimSuffix(logs, "\n"), "\n"))
+	require.True(t, strings.HasSuffix(logs, "crashing\n"))
+
+	logs, err = svc.Logs(ctx, dagger.ServiceLogsOpts{Tail: 1})
+	require.NoError(t, err)
+	require.Equal(t, "crashing\n", logs)
+
+	logs, err = svc.Logs(ctx, dagger.ServiceLogsOpts{Since: started})
+	require.NoError(t, err)
+	require.Contains(t, logs, "starting\n")
+
+	logs, err = svc.Logs(ctx, dagger.ServiceLogsOpts{Since: "1h"})
+	require.NoError(t, err)
+	require.Contains(t, logs, "crashing\n")
+
+	// logs are kept after the service stops
+	_, err = svc.Stop(ctx)
+	require.NoError(t, err)
+
+	logs, err = svc.Logs(ctx, dagger.ServiceLogsOpts{Tail: 1})
+	require.NoError(t, err)
+	require.Equal(t, "crashing\n", logs)
+}
+
+func TestServiceHostReverseTunnel(t *testing.T) {
+	t.Parallel()
+	c, ctx := connect(t)
+
+	content := identity.NewID()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	go http.Serve(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, content)
+	}))
+
+	port := l.Addr().(*net.TCPAddr).Port
+
+	fetch := func(svc *dagger.Service, url string) (string, error) {
+		return c.Container().
+			From(alpineImage).
+			WithBoundService("myhost", svc).
+			WithEnvVariable("BUST", identity.NewID()).
+			WithExec([]string{"wget", "-q", "-O-", url}).
+			Stdout(ctx)
+	}
+
+	t.Run("same port", func(t *testing.T) {
+		svc := c.Host().Service([]dagger.PortForward{
+			{Backend: port, Protocol: dagger.Tcp},
+		})
+
+		out, err := fetch(svc, fmt.Sprintf("http://myhost:%d", port))
+		require.NoError(t, err)
+		require.Equal(t, content, out)
+	})
+
+	t.Run("different port", func(t *testing.T) {
+		svc := c.Host().Service([]dagger.PortForward{
+			{Frontend: 80, Backend: port, Protocol: dagger.Tcp},
+		})
+
+		out, err := fetch(svc, "http://myhost")
+		require.NoError(t, err)
+		require.Equal(t, content, out)
+	})
+}
+
+func TestServiceHostTunnel(t *testing.T) {
+	t.Parallel()
+	c, ctx := connect(t)
+
+	content := identity.NewID()
+	srv, _ := httpService(ctx, t, c, content)
+
+	fetch := func(url string) string {
+		t.Helper()
+		res, err := http.Get(url)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	t.Run("exposed ports", func(t *testing.T) {
+		tunnel, err := c.Host().Tunnel(srv.AsService()).Start(ctx)
+		require.NoError(t, err)
+
+		url, err := tunnel.Endpoint(ctx, dagger.ServiceEndpointOpts{Scheme: "http"})
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(url, "http://localhost:"), url)
+		require.NotEqual(t, "http://localhost:8000", url)
+
+		require.Equal(t, content, fetch(url))
+
+		_, err = tunnel.Stop(ctx)
+		require.NoError(t, err)
+
+		_, err = http.Get(url)
+		require.Error(t, err)
+	})
+
+	t.Run("configured ports", func(t *testing.T) {
+		tunnel, err := c.Host().Tunnel(srv.AsService(), dagger.HostTunnelOpts{
+			Ports: []dagger.PortForward{{Backend: 8000, Protocol: dagger.Tcp}},
+		}).Start(ctx)
+		require.NoError(t, err)
+
+		url, err := tunnel.Endpoint(ctx, dagger.ServiceEndpointOpts{Scheme: "http"})
+		require.NoError(t, err)
+		require.Equal(t, content, fetch(url))
+
+		_, err = tunnel.Stop(ctx)
+		require.NoError(t, err)
+	})
+}
+
 func TestContainerExecServicesError(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,67 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/dagger/dagger/engine"
+	"github.com/dagger/dagger/engine/client"
+	"github.com/stretchr/testify/require"
+)
+
+func connectEngine(t *testing.T) (*client.Client, context.Context) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c, ctx, err := client.Connect(ctx, client.Params{
+		RunnerHost: engine.RunnerHost(),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+
+	return c, ctx
+}
+
+func TestContainerTerminal(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connectEngine(t)
+
+	var res struct {
+		Container struct {
+			From struct {
+				WithEnvVariable struct {
+					Terminal struct {
+						ID                string
+						WebsocketEndpoint string
+					}
+				}
+			}
+		}
+	}
+	err := c.Do(ctx, `{
+		container {
+			from(address: "`+alpineImage+`") {
+				withEnvVariable(name: "FOO", value: "bar") {
+					terminal(cmd: ["sh", "-c", "echo $FOO; read line; echo got $line; exit 3"]) {
+						id
+						websocketEndpoint
+					}
+				}
+			}
+		}
+	}`, "", nil, &res)
+	require.NoError(t, err)
+
+	term := res.Container.From.WithEnvVariable.Terminal
+	require.Equal(t, "ws://dagger/terminals/"+term.ID, term.WebsocketEndpoint)
+
+	out := new(bytes.Buffer)
+	exitCode, err := c.AttachTerminal(ctx, term.ID, strings.NewReader("hello\n"), out, out, nil)
+	require.NoError(t, err)
+	require.Equal(t, 3, exitCode)
+	require.Contains(t, out.String(), "bar")
+	require.Contains(t, out.String(), "got hello")
+}
@@ -0,0 +1,163 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/docker/go-units"
+	"github.com/moby/buildkit/client/llb"
+	runtimespecs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// ResourceLimitsEnv is the env var that carries an exec's resource limits to
+// the shim, which applies them to the OCI spec passed to runc.
+const ResourceLimitsEnv = "_DAGGER_RESOURCE_LIMITS"
+
+// cpuPeriod is the CFS period that CPU quotas are relative to, in
+// microseconds.
+const cpuPeriod = 100000
+
+// Resource limits that an exec can fail for exceeding.
+const (
+	LimitTimeout = "timeout"
+	LimitMemory  = "memory"
+	LimitPids    = "pids"
+)
+
+// ResourceLimits caps the resources available to an exec. Zero values mean
+// no limit.
+type ResourceLimits struct {
+	// CPU time available, in thousandths of a CPU.
+	MilliCPUs int `json:"milliCPUs,omitempty"`
+
+	// Maximum memory, in bytes or with a unit suffix, e.g. "512MiB".
+	Memory string `json:"memory,omitempty"`
+
+	// Maximum number of processes.
+	Pids int `json:"pids,omitempty"`
+
+	// Wall-clock time in seconds after which the exec is killed.
+	Timeout int `json:"timeout,omitempty"`
+}
+
+// IsZero returns true if no limit is set.
+func (limits ResourceLimits) IsZero() bool {
+	return limits == ResourceLimits{}
+}
+
+// Merge returns the limits with any limit set in other taking precedence.
+func (limits ResourceLimits) Merge(other ResourceLimits) ResourceLimits {
+	if other.MilliCPUs != 0 {
+		limits.MilliCPUs = other.MilliCPUs
+	}
+	if other.Memory != "" {
+		limits.Memory = other.Memory
+	}
+	if other.Pids != 0 {
+		limits.Pids = other.Pids
+	}
+	if other.Timeout != 0 {
+		limits.Timeout = other.Timeout
+	}
+	return limits
+}
+
+// Validate returns an error if any of the limits are invalid.
+func (limits ResourceLimits) Validate() error {
+	if limits.MilliCPUs < 0 {
+		return fmt.Errorf("invalid CPU limit %d: must not be negative", limits.MilliCPUs)
+	}
+	if limits.Pids < 0 {
+		return fmt.Errorf("invalid pids limit %d: must not be negative", limits.Pids)
+	}
+	if limits.Timeout < 0 {
+		return fmt.Errorf("invalid timeout %d: must not be negative", limits.Timeout)
+	}
+	if _, err := limits.MemoryBytes(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MemoryBytes returns the memory limit in bytes, or 0 if there is none.
+func (limits ResourceLimits) MemoryBytes() (int64, error) {
+	if limits.Memory == "" {
+		return 0, nil
+	}
+	bytes, err := units.RAMInBytes(limits.Memory)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory limit %q: %w", limits.Memory, err)
+	}
+	if bytes <= 0 {
+		return 0, fmt.Errorf("invalid memory limit %q: must be positive", limits.Memory)
+	}
+	return bytes, nil
+}
+
+// Apply sets the cgroup limits in the given OCI spec. The timeout is not
+// applied, since it's enforced by the shim.
+func (limits ResourceLimits) Apply(spec *runtimespecs.Spec) error {
+	memory, err := limits.MemoryBytes()
+	if err != nil {
+		return err
+	}
+
+	if limits.MilliCPUs == 0 && memory == 0 && limits.Pids == 0 {
+		return nil
+	}
+
+	if spec.Linux == nil {
+		spec.Linux = &runtimespecs.Linux{}
+	}
+	if spec.Linux.Resources == nil {
+		spec.Linux.Resources = &runtimespecs.LinuxResources{}
+	}
+	res := spec.Linux.Resources
+
+	if limits.MilliCPUs > 0 {
+		if res.CPU == nil {
+			res.CPU = &runtimespecs.LinuxCPU{}
+		}
+		period := uint64(cpuPeriod)
+		quota := int64(limits.MilliCPUs) * cpuPeriod / 1000
+		res.CPU.Period = &period
+		res.CPU.Quota = &quota
+	}
+
+	if memory > 0 {
+		if res.Memory == nil {
+			res.Memory = &runtimespecs.LinuxMemory{}
+		}
+		// setting swap to the same value disables swap, so that the limit
+		// actually limits
+		res.Memory.Limit = &memory
+		res.Memory.Swap = &memory
+	}
+
+	if limits.Pids > 0 {
+		res.Pids = &runtimespecs.LinuxPids{Limit: int64(limits.Pids)}
+	}
+
+	return nil
+}
+
+func (limits ResourceLimits) ToLLBRunOpt() (llb.RunOption, error) {
+	b, err := json.Marshal(limits)
+	if err != nil {
+		return nil, err
+	}
+
+	return llb.AddEnv(ResourceLimitsEnv, string(b)), nil
+}
+
+func (limits *ResourceLimits) FromEnv(envKV string) (bool, error) {
+	val, ok := strings.CutPrefix(envKV, ResourceLimitsEnv+"=")
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal([]byte(val), limits); err != nil {
+		return false, fmt.Errorf("invalid resource limits: %w", err)
+	}
+	return true, nil
+}
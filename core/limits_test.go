@@ -0,0 +1,66 @@
+package core
+
+import (
+	"testing"
+
+	runtimespecs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceLimitsApply(t *testing.T) {
+	t.Parallel()
+
+	t.Run("no limits", func(t *testing.T) {
+		spec := &runtimespecs.Spec{}
+		require.NoError(t, ResourceLimits{Timeout: 10}.Apply(spec))
+		require.Nil(t, spec.Linux)
+	})
+
+	t.Run("all limits", func(t *testing.T) {
+		spec := &runtimespecs.Spec{}
+		require.NoError(t, ResourceLimits{
+			MilliCPUs: 1500,
+			Memory:    "512MiB",
+			Pids:      100,
+		}.Apply(spec))
+
+		res := spec.Linux.Resources
+		require.Equal(t, uint64(100000), *res.CPU.Period)
+		require.Equal(t, int64(150000), *res.CPU.Quota)
+		require.Equal(t, int64(512*1024*1024), *res.Memory.Limit)
+		require.Equal(t, int64(512*1024*1024), *res.Memory.Swap)
+		require.Equal(t, int64(100), res.Pids.Limit)
+	})
+
+	t.Run("invalid memory", func(t *testing.T) {
+		err := ResourceLimits{Memory: "lots"}.Apply(&runtimespecs.Spec{})
+		require.ErrorContains(t, err, "invalid memory limit")
+	})
+}
+
+func TestResourceLimitsMerge(t *testing.T) {
+	t.Parallel()
+
+	merged := ResourceLimits{MilliCPUs: 500, Memory: "1g", Timeout: 60}.
+		Merge(ResourceLimits{Memory: "2g", Pids: 10})
+	require.Equal(t, ResourceLimits{MilliCPUs: 500, Memory: "2g", Pids: 10, Timeout: 60}, merged)
+}
+
+func TestResourceLimitsEnv(t *testing.T) {
+	t.Parallel()
+
+	limits := ResourceLimits{MilliCPUs: 250, Timeout: 5}
+	b, err := limits.ToLLBRunOpt()
+	require.NoError(t, err)
+	require.NotNil(t, b)
+
+	var parsed ResourceLimits
+	found, err := parsed.FromEnv(ResourceLimitsEnv + `={"milliCPUs":250,"timeout":5}`)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, limits, parsed)
+
+	found, err = parsed.FromEnv("PATH=/bin")
+	require.NoError(t, err)
+	require.False(t, found)
+}
@@ -27,3 +27,25 @@ func (proto NetworkProtocol) EnumName() string {
 func (proto NetworkProtocol) Network() string {
 	return strings.ToLower(string(proto))
 }
+
+// PortForward configures a port to forward from a frontend to a backend.
+type PortForward struct {
+	// Frontend is the port to listen on. If 0, a port is chosen at random when
+	// the forward is set up.
+	Frontend int `json:"frontend"`
+
+	// Backend is the port to forward connections to.
+	Backend int `json:"backend"`
+
+	// Protocol is the protocol of the forwarded port.
+	Protocol NetworkProtocol `json:"protocol"`
+}
+
+// FrontendOrBackendPort returns the frontend port, or the backend port if
+// the frontend port is not set.
+func (pf PortForward) FrontendOrBackendPort() int {
+	if pf.Frontend != 0 {
+		return pf.Frontend
+	}
+	return pf.Backend
+}
@@ -0,0 +1,160 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/moby/buildkit/client/llb"
+	bkgw "github.com/moby/buildkit/frontend/gateway/client"
+	"github.com/moby/buildkit/identity"
+	"github.com/moby/buildkit/util/leaseutil"
+	"github.com/opencontainers/go-digest"
+	specsgo "github.com/opencontainers/image-spec/specs-go"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/dagger/dagger/engine/buildkit"
+)
+
+// AsOCILayout returns the container and its platform variants as an OCI image
+// layout directory, as Export would write it to the host in a tarball.
+func (container *Container) AsOCILayout(
+	ctx context.Context,
+	bk *buildkit.Client,
+	svcs *Services,
+	platformVariants []ContainerID,
+	forcedCompression ImageLayerCompression,
+	mediaTypes ImageMediaTypes,
+	provenance bool,
+	sbom bool,
+	store content.Store,
+	lm *leaseutil.Manager,
+) (*Directory, error) {
+	inputByPlatform, services, opts, err := container.exportInputs(ctx, bk, platformVariants, forcedCompression, mediaTypes, provenance, sbom)
+	if err != nil {
+		return nil, err
+	}
+
+	detach, _, err := svcs.StartBindings(ctx, bk, services)
+	if err != nil {
+		return nil, err
+	}
+	defer detach()
+
+	ctx, release, err := leaseutil.WithLease(ctx, lm, leaseutil.MakeTemporary)
+	if err != nil {
+		return nil, err
+	}
+	defer release(context.Background())
+
+	// Write the tarball to the OCI store as the only layer of an image, which
+	// Buildkit can then unpack like any other image.
+	w, err := content.OpenWriter(ctx, store, content.WithRef("dagger-oci-layout-"+identity.NewID()))
+	if err != nil {
+		return nil, fmt.Errorf("open layout writer: %w", err)
+	}
+	defer w.Close()
+
+	if _, err := bk.ExportContainerImageTarball(ctx, inputByPlatform, w, opts); err != nil {
+		return nil, err
+	}
+	if err := w.Commit(ctx, 0, ""); err != nil && !errdefs.IsAlreadyExists(err) {
+		return nil, fmt.Errorf("commit layout: %w", err)
+	}
+	info, err := store.Info(ctx, w.Digest())
+	if err != nil {
+		return nil, fmt.Errorf("layout info: %w", err)
+	}
+
+	layerDesc := specs.Descriptor{
+		MediaType: specs.MediaTypeImageLayer,
+		Digest:    info.Digest,
+		Size:      info.Size,
+	}
+	configDesc, err := writeJSONBlob(ctx, store, specs.MediaTypeImageConfig, specs.Image{
+		Platform: container.Platform,
+		RootFS: specs.RootFS{
+			Type:    "layers",
+			DiffIDs: []digest.Digest{layerDesc.Digest},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	manifestDesc, err := writeJSONBlob(ctx, store, specs.MediaTypeImageManifest, specs.Manifest{
+		Versioned: specsgo.Versioned{SchemaVersion: 2},
+		MediaType: specs.MediaTypeImageManifest,
+		Config:    configDesc,
+		Layers:    []specs.Descriptor{layerDesc},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	st := llb.OCILayout(
+		fmt.Sprintf("dagger/oci-layout@%s", manifestDesc.Digest),
+		llb.OCIStore("", buildkit.OCIStoreName),
+		llb.Platform(container.Platform),
+	)
+
+	def, err := st.Marshal(ctx, llb.Platform(container.Platform))
+	if err != nil {
+		return nil, fmt.Errorf("marshal layout: %w", err)
+	}
+
+	// eagerly evaluate the OCI reference so Buildkit sets up a long-term lease
+	_, err = bk.Solve(ctx, bkgw.SolveRequest{
+		Definition: def.ToPB(),
+		Evaluate:   true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("solve: %w", err)
+	}
+
+	return NewDirectory(ctx, def.ToPB(), "/", container.Pipeline, container.Platform, nil), nil
+}
+
+// ImportOCILayout reads the container from an OCI image layout directory.
+func (container *Container) ImportOCILayout(
+	ctx context.Context,
+	dir *Directory,
+	tag string,
+	bk *buildkit.Client,
+	host *Host,
+	svcs *Services,
+	importCache *CacheMap[uint64, *specs.Descriptor],
+	store content.Store,
+	lm *leaseutil.Manager,
+) (*Container, error) {
+	tarball, err := dir.AsTarball(ctx, CompressionUncompressed)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := tarball.ID()
+	if err != nil {
+		return nil, err
+	}
+
+	return container.Import(ctx, id, tag, bk, host, svcs, importCache, store, lm)
+}
+
+func writeJSONBlob(ctx context.Context, store content.Store, mediaType string, v any) (specs.Descriptor, error) {
+	blob, err := json.Marshal(v)
+	if err != nil {
+		return specs.Descriptor{}, err
+	}
+
+	desc := specs.Descriptor{
+		MediaType: mediaType,
+		Digest:    digest.FromBytes(blob),
+		Size:      int64(len(blob)),
+	}
+	if err := content.WriteBlob(ctx, store, desc.Digest.String(), bytes.NewReader(blob), desc); err != nil {
+		return specs.Descriptor{}, fmt.Errorf("write %s: %w", mediaType, err)
+	}
+	return desc, nil
+}
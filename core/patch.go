@@ -0,0 +1,242 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FilePatch is the change to a single file in a unified diff.
+type FilePatch struct {
+	// OldPath is the path of the file before the change, or empty if the
+	// file is created by it.
+	OldPath string
+
+	// NewPath is the path of the file after the change, or empty if the
+	// file is deleted by it.
+	NewPath string
+
+	Hunks []*patchHunk
+}
+
+type patchHunk struct {
+	OldStart, OldLines int
+	NewStart, NewLines int
+
+	// Lines are the lines of the hunk, each prefixed with ' ', '-' or '+'
+	// and ending with a newline unless the file it's from doesn't.
+	Lines []string
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// ParsePatch parses a unified diff, as output by diff -u or git diff, into
+// the changes it makes to each file.
+func ParsePatch(patch string) ([]*FilePatch, error) {
+	lines := strings.SplitAfter(patch, "\n")
+
+	var files []*FilePatch
+	var file *FilePatch
+	var hunk *patchHunk
+	var oldLeft, newLeft int
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if hunk != nil && (oldLeft > 0 || newLeft > 0) {
+			if line == "" {
+				return nil, fmt.Errorf("hunk in %s ends early", file.Path())
+			}
+			switch line[0] {
+			case ' ':
+				oldLeft--
+				newLeft--
+			case '-':
+				oldLeft--
+			case '+':
+				newLeft--
+			case '\n':
+				// some tools strip the space from empty context lines
+				line = " \n"
+				oldLeft--
+				newLeft--
+			default:
+				return nil, fmt.Errorf("invalid line in hunk in %s: %q", file.Path(), strings.TrimSuffix(line, "\n"))
+			}
+			if oldLeft < 0 || newLeft < 0 {
+				return nil, fmt.Errorf("hunk in %s is longer than its header says", file.Path())
+			}
+			hunk.Lines = append(hunk.Lines, line)
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, `\ `):
+			// "\ No newline at end of file" applies to the line before it
+			if hunk == nil || len(hunk.Lines) == 0 {
+				return nil, fmt.Errorf("unexpected %q", strings.TrimSuffix(line, "\n"))
+			}
+			last := len(hunk.Lines) - 1
+			hunk.Lines[last] = strings.TrimSuffix(hunk.Lines[last], "\n")
+
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			file = &FilePatch{
+				OldPath: patchPath(line[len("--- "):]),
+				NewPath: patchPath(lines[i+1][len("+++ "):]),
+			}
+			files = append(files, file)
+			hunk = nil
+			i++
+
+		case strings.HasPrefix(line, "@@ "):
+			if file == nil {
+				return nil, fmt.Errorf("hunk without a file header: %q", strings.TrimSuffix(line, "\n"))
+			}
+			m := hunkHeader.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("invalid hunk header: %q", strings.TrimSuffix(line, "\n"))
+			}
+			hunk = &patchHunk{
+				OldStart: atoiDefault(m[1], 1),
+				OldLines: atoiDefault(m[2], 1),
+				NewStart: atoiDefault(m[3], 1),
+				NewLines: atoiDefault(m[4], 1),
+			}
+			oldLeft, newLeft = hunk.OldLines, hunk.NewLines
+			file.Hunks = append(file.Hunks, hunk)
+
+		case strings.HasPrefix(line, "GIT binary patch"):
+			return nil, fmt.Errorf("binary patches are not supported")
+
+		default:
+			// anything else, e.g. a commit message or git's extended
+			// headers, is ignored like patch(1) does
+			hunk = nil
+		}
+	}
+
+	if hunk != nil && (oldLeft > 0 || newLeft > 0) {
+		return nil, fmt.Errorf("hunk in %s ends early", file.Path())
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no changes found in patch")
+	}
+
+	return files, nil
+}
+
+// Apply applies the patch's hunks to the original contents of the file.
+// Hunks are applied where their context matches, closest to the line they
+// expect to apply at.
+func (fp *FilePatch) Apply(orig string) (string, error) {
+	lines := strings.SplitAfter(orig, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	var out []string
+	pos := 0
+	offset := 0
+	for i, hunk := range fp.Hunks {
+		var oldLines, newLines []string
+		for _, line := range hunk.Lines {
+			switch line[0] {
+			case ' ':
+				oldLines = append(oldLines, line[1:])
+				newLines = append(newLines, line[1:])
+			case '-':
+				oldLines = append(oldLines, line[1:])
+			case '+':
+				newLines = append(newLines, line[1:])
+			}
+		}
+
+		want := hunk.OldStart - 1
+		if hunk.OldLines == 0 {
+			// pure additions are placed after the line they start at
+			want = hunk.OldStart
+		}
+
+		at := findLines(lines, oldLines, pos, want+offset)
+		if at == -1 {
+			return "", fmt.Errorf("hunk #%d does not apply to %s", i+1, fp.Path())
+		}
+
+		out = append(out, lines[pos:at]...)
+		out = append(out, newLines...)
+		pos = at + len(oldLines)
+		offset = at - want
+	}
+	out = append(out, lines[pos:]...)
+
+	return strings.Join(out, ""), nil
+}
+
+// Path returns the path of the file after the change, or before it if the
+// change deletes it.
+func (fp *FilePatch) Path() string {
+	if fp.NewPath != "" {
+		return fp.NewPath
+	}
+	return fp.OldPath
+}
+
+// findLines returns the index in lines at or after min at which find occurs,
+// preferring those closest to want, or -1 if there are none.
+func findLines(lines, find []string, min, want int) int {
+	matches := func(at int) bool {
+		if at < min || at+len(find) > len(lines) {
+			return false
+		}
+		for i, line := range find {
+			if lines[at+i] != line {
+				return false
+			}
+		}
+		return true
+	}
+
+	for dist := 0; want-dist >= min || want+dist <= len(lines)-len(find); dist++ {
+		if matches(want - dist) {
+			return want - dist
+		}
+		if matches(want + dist) {
+			return want + dist
+		}
+	}
+
+	return -1
+}
+
+// patchPath returns the path of a file in a patch header, without the a/ or
+// b/ prefix that git adds, or empty if it's /dev/null.
+func patchPath(header string) string {
+	header = strings.TrimSuffix(header, "\n")
+	// diff -u follows the path with a tab and a timestamp
+	header, _, _ = strings.Cut(header, "\t")
+	header = strings.TrimSpace(header)
+
+	if header == "/dev/null" {
+		return ""
+	}
+
+	for _, prefix := range []string{"a/", "b/"} {
+		if strings.HasPrefix(header, prefix) {
+			return strings.TrimPrefix(header, prefix)
+		}
+	}
+
+	return header
+}
+
+func atoiDefault(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return def
+	}
+	return n
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatchApply(t *testing.T) {
+	t.Parallel()
+
+	orig := "one\ntwo\nthree\nfour\nfive\nsix\nseven\n"
+
+	t.Run("git diff", func(t *testing.T) {
+		files, err := ParsePatch(`diff --git a/numbers.txt b/numbers.txt
+index 1111111..2222222 100644
+--- a/numbers.txt
++++ b/numbers.txt
+@@ -1,3 +1,3 @@
+-one
++uno
+ two
+ three
+@@ -5,3 +5,4 @@
+ five
+ six
++six and a half
+ seven
+`)
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+		require.Equal(t, "numbers.txt", files[0].OldPath)
+		require.Equal(t, "numbers.txt", files[0].NewPath)
+
+		patched, err := files[0].Apply(orig)
+		require.NoError(t, err)
+		require.Equal(t, "uno\ntwo\nthree\nfour\nfive\nsix\nsix and a half\nseven\n", patched)
+	})
+
+	t.Run("offset hunk", func(t *testing.T) {
+		files, err := ParsePatch(`--- numbers.txt	2023-01-01 00:00:00
++++ numbers.txt	2023-01-02 00:00:00
+@@ -1,2 +1,2 @@
+ three
+-four
++cuatro
+`)
+		require.NoError(t, err)
+
+		patched, err := files[0].Apply(orig)
+		require.NoError(t, err)
+		require.Equal(t, "one\ntwo\nthree\ncuatro\nfive\nsix\nseven\n", patched)
+	})
+
+	t.Run("new file without newline", func(t *testing.T) {
+		files, err := ParsePatch(`--- /dev/null
++++ b/new.txt
+@@ -0,0 +1,2 @@
++hello
++world
+\ No newline at end of file
+`)
+		require.NoError(t, err)
+		require.Equal(t, "", files[0].OldPath)
+		require.Equal(t, "new.txt", files[0].NewPath)
+
+		patched, err := files[0].Apply("")
+		require.NoError(t, err)
+		require.Equal(t, "hello\nworld", patched)
+	})
+
+	t.Run("deleted file", func(t *testing.T) {
+		files, err := ParsePatch(`--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+`)
+		require.NoError(t, err)
+		require.Equal(t, "old.txt", files[0].OldPath)
+		require.Equal(t, "", files[0].NewPath)
+	})
+
+	t.Run("mismatched context", func(t *testing.T) {
+		files, err := ParsePatch(`--- a/numbers.txt
++++ b/numbers.txt
+@@ -1,2 +1,2 @@
+ one
+-zwei
++deux
+`)
+		require.NoError(t, err)
+
+		_, err = files[0].Apply(orig)
+		require.ErrorContains(t, err, "hunk #1 does not apply to numbers.txt")
+	})
+
+	t.Run("truncated hunk", func(t *testing.T) {
+		_, err := ParsePatch(`--- a/numbers.txt
++++ b/numbers.txt
+@@ -1,3 +1,3 @@
+ one
+`)
+		require.ErrorContains(t, err, "ends early")
+	})
+
+	t.Run("no changes", func(t *testing.T) {
+		_, err := ParsePatch("just some text\n")
+		require.ErrorContains(t, err, "no changes found")
+	})
+}
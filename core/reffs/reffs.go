@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"path"
+	"sort"
 	"time"
 
 	"github.com/dagger/dagger/engine/buildkit"
@@ -62,6 +64,32 @@ func (fs *FS) Open(name string) (fs.File, error) {
 	return &File{ctx: fs.ctx, ref: fs.ref, stat: stat, name: name}, nil
 }
 
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	stat, err := fsys.ref.StatFile(fsys.ctx, bkgw.StatRequest{Path: name})
+	if err != nil {
+		return nil, err
+	}
+
+	return &refFileInfo{stat: stat}, nil
+}
+
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	stats, err := fsys.ref.ReadDir(fsys.ctx, bkgw.ReadDirRequest{Path: name})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]fs.DirEntry, 0, len(stats))
+	for _, stat := range stats {
+		entries = append(entries, &refDirEntry{info: &refFileInfo{stat: stat}})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	return entries, nil
+}
+
 type File struct {
 	ctx    context.Context
 	ref    bkgw.Reference
@@ -103,7 +131,7 @@ type refFileInfo struct {
 }
 
 func (fi *refFileInfo) Name() string {
-	return fi.stat.Path
+	return path.Base(fi.stat.Path)
 }
 
 func (fi *refFileInfo) Size() int64 {
@@ -122,6 +150,28 @@ func (fi *refFileInfo) IsDir() bool {
 	return fi.stat.IsDir()
 }
 
+// Sys returns the underlying *fstypes.Stat, which includes ownership and
+// symlink targets.
 func (fi *refFileInfo) Sys() interface{} {
-	return nil
+	return fi.stat
+}
+
+type refDirEntry struct {
+	info *refFileInfo
+}
+
+func (de *refDirEntry) Name() string {
+	return de.info.Name()
+}
+
+func (de *refDirEntry) IsDir() bool {
+	return de.info.IsDir()
+}
+
+func (de *refDirEntry) Type() fs.FileMode {
+	return de.info.Mode().Type()
+}
+
+func (de *refDirEntry) Info() (fs.FileInfo, error) {
+	return de.info, nil
 }
@@ -1,9 +1,11 @@
 package schema
 
 import (
+	"crypto"
 	"fmt"
 	"os"
 	"path"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -14,6 +16,7 @@ import (
 	"github.com/dagger/dagger/core/pipeline"
 	"github.com/dagger/dagger/core/socket"
 
+	dockerspec "github.com/moby/buildkit/exporter/containerimage/image"
 	"github.com/moby/buildkit/frontend/dockerfile/shell"
 	"github.com/moby/buildkit/util/leaseutil"
 )
@@ -44,7 +47,8 @@ func (s *containerSchema) Resolvers() Resolvers {
 	return Resolvers{
 		"ContainerID": stringResolver(core.ContainerID("")),
 		"Query": ObjectResolver{
-			"container": ToResolver(s.container),
+			"container":              ToResolver(s.container),
+			"containerFromOCILayout": ToResolver(s.containerFromOCILayout),
 		},
 		"Container": ObjectResolver{
 			"id":                   ToResolver(s.id),
@@ -54,6 +58,8 @@ func (s *containerSchema) Resolvers() Resolvers {
 			"rootfs":               ToResolver(s.rootfs),
 			"pipeline":             ToResolver(s.pipeline),
 			"withRootfs":           ToResolver(s.withRootfs),
+			"squash":               ToResolver(s.squash),
+			"withLayerBoundary":    ToResolver(s.withLayerBoundary),
 			"file":                 ToResolver(s.file),
 			"directory":            ToResolver(s.directory),
 			"user":                 ToResolver(s.user),
@@ -69,6 +75,20 @@ func (s *containerSchema) Resolvers() Resolvers {
 			"label":                ToResolver(s.label),
 			"labels":               ToResolver(s.labels),
 			"withoutLabel":         ToResolver(s.withoutLabel),
+			"healthcheck":          ToResolver(s.healthcheck),
+			"withHealthcheck":      ToResolver(s.withHealthcheck),
+			"withoutHealthcheck":   ToResolver(s.withoutHealthcheck),
+			"stopSignal":           ToResolver(s.stopSignal),
+			"withStopSignal":       ToResolver(s.withStopSignal),
+			"volumes":              ToResolver(s.volumes),
+			"withVolume":           ToResolver(s.withVolume),
+			"withoutVolume":        ToResolver(s.withoutVolume),
+			"onBuild":              ToResolver(s.onBuild),
+			"withOnBuild":          ToResolver(s.withOnBuild),
+			"shell":                ToResolver(s.shell),
+			"withShell":            ToResolver(s.withShell),
+			"history":              ToResolver(s.history),
+			"layers":               ToResolver(s.layers),
 			"entrypoint":           ToResolver(s.entrypoint),
 			"withEntrypoint":       ToResolver(s.withEntrypoint),
 			"defaultArgs":          ToResolver(s.defaultArgs),
@@ -86,12 +106,19 @@ func (s *containerSchema) Resolvers() Resolvers {
 			"withNewFile":          ToResolver(s.withNewFile),
 			"withDirectory":        ToResolver(s.withDirectory),
 			"withExec":             ToResolver(s.withExec),
+			"withResourceLimits":   ToResolver(s.withResourceLimits),
 			"stdout":               ToResolver(s.stdout),
 			"stderr":               ToResolver(s.stderr),
+			"exitCode":             ToResolver(s.exitCode),
+			"terminal":             ToResolver(s.terminal),
 			"publish":              ToResolver(s.publish),
+			"publishAll":           ToResolver(s.publishAll),
 			"platform":             ToResolver(s.platform),
 			"export":               ToResolver(s.export),
 			"import":               ToResolver(s.import_),
+			"asOCILayout":          ToResolver(s.asOCILayout),
+			"withAttestation":      ToResolver(s.withAttestation),
+			"verifySignature":      ToResolver(s.verifySignature),
 			"withRegistryAuth":     ToResolver(s.withRegistryAuth),
 			"withoutRegistryAuth":  ToResolver(s.withoutRegistryAuth),
 			"imageRef":             ToResolver(s.imageRef),
@@ -101,6 +128,7 @@ func (s *containerSchema) Resolvers() Resolvers {
 			"hostname":             ToResolver(s.hostname),
 			"endpoint":             ToResolver(s.endpoint),
 			"withServiceBinding":   ToResolver(s.withServiceBinding),
+			"withBoundService":     ToResolver(s.withBoundService),
 			"withFocus":            ToResolver(s.withFocus),
 			"withoutFocus":         ToResolver(s.withoutFocus),
 		},
@@ -132,6 +160,41 @@ func (s *containerSchema) container(ctx *core.Context, parent *core.Query, args
 	return ctr, err
 }
 
+type containerFromOCILayoutArgs struct {
+	Dir      core.DirectoryID
+	Tag      string
+	Platform *specs.Platform
+}
+
+func (s *containerSchema) containerFromOCILayout(ctx *core.Context, parent *core.Query, args containerFromOCILayoutArgs) (*core.Container, error) {
+	platform := s.MergedSchemas.platform
+	if args.Platform != nil {
+		platform = *args.Platform
+	}
+
+	dir, err := args.Dir.ToDirectory()
+	if err != nil {
+		return nil, err
+	}
+
+	ctr, err := core.NewContainer("", parent.PipelinePath(), platform)
+	if err != nil {
+		return nil, err
+	}
+
+	return ctr.ImportOCILayout(
+		ctx,
+		dir,
+		args.Tag,
+		s.bk,
+		s.host,
+		s.svcs,
+		s.importCache,
+		s.ociStore,
+		s.leaseManager,
+	)
+}
+
 func (s *containerSchema) sync(ctx *core.Context, parent *core.Container, _ any) (core.ContainerID, error) {
 	err := parent.Evaluate(ctx, s.bk, s.svcs)
 	if err != nil {
@@ -145,19 +208,24 @@ func (s *containerSchema) id(ctx *core.Context, parent *core.Container, args any
 }
 
 type containerFromArgs struct {
-	Address string
+	Address    string
+	PullPolicy core.ImagePullPolicy
 }
 
 func (s *containerSchema) from(ctx *core.Context, parent *core.Container, args containerFromArgs) (*core.Container, error) {
-	return parent.From(ctx, s.bk, args.Address)
+	return parent.From(ctx, s.bk, args.Address, args.PullPolicy)
 }
 
 type containerBuildArgs struct {
-	Context    core.DirectoryID
-	Dockerfile string
-	BuildArgs  []core.BuildArg
-	Target     string
-	Secrets    []core.SecretID
+	Context       core.DirectoryID
+	Dockerfile    string
+	BuildArgs     []core.BuildArg
+	Target        string
+	Secrets       []core.SecretID
+	BuildContexts []core.BuildContext
+	SSH           socket.ID
+	CacheFrom     []string
+	Network       string
 }
 
 func (s *containerSchema) build(ctx *core.Context, parent *core.Container, args containerBuildArgs) (*core.Container, error) {
@@ -172,6 +240,10 @@ func (s *containerSchema) build(ctx *core.Context, parent *core.Container, args
 		args.BuildArgs,
 		args.Target,
 		args.Secrets,
+		args.BuildContexts,
+		args.SSH,
+		args.CacheFrom,
+		args.Network,
 		s.bk,
 		s.svcs,
 		s.buildCache,
@@ -190,6 +262,14 @@ func (s *containerSchema) withRootfs(ctx *core.Context, parent *core.Container,
 	return parent.WithRootFS(ctx, dir)
 }
 
+func (s *containerSchema) squash(ctx *core.Context, parent *core.Container, args any) (*core.Container, error) {
+	return parent.Squash(ctx)
+}
+
+func (s *containerSchema) withLayerBoundary(ctx *core.Context, parent *core.Container, args any) (*core.Container, error) {
+	return parent.WithLayerBoundary(ctx)
+}
+
 type containerPipelineArgs struct {
 	Name        string
 	Description string
@@ -212,6 +292,14 @@ func (s *containerSchema) withExec(ctx *core.Context, parent *core.Container, ar
 	return parent.WithExec(ctx, s.bk, s.progSockPath, s.MergedSchemas.platform, args.ContainerExecOpts)
 }
 
+type containerWithResourceLimitsArgs struct {
+	Limits core.ResourceLimits
+}
+
+func (s *containerSchema) withResourceLimits(ctx *core.Context, parent *core.Container, args containerWithResourceLimitsArgs) (*core.Container, error) {
+	return parent.WithResourceLimits(args.Limits)
+}
+
 func (s *containerSchema) stdout(ctx *core.Context, parent *core.Container, _ any) (string, error) {
 	return parent.MetaFileContents(ctx, s.bk, s.svcs, s.progSockPath, "stdout")
 }
@@ -220,12 +308,24 @@ func (s *containerSchema) stderr(ctx *core.Context, parent *core.Container, _ an
 	return parent.MetaFileContents(ctx, s.bk, s.svcs, s.progSockPath, "stderr")
 }
 
+func (s *containerSchema) exitCode(ctx *core.Context, parent *core.Container, _ any) (int, error) {
+	return parent.ExitCode(ctx, s.bk, s.svcs, s.progSockPath)
+}
+
+type containerTerminalArgs struct {
+	Cmd []string
+}
+
+func (s *containerSchema) terminal(ctx *core.Context, parent *core.Container, args containerTerminalArgs) (*core.Terminal, error) {
+	return parent.Terminal(ctx, s.bk, s.svcs, s.progSockPath, s.MergedSchemas.platform, args.Cmd)
+}
+
 type containerWithEntrypointArgs struct {
 	Args []string
 }
 
 func (s *containerSchema) withEntrypoint(ctx *core.Context, parent *core.Container, args containerWithEntrypointArgs) (*core.Container, error) {
-	return parent.UpdateImageConfig(ctx, func(cfg specs.ImageConfig) specs.ImageConfig {
+	return parent.UpdateImageConfig(ctx, func(cfg dockerspec.ImageConfig) dockerspec.ImageConfig {
 		cfg.Entrypoint = args.Args
 		return cfg
 	})
@@ -245,7 +345,7 @@ type containerWithDefaultArgs struct {
 }
 
 func (s *containerSchema) withDefaultArgs(ctx *core.Context, parent *core.Container, args containerWithDefaultArgs) (*core.Container, error) {
-	return parent.UpdateImageConfig(ctx, func(cfg specs.ImageConfig) specs.ImageConfig {
+	return parent.UpdateImageConfig(ctx, func(cfg dockerspec.ImageConfig) dockerspec.ImageConfig {
 		if args.Args == nil {
 			cfg.Cmd = []string{}
 			return cfg
@@ -270,7 +370,7 @@ type containerWithUserArgs struct {
 }
 
 func (s *containerSchema) withUser(ctx *core.Context, parent *core.Container, args containerWithUserArgs) (*core.Container, error) {
-	return parent.UpdateImageConfig(ctx, func(cfg specs.ImageConfig) specs.ImageConfig {
+	return parent.UpdateImageConfig(ctx, func(cfg dockerspec.ImageConfig) dockerspec.ImageConfig {
 		cfg.User = args.Name
 		return cfg
 	})
@@ -290,7 +390,7 @@ type containerWithWorkdirArgs struct {
 }
 
 func (s *containerSchema) withWorkdir(ctx *core.Context, parent *core.Container, args containerWithWorkdirArgs) (*core.Container, error) {
-	return parent.UpdateImageConfig(ctx, func(cfg specs.ImageConfig) specs.ImageConfig {
+	return parent.UpdateImageConfig(ctx, func(cfg dockerspec.ImageConfig) dockerspec.ImageConfig {
 		cfg.WorkingDir = absPath(cfg.WorkingDir, args.Path)
 		return cfg
 	})
@@ -312,7 +412,7 @@ type containerWithVariableArgs struct {
 }
 
 func (s *containerSchema) withEnvVariable(ctx *core.Context, parent *core.Container, args containerWithVariableArgs) (*core.Container, error) {
-	return parent.UpdateImageConfig(ctx, func(cfg specs.ImageConfig) specs.ImageConfig {
+	return parent.UpdateImageConfig(ctx, func(cfg dockerspec.ImageConfig) dockerspec.ImageConfig {
 		value := args.Value
 
 		if args.Expand {
@@ -333,7 +433,7 @@ type containerWithoutVariableArgs struct {
 }
 
 func (s *containerSchema) withoutEnvVariable(ctx *core.Context, parent *core.Container, args containerWithoutVariableArgs) (*core.Container, error) {
-	return parent.UpdateImageConfig(ctx, func(cfg specs.ImageConfig) specs.ImageConfig {
+	return parent.UpdateImageConfig(ctx, func(cfg dockerspec.ImageConfig) dockerspec.ImageConfig {
 		newEnv := []string{}
 
 		core.WalkEnv(cfg.Env, func(k, _, env string) {
@@ -445,10 +545,62 @@ type containerPublishArgs struct {
 	PlatformVariants  []core.ContainerID
 	ForcedCompression core.ImageLayerCompression
 	MediaTypes        core.ImageMediaTypes
+	Provenance        bool
+	SBOM              bool
+	SigningKey        core.SecretID
+	SigningPassword   core.SecretID
 }
 
 func (s *containerSchema) publish(ctx *core.Context, parent *core.Container, args containerPublishArgs) (string, error) {
-	return parent.Publish(ctx, s.bk, s.svcs, args.Address, args.PlatformVariants, args.ForcedCompression, args.MediaTypes)
+	signer, err := s.signer(ctx, args.SigningKey, args.SigningPassword)
+	if err != nil {
+		return "", err
+	}
+
+	return parent.Publish(ctx, s.bk, s.svcs, args.Address, args.PlatformVariants, args.ForcedCompression, args.MediaTypes, args.Provenance, args.SBOM, signer)
+}
+
+type containerPublishAllArgs struct {
+	Addresses         []string
+	PlatformVariants  []core.ContainerID
+	ForcedCompression core.ImageLayerCompression
+	MediaTypes        core.ImageMediaTypes
+	Provenance        bool
+	SBOM              bool
+	SigningKey        core.SecretID
+	SigningPassword   core.SecretID
+}
+
+func (s *containerSchema) publishAll(ctx *core.Context, parent *core.Container, args containerPublishAllArgs) ([]core.PublishResult, error) {
+	signer, err := s.signer(ctx, args.SigningKey, args.SigningPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	return parent.PublishAll(ctx, s.bk, s.svcs, args.Addresses, args.PlatformVariants, args.ForcedCompression, args.MediaTypes, args.Provenance, args.SBOM, signer)
+}
+
+// signer returns the signer for a signing key secret, or nil if there isn't
+// one.
+func (s *containerSchema) signer(ctx *core.Context, key, password core.SecretID) (crypto.Signer, error) {
+	if key == "" {
+		return nil, nil
+	}
+
+	keyBytes, err := s.secrets.GetSecret(ctx, key.String())
+	if err != nil {
+		return nil, err
+	}
+
+	var passwordBytes []byte
+	if password != "" {
+		passwordBytes, err = s.secrets.GetSecret(ctx, password.String())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return core.ParseSigningKey(keyBytes, passwordBytes)
 }
 
 type containerWithMountedFileArgs struct {
@@ -517,7 +669,7 @@ type containerWithLabelArgs struct {
 }
 
 func (s *containerSchema) withLabel(ctx *core.Context, parent *core.Container, args containerWithLabelArgs) (*core.Container, error) {
-	return parent.UpdateImageConfig(ctx, func(cfg specs.ImageConfig) specs.ImageConfig {
+	return parent.UpdateImageConfig(ctx, func(cfg dockerspec.ImageConfig) dockerspec.ImageConfig {
 		if cfg.Labels == nil {
 			cfg.Labels = make(map[string]string)
 		}
@@ -531,12 +683,140 @@ type containerWithoutLabelArgs struct {
 }
 
 func (s *containerSchema) withoutLabel(ctx *core.Context, parent *core.Container, args containerWithoutLabelArgs) (*core.Container, error) {
-	return parent.UpdateImageConfig(ctx, func(cfg specs.ImageConfig) specs.ImageConfig {
+	return parent.UpdateImageConfig(ctx, func(cfg dockerspec.ImageConfig) dockerspec.ImageConfig {
 		delete(cfg.Labels, args.Name)
 		return cfg
 	})
 }
 
+func (s *containerSchema) healthcheck(ctx *core.Context, parent *core.Container, args any) (*core.Healthcheck, error) {
+	return parent.Healthcheck(ctx)
+}
+
+type containerWithHealthcheckArgs struct {
+	Test          []string
+	Interval      string
+	Timeout       string
+	StartPeriod   string
+	StartInterval string
+	Retries       int
+}
+
+func (s *containerSchema) withHealthcheck(ctx *core.Context, parent *core.Container, args containerWithHealthcheckArgs) (*core.Container, error) {
+	return parent.WithHealthcheck(ctx, core.Healthcheck(args))
+}
+
+func (s *containerSchema) withoutHealthcheck(ctx *core.Context, parent *core.Container, args any) (*core.Container, error) {
+	return parent.UpdateImageConfig(ctx, func(cfg dockerspec.ImageConfig) dockerspec.ImageConfig {
+		cfg.Healthcheck = nil
+		return cfg
+	})
+}
+
+func (s *containerSchema) stopSignal(ctx *core.Context, parent *core.Container, args any) (string, error) {
+	cfg, err := parent.ImageConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return cfg.StopSignal, nil
+}
+
+type containerWithStopSignalArgs struct {
+	Signal string
+}
+
+func (s *containerSchema) withStopSignal(ctx *core.Context, parent *core.Container, args containerWithStopSignalArgs) (*core.Container, error) {
+	return parent.UpdateImageConfig(ctx, func(cfg dockerspec.ImageConfig) dockerspec.ImageConfig {
+		cfg.StopSignal = args.Signal
+		return cfg
+	})
+}
+
+func (s *containerSchema) volumes(ctx *core.Context, parent *core.Container, args any) ([]string, error) {
+	cfg, err := parent.ImageConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	volumes := make([]string, 0, len(cfg.Volumes))
+	for volume := range cfg.Volumes {
+		volumes = append(volumes, volume)
+	}
+	sort.Strings(volumes)
+
+	return volumes, nil
+}
+
+type containerWithVolumeArgs struct {
+	Path string
+}
+
+func (s *containerSchema) withVolume(ctx *core.Context, parent *core.Container, args containerWithVolumeArgs) (*core.Container, error) {
+	return parent.UpdateImageConfig(ctx, func(cfg dockerspec.ImageConfig) dockerspec.ImageConfig {
+		if cfg.Volumes == nil {
+			cfg.Volumes = make(map[string]struct{})
+		}
+		cfg.Volumes[absPath(cfg.WorkingDir, args.Path)] = struct{}{}
+		return cfg
+	})
+}
+
+func (s *containerSchema) withoutVolume(ctx *core.Context, parent *core.Container, args containerWithVolumeArgs) (*core.Container, error) {
+	return parent.UpdateImageConfig(ctx, func(cfg dockerspec.ImageConfig) dockerspec.ImageConfig {
+		delete(cfg.Volumes, absPath(cfg.WorkingDir, args.Path))
+		return cfg
+	})
+}
+
+func (s *containerSchema) onBuild(ctx *core.Context, parent *core.Container, args any) ([]string, error) {
+	cfg, err := parent.ImageConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg.OnBuild, nil
+}
+
+type containerWithOnBuildArgs struct {
+	Triggers []string
+}
+
+func (s *containerSchema) withOnBuild(ctx *core.Context, parent *core.Container, args containerWithOnBuildArgs) (*core.Container, error) {
+	return parent.UpdateImageConfig(ctx, func(cfg dockerspec.ImageConfig) dockerspec.ImageConfig {
+		cfg.OnBuild = args.Triggers
+		return cfg
+	})
+}
+
+func (s *containerSchema) shell(ctx *core.Context, parent *core.Container, args any) ([]string, error) {
+	cfg, err := parent.ImageConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg.Shell, nil
+}
+
+type containerWithShellArgs struct {
+	Args []string
+}
+
+func (s *containerSchema) withShell(ctx *core.Context, parent *core.Container, args containerWithShellArgs) (*core.Container, error) {
+	return parent.UpdateImageConfig(ctx, func(cfg dockerspec.ImageConfig) dockerspec.ImageConfig {
+		cfg.Shell = args.Args
+		return cfg
+	})
+}
+
+func (s *containerSchema) history(ctx *core.Context, parent *core.Container, args any) ([]core.ImageHistory, error) {
+	return parent.ImageHistory(ctx)
+}
+
+func (s *containerSchema) layers(ctx *core.Context, parent *core.Container, args any) ([]core.ImageLayer, error) {
+	return parent.ImageLayers(ctx, s.bk)
+}
+
 type containerDirectoryArgs struct {
 	Path string
 }
@@ -659,16 +939,45 @@ type containerExportArgs struct {
 	PlatformVariants  []core.ContainerID
 	ForcedCompression core.ImageLayerCompression
 	MediaTypes        core.ImageMediaTypes
+	Provenance        bool
+	SBOM              bool
 }
 
 func (s *containerSchema) export(ctx *core.Context, parent *core.Container, args containerExportArgs) (bool, error) {
-	if err := parent.Export(ctx, s.bk, s.svcs, args.Path, args.PlatformVariants, args.ForcedCompression, args.MediaTypes); err != nil {
+	if err := parent.Export(ctx, s.bk, s.svcs, args.Path, args.PlatformVariants, args.ForcedCompression, args.MediaTypes, args.Provenance, args.SBOM); err != nil {
 		return false, err
 	}
 
 	return true, nil
 }
 
+type containerWithAttestationArgs struct {
+	PredicateType string
+	Predicate     core.FileID
+}
+
+func (s *containerSchema) withAttestation(ctx *core.Context, parent *core.Container, args containerWithAttestationArgs) (*core.Container, error) {
+	return parent.WithAttestation(ctx, args.PredicateType, args.Predicate)
+}
+
+type containerVerifySignatureArgs struct {
+	PublicKey core.FileID
+}
+
+func (s *containerSchema) verifySignature(ctx *core.Context, parent *core.Container, args containerVerifySignatureArgs) (*core.Container, error) {
+	file, err := args.PublicKey.ToFile()
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := file.Contents(ctx, s.bk, s.svcs)
+	if err != nil {
+		return nil, err
+	}
+
+	return parent.VerifySignature(ctx, s.bk, publicKey)
+}
+
 type containerImportArgs struct {
 	Source core.FileID
 	Tag    string
@@ -688,6 +997,29 @@ func (s *containerSchema) import_(ctx *core.Context, parent *core.Container, arg
 	)
 }
 
+type containerAsOCILayoutArgs struct {
+	PlatformVariants  []core.ContainerID
+	ForcedCompression core.ImageLayerCompression
+	MediaTypes        core.ImageMediaTypes
+	Provenance        bool
+	SBOM              bool
+}
+
+func (s *containerSchema) asOCILayout(ctx *core.Context, parent *core.Container, args containerAsOCILayoutArgs) (*core.Directory, error) {
+	return parent.AsOCILayout(
+		ctx,
+		s.bk,
+		s.svcs,
+		args.PlatformVariants,
+		args.ForcedCompression,
+		args.MediaTypes,
+		args.Provenance,
+		args.SBOM,
+		s.ociStore,
+		s.leaseManager,
+	)
+}
+
 type containerWithRegistryAuthArgs struct {
 	Address  string        `json:"address"`
 	Username string        `json:"username"`
@@ -765,6 +1097,20 @@ func (s *containerSchema) withServiceBinding(ctx *core.Context, parent *core.Con
 	return parent.WithServiceBinding(ctx, s.svcs, svc, args.Alias)
 }
 
+type containerWithBoundServiceArgs struct {
+	Service core.ServiceID
+	Alias   string
+}
+
+func (s *containerSchema) withBoundService(ctx *core.Context, parent *core.Container, args containerWithBoundServiceArgs) (*core.Container, error) {
+	svc, err := args.Service.ToService()
+	if err != nil {
+		return nil, err
+	}
+
+	return parent.WithServiceBinding(ctx, s.svcs, svc, args.Alias)
+}
+
 type containerWithExposedPortArgs struct {
 	Protocol    core.NetworkProtocol
 	Port        int
@@ -5,6 +5,7 @@ import (
 
 	"github.com/dagger/dagger/core"
 	"github.com/dagger/dagger/core/pipeline"
+	"github.com/dagger/dagger/core/socket"
 	specs "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
@@ -39,16 +40,23 @@ func (s *directorySchema) Resolvers() Resolvers {
 			"sync":             ToResolver(s.sync),
 			"pipeline":         ToResolver(s.pipeline),
 			"entries":          ToResolver(s.entries),
+			"glob":             ToResolver(s.glob),
+			"digest":           ToResolver(s.digest),
+			"stat":             ToResolver(s.stat),
 			"file":             ToResolver(s.file),
 			"withFile":         ToResolver(s.withFile),
 			"withNewFile":      ToResolver(s.withNewFile),
 			"withoutFile":      ToResolver(s.withoutFile),
+			"withPatch":        ToResolver(s.withPatch),
 			"directory":        ToResolver(s.subdirectory),
 			"withDirectory":    ToResolver(s.withDirectory),
 			"withTimestamps":   ToResolver(s.withTimestamps),
 			"withNewDirectory": ToResolver(s.withNewDirectory),
 			"withoutDirectory": ToResolver(s.withoutDirectory),
 			"diff":             ToResolver(s.diff),
+			"changes":          ToResolver(s.changes),
+			"asTarball":        ToResolver(s.asTarball),
+			"asZip":            ToResolver(s.asZip),
 			"export":           ToResolver(s.export),
 			"dockerBuild":      ToResolver(s.dockerBuild),
 		}),
@@ -141,6 +149,40 @@ func (s *directorySchema) entries(ctx *core.Context, parent *core.Directory, arg
 	return parent.Entries(ctx, s.bk, s.svcs, args.Path)
 }
 
+type globArgs struct {
+	Pattern string
+}
+
+func (s *directorySchema) glob(ctx *core.Context, parent *core.Directory, args globArgs) ([]string, error) {
+	return parent.Glob(ctx, s.bk, s.svcs, args.Pattern)
+}
+
+type digestArgs struct {
+	IgnoreTimestamps bool
+}
+
+func (s *directorySchema) digest(ctx *core.Context, parent *core.Directory, args digestArgs) (string, error) {
+	dgst, err := parent.ContentDigest(ctx, s.bk, s.svcs, args.IgnoreTimestamps)
+	if err != nil {
+		return "", err
+	}
+
+	return dgst.String(), nil
+}
+
+type statArgs struct {
+	Path string
+}
+
+func (s *directorySchema) stat(ctx *core.Context, parent *core.Directory, args statArgs) (*core.Stat, error) {
+	stat, err := parent.Stat(ctx, s.bk, s.svcs, args.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	return core.NewStat(args.Path, stat), nil
+}
+
 type dirFileArgs struct {
 	Path string
 }
@@ -190,6 +232,31 @@ func (s *directorySchema) withoutFile(ctx *core.Context, parent *core.Directory,
 	return parent.Without(ctx, args.Path)
 }
 
+type withPatchArgs struct {
+	Patch core.FileID
+}
+
+func (s *directorySchema) withPatch(ctx *core.Context, parent *core.Directory, args withPatchArgs) (*core.Directory, error) {
+	patch, err := args.Patch.ToFile()
+	if err != nil {
+		return nil, err
+	}
+
+	return parent.WithPatch(ctx, patch)
+}
+
+type asTarballArgs struct {
+	Compression core.ImageLayerCompression
+}
+
+func (s *directorySchema) asTarball(ctx *core.Context, parent *core.Directory, args asTarballArgs) (*core.File, error) {
+	return parent.AsTarball(ctx, args.Compression)
+}
+
+func (s *directorySchema) asZip(ctx *core.Context, parent *core.Directory, args any) (*core.File, error) {
+	return parent.AsZip(ctx)
+}
+
 type diffArgs struct {
 	Other core.DirectoryID
 }
@@ -202,6 +269,15 @@ func (s *directorySchema) diff(ctx *core.Context, parent *core.Directory, args d
 	return parent.Diff(ctx, dir)
 }
 
+func (s *directorySchema) changes(ctx *core.Context, parent *core.Directory, args diffArgs) ([]core.Change, error) {
+	dir, err := args.Other.ToDirectory()
+	if err != nil {
+		return nil, err
+	}
+
+	return parent.Changes(ctx, s.bk, s.svcs, dir)
+}
+
 type dirExportArgs struct {
 	Path string
 }
@@ -216,11 +292,15 @@ func (s *directorySchema) export(ctx *core.Context, parent *core.Directory, args
 }
 
 type dirDockerBuildArgs struct {
-	Platform   *specs.Platform
-	Dockerfile string
-	BuildArgs  []core.BuildArg
-	Target     string
-	Secrets    []core.SecretID
+	Platform      *specs.Platform
+	Dockerfile    string
+	BuildArgs     []core.BuildArg
+	Target        string
+	Secrets       []core.SecretID
+	BuildContexts []core.BuildContext
+	SSH           socket.ID
+	CacheFrom     []string
+	Network       string
 }
 
 func (s *directorySchema) dockerBuild(ctx *core.Context, parent *core.Directory, args dirDockerBuildArgs) (*core.Container, error) {
@@ -239,6 +319,10 @@ func (s *directorySchema) dockerBuild(ctx *core.Context, parent *core.Directory,
 		args.BuildArgs,
 		args.Target,
 		args.Secrets,
+		args.BuildContexts,
+		args.SSH,
+		args.CacheFrom,
+		args.Network,
 		s.bk,
 		s.svcs,
 		s.buildCache,
@@ -1,6 +1,8 @@
 package schema
 
 import (
+	"path"
+
 	"github.com/dagger/dagger/core"
 )
 
@@ -34,8 +36,13 @@ func (s *fileSchema) Resolvers() Resolvers {
 			"sync":           ToResolver(s.sync),
 			"contents":       ToResolver(s.contents),
 			"size":           ToResolver(s.size),
+			"stat":           ToResolver(s.stat),
+			"digest":         ToResolver(s.digest),
+			"unpack":         ToResolver(s.unpack),
 			"export":         ToResolver(s.export),
 			"withTimestamps": ToResolver(s.withTimestamps),
+			"withReplaced":   ToResolver(s.withReplaced),
+			"withTemplate":   ToResolver(s.withTemplate),
 		}),
 	}
 }
@@ -82,6 +89,28 @@ func (s *fileSchema) size(ctx *core.Context, file *core.File, args any) (int64,
 	return info.Size_, nil
 }
 
+func (s *fileSchema) stat(ctx *core.Context, file *core.File, args any) (*core.Stat, error) {
+	info, err := file.Stat(ctx, s.bk, s.svcs)
+	if err != nil {
+		return nil, err
+	}
+
+	return core.NewStat(path.Base(file.File), info), nil
+}
+
+func (s *fileSchema) digest(ctx *core.Context, file *core.File, args digestArgs) (string, error) {
+	dgst, err := file.ContentDigest(ctx, s.bk, s.svcs, args.IgnoreTimestamps)
+	if err != nil {
+		return "", err
+	}
+
+	return dgst.String(), nil
+}
+
+func (s *fileSchema) unpack(ctx *core.Context, file *core.File, args any) (*core.Directory, error) {
+	return file.Unpack(ctx)
+}
+
 type fileExportArgs struct {
 	Path               string
 	AllowParentDirPath bool
@@ -103,3 +132,20 @@ type fileWithTimestampsArgs struct {
 func (s *fileSchema) withTimestamps(ctx *core.Context, parent *core.File, args fileWithTimestampsArgs) (*core.File, error) {
 	return parent.WithTimestamps(ctx, args.Timestamp)
 }
+
+type fileWithReplacedArgs struct {
+	Search      string
+	Replacement string
+}
+
+func (s *fileSchema) withReplaced(ctx *core.Context, parent *core.File, args fileWithReplacedArgs) (*core.File, error) {
+	return parent.WithReplaced(ctx, args.Search, args.Replacement)
+}
+
+type fileWithTemplateArgs struct {
+	Vars []core.TemplateVariable
+}
+
+func (s *fileSchema) withTemplate(ctx *core.Context, parent *core.File, args fileWithTemplateArgs) (*core.File, error) {
+	return parent.WithTemplate(ctx, args.Vars)
+}
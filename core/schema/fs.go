@@ -37,3 +37,9 @@ var Socket string
 
 //go:embed project.graphqls
 var Project string
+
+//go:embed terminal.graphqls
+var Terminal string
+
+//go:embed service.graphqls
+var Service string
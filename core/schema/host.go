@@ -33,6 +33,8 @@ func (s *hostSchema) Resolvers() Resolvers {
 			"directory":     ToResolver(s.directory),
 			"file":          ToResolver(s.file),
 			"unixSocket":    ToResolver(s.socket),
+			"service":       ToResolver(s.service),
+			"tunnel":        ToResolver(s.tunnel),
 			"setSecretFile": ToResolver(s.setSecretFile),
 		},
 	}
@@ -86,3 +88,31 @@ type hostFileArgs struct {
 func (s *hostSchema) file(ctx *core.Context, parent *core.Query, args hostFileArgs) (*core.File, error) {
 	return s.host.File(ctx, s.bk, s.svcs, args.Path, parent.PipelinePath(), s.platform)
 }
+
+type hostServiceArgs struct {
+	Host  string
+	Ports []core.PortForward
+}
+
+func (s *hostSchema) service(ctx *core.Context, parent any, args hostServiceArgs) (*core.Service, error) {
+	if args.Host == "" {
+		args.Host = "localhost"
+	}
+
+	return s.host.Service(ctx, args.Host, args.Ports)
+}
+
+type hostTunnelArgs struct {
+	Service core.ServiceID
+	Ports   []core.PortForward
+	Native  bool
+}
+
+func (s *hostSchema) tunnel(ctx *core.Context, parent any, args hostTunnelArgs) (*core.Service, error) {
+	svc, err := args.Service.ToService()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.host.Tunnel(ctx, s.svcs, svc, args.Ports, args.Native)
+}
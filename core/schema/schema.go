@@ -63,7 +63,9 @@ func New(params InitializeArgs) (*MergedSchemas, error) {
 		&projectSchema{merged, svcs},
 		&httpSchema{merged, svcs},
 		&platformSchema{merged},
+		&serviceSchema{merged, svcs},
 		&socketSchema{merged, host},
+		&terminalSchema{merged},
 	)
 	if err != nil {
 		return nil, err
@@ -91,6 +93,11 @@ func (s *MergedSchemas) Schema() *graphql.Schema {
 	return s.compiledSchema
 }
 
+// Services returns the services started by the schema's resolvers.
+func (s *MergedSchemas) Services() *core.Services {
+	return s.services
+}
+
 func (s *MergedSchemas) ShutdownClient(ctx context.Context, client *engine.ClientMetadata) error {
 	return s.services.StopClientServices(ctx, client)
 }
@@ -0,0 +1,160 @@
+package schema
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dagger/dagger/core"
+)
+
+type serviceSchema struct {
+	*MergedSchemas
+
+	svcs *core.Services
+}
+
+var _ ExecutableSchema = &serviceSchema{}
+
+func (s *serviceSchema) Name() string {
+	return "service"
+}
+
+func (s *serviceSchema) Schema() string {
+	return Service
+}
+
+var serviceIDResolver = stringResolver(core.ServiceID(""))
+
+func (s *serviceSchema) Resolvers() Resolvers {
+	return Resolvers{
+		"ServiceID": serviceIDResolver,
+		"Query": ObjectResolver{
+			"service": ToResolver(s.service),
+		},
+		"Container": ObjectResolver{
+			"asService": ToResolver(s.containerAsService),
+		},
+		"Service": ObjectResolver{
+			"id":              ToResolver(s.id),
+			"hostname":        ToResolver(s.hostname),
+			"ports":           ToResolver(s.ports),
+			"endpoint":        ToResolver(s.endpoint),
+			"withHealthcheck": ToResolver(s.withHealthcheck),
+			"logs":            ToResolver(s.logs),
+			"exitCode":        ToResolver(s.exitCode),
+			"start":           ToResolver(s.start),
+			"stop":            ToResolver(s.stop),
+		},
+	}
+}
+
+func (s *serviceSchema) Dependencies() []ExecutableSchema {
+	return nil
+}
+
+type serviceArgs struct {
+	ID core.ServiceID
+}
+
+func (s *serviceSchema) service(ctx *core.Context, parent any, args serviceArgs) (*core.Service, error) {
+	return args.ID.ToService()
+}
+
+func (s *serviceSchema) containerAsService(ctx *core.Context, parent *core.Container, args any) (*core.Service, error) {
+	return parent.Service(ctx, s.bk, s.progSockPath)
+}
+
+func (s *serviceSchema) id(ctx *core.Context, parent *core.Service, args any) (core.ServiceID, error) {
+	return parent.ID()
+}
+
+func (s *serviceSchema) hostname(ctx *core.Context, parent *core.Service, args any) (string, error) {
+	return parent.Hostname(ctx, s.svcs)
+}
+
+func (s *serviceSchema) ports(ctx *core.Context, parent *core.Service, args any) ([]core.Port, error) {
+	return parent.Ports(ctx, s.svcs)
+}
+
+type serviceEndpointArgs struct {
+	Port   int
+	Scheme string
+}
+
+func (s *serviceSchema) endpoint(ctx *core.Context, parent *core.Service, args serviceEndpointArgs) (string, error) {
+	return parent.Endpoint(ctx, s.svcs, args.Port, args.Scheme)
+}
+
+type serviceWithHealthcheckArgs struct {
+	Exec          []string
+	HTTPPath      string
+	HTTPStatus    int
+	TCP           bool
+	Image         bool
+	Port          int
+	Interval      string
+	Timeout       string
+	StartPeriod   string
+	StartInterval string
+	Retries       int
+}
+
+func (s *serviceSchema) withHealthcheck(ctx *core.Context, parent *core.Service, args serviceWithHealthcheckArgs) (*core.Service, error) {
+	timings, err := core.ParseHealthcheckTimings(args.Interval, args.Timeout, args.StartPeriod, args.StartInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	return parent.WithHealthcheck(core.ServiceHealthcheck{
+		Exec:               args.Exec,
+		HTTPPath:           args.HTTPPath,
+		HTTPStatus:         args.HTTPStatus,
+		TCP:                args.TCP,
+		Port:               args.Port,
+		HealthcheckTimings: timings,
+		Retries:            args.Retries,
+	}, args.Image)
+}
+
+type serviceLogsArgs struct {
+	Since string
+	Tail  int
+}
+
+func (s *serviceSchema) logs(ctx *core.Context, parent *core.Service, args serviceLogsArgs) (string, error) {
+	var since time.Time
+	if args.Since != "" {
+		var err error
+		since, err = time.Parse(time.RFC3339, args.Since)
+		if err != nil {
+			duration, durErr := time.ParseDuration(args.Since)
+			if durErr != nil {
+				return "", fmt.Errorf("since %q is neither a timestamp nor a duration", args.Since)
+			}
+			since = time.Now().Add(-duration)
+		}
+	}
+	if args.Tail < 0 {
+		return "", fmt.Errorf("tail must not be negative")
+	}
+
+	return parent.Logs(ctx, s.svcs, since, args.Tail)
+}
+
+func (s *serviceSchema) exitCode(ctx *core.Context, parent *core.Service, args any) (*int, error) {
+	return parent.ExitCode(ctx, s.svcs)
+}
+
+func (s *serviceSchema) start(ctx *core.Context, parent *core.Service, args any) (core.ServiceID, error) {
+	if _, err := s.svcs.Start(ctx, parent); err != nil {
+		return "", err
+	}
+	return parent.ID()
+}
+
+func (s *serviceSchema) stop(ctx *core.Context, parent *core.Service, args any) (core.ServiceID, error) {
+	if err := s.svcs.Stop(ctx, s.bk, parent); err != nil {
+		return "", err
+	}
+	return parent.ID()
+}
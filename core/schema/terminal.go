@@ -0,0 +1,43 @@
+package schema
+
+import (
+	"github.com/dagger/dagger/core"
+)
+
+type terminalSchema struct {
+	*MergedSchemas
+}
+
+var _ ExecutableSchema = &terminalSchema{}
+
+func (s *terminalSchema) Name() string {
+	return "terminal"
+}
+
+func (s *terminalSchema) Schema() string {
+	return Terminal
+}
+
+var terminalIDResolver = stringResolver(core.TerminalID(""))
+
+func (s *terminalSchema) Resolvers() Resolvers {
+	return Resolvers{
+		"TerminalID": terminalIDResolver,
+		"Terminal": ObjectResolver{
+			"id":                ToResolver(s.id),
+			"websocketEndpoint": ToResolver(s.websocketEndpoint),
+		},
+	}
+}
+
+func (s *terminalSchema) Dependencies() []ExecutableSchema {
+	return nil
+}
+
+func (s *terminalSchema) id(ctx *core.Context, parent *core.Terminal, args any) (core.TerminalID, error) {
+	return parent.ID, nil
+}
+
+func (s *terminalSchema) websocketEndpoint(ctx *core.Context, parent *core.Terminal, args any) (string, error) {
+	return parent.WebsocketEndpoint, nil
+}
@@ -6,15 +6,21 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/dagger/dagger/core/pipeline"
 	"github.com/dagger/dagger/core/resourceid"
+	"github.com/dagger/dagger/core/socket"
 	"github.com/dagger/dagger/engine"
 	"github.com/dagger/dagger/engine/buildkit"
 	"github.com/dagger/dagger/network"
+	"github.com/moby/buildkit/client/llb"
 	bkgw "github.com/moby/buildkit/frontend/gateway/client"
+	gwpb "github.com/moby/buildkit/frontend/gateway/pb"
 	"github.com/moby/buildkit/solver/pb"
 	"github.com/opencontainers/go-digest"
 	"github.com/vito/progrock"
@@ -23,6 +29,21 @@ import (
 type Service struct {
 	// Container is the container to run as a service.
 	Container *Container `json:"container"`
+	// Healthcheck configures how to check that the container is ready. If
+	// unset, the image's HEALTHCHECK is run, or else the exposed ports are
+	// waited for.
+	Healthcheck *ServiceHealthcheck `json:"health_check,omitempty"`
+
+	// TunnelUpstream is the service that this service tunnels to.
+	TunnelUpstream *Service `json:"tunnel_upstream,omitempty"`
+	// TunnelPorts configures the ports forwarded from the host to the upstream.
+	TunnelPorts []PortForward `json:"tunnel_ports,omitempty"`
+
+	// HostUpstream is the host address (i.e. hostname or IP) that the service
+	// forwards to.
+	HostUpstream string `json:"host_upstream,omitempty"`
+	// HostPorts configures the ports forwarded from the service to the host.
+	HostPorts []PortForward `json:"host_ports,omitempty"`
 }
 
 func NewContainerService(ctr *Container) *Service {
@@ -31,6 +52,20 @@ func NewContainerService(ctr *Container) *Service {
 	}
 }
 
+func NewHostService(upstream string, ports []PortForward) *Service {
+	return &Service{
+		HostUpstream: upstream,
+		HostPorts:    ports,
+	}
+}
+
+func NewTunnelService(upstream *Service, ports []PortForward) *Service {
+	return &Service{
+		TunnelUpstream: upstream,
+		TunnelPorts:    ports,
+	}
+}
+
 type ServiceID string
 
 func (id ServiceID) String() string {
@@ -78,14 +113,93 @@ func (svc *Service) Clone() *Service {
 	if cp.Container != nil {
 		cp.Container = cp.Container.Clone()
 	}
+	if cp.Healthcheck != nil {
+		check := *cp.Healthcheck
+		check.Exec = cloneSlice(check.Exec)
+		cp.Healthcheck = &check
+	}
+	if cp.TunnelUpstream != nil {
+		cp.TunnelUpstream = cp.TunnelUpstream.Clone()
+	}
+	cp.TunnelPorts = cloneSlice(cp.TunnelPorts)
+	cp.HostPorts = cloneSlice(cp.HostPorts)
 	return &cp
 }
 
+// WithHealthcheck returns the service with the given health check, which
+// must pass for the service to start. If fromImage is true, the check runs the
+// HEALTHCHECK of the container's image, with the timings of the given check
+// overriding the image's.
+func (svc *Service) WithHealthcheck(check ServiceHealthcheck, fromImage bool) (*Service, error) {
+	if svc.Container == nil {
+		return nil, errors.New("health checks are only supported by container services")
+	}
+
+	kinds := 0
+	if len(check.Exec) > 0 {
+		kinds++
+	}
+	if check.HTTPPath != "" {
+		kinds++
+	}
+	if check.TCP {
+		kinds++
+	}
+	if fromImage {
+		kinds++
+	}
+	if kinds != 1 {
+		return nil, errors.New("health check must set exactly one of exec, httpPath, tcp or image")
+	}
+
+	if fromImage {
+		imageCheck := imageHealthcheck(svc.Container.Config)
+		if imageCheck == nil {
+			return nil, errors.New("container's image has no HEALTHCHECK")
+		}
+		check.Exec = imageCheck.Exec
+		for _, d := range []struct {
+			dest  *time.Duration
+			image time.Duration
+		}{
+			{&check.Interval, imageCheck.Interval},
+			{&check.Timeout, imageCheck.Timeout},
+			{&check.StartPeriod, imageCheck.StartPeriod},
+			{&check.StartInterval, imageCheck.StartInterval},
+		} {
+			if *d.dest == 0 {
+				*d.dest = d.image
+			}
+		}
+		if check.Retries == 0 {
+			check.Retries = imageCheck.Retries
+		}
+	}
+
+	if check.Retries < 0 {
+		return nil, errors.New("health check retries must not be negative")
+	}
+
+	if len(check.Exec) == 0 && check.Port == 0 {
+		if len(svc.Container.Ports) == 0 {
+			return nil, errors.New("health check has no port to check, and no ports are exposed")
+		}
+
+		check.Port = svc.Container.Ports[0].Port
+	}
+
+	svc = svc.Clone()
+	svc.Healthcheck = &check
+	return svc, nil
+}
+
 // PipelinePath returns the service's pipeline path.
 func (svc *Service) PipelinePath() pipeline.Path {
 	switch {
 	case svc.Container != nil:
 		return svc.Container.Pipeline
+	case svc.TunnelUpstream != nil:
+		return svc.TunnelUpstream.PipelinePath()
 	default:
 		return pipeline.Path{}
 	}
@@ -102,13 +216,16 @@ func (svc *Service) Digest() (digest.Digest, error) {
 
 func (svc *Service) Hostname(ctx context.Context, svcs *Services) (string, error) {
 	switch {
-	case svc.Container != nil: // container=>container
+	case svc.Container != nil, // container=>container
+		svc.HostUpstream != "": // container=>host
 		dig, err := svc.Digest()
 		if err != nil {
 			return "", err
 		}
 
 		return network.HostHash(dig), nil
+	case svc.TunnelUpstream != nil: // host=>container (127.0.0.1)
+		return "localhost", nil
 	default:
 		return "", errors.New("unknown service type")
 	}
@@ -118,6 +235,23 @@ func (svc *Service) Ports(ctx context.Context, svcs *Services) ([]Port, error) {
 	switch {
 	case svc.Container != nil:
 		return svc.Container.Ports, nil
+	case svc.TunnelUpstream != nil:
+		running, err := svcs.Get(ctx, svc)
+		if err != nil {
+			return nil, err
+		}
+
+		return running.Ports, nil
+	case svc.HostUpstream != "":
+		ports := make([]Port, 0, len(svc.HostPorts))
+		for _, fwd := range svc.HostPorts {
+			ports = append(ports, Port{
+				Port:     fwd.FrontendOrBackendPort(),
+				Protocol: fwd.Protocol,
+			})
+		}
+
+		return ports, nil
 	default:
 		return nil, errors.New("unknown service type")
 	}
@@ -140,6 +274,34 @@ func (svc *Service) Endpoint(ctx context.Context, svcs *Services, port int, sche
 
 			port = svc.Container.Ports[0].Port
 		}
+	case svc.TunnelUpstream != nil:
+		running, err := svcs.Get(ctx, svc)
+		if err != nil {
+			return "", err
+		}
+
+		host = running.Host
+
+		if port == 0 {
+			if len(running.Ports) == 0 {
+				return "", fmt.Errorf("no ports")
+			}
+
+			port = running.Ports[0].Port
+		}
+	case svc.HostUpstream != "":
+		host, err = svc.Hostname(ctx, svcs)
+		if err != nil {
+			return "", err
+		}
+
+		if port == 0 {
+			if len(svc.HostPorts) == 0 {
+				return "", fmt.Errorf("no ports")
+			}
+
+			port = svc.HostPorts[0].FrontendOrBackendPort()
+		}
 	default:
 		return "", fmt.Errorf("unknown service type")
 	}
@@ -152,10 +314,51 @@ func (svc *Service) Endpoint(ctx context.Context, svcs *Services, port int, sche
 	return endpoint, nil
 }
 
+// Logs returns the output of the latest run of the service since the given
+// time, or all of it if it is zero, limited to the last tail lines if tail is
+// positive.
+func (svc *Service) Logs(ctx context.Context, svcs *Services, since time.Time, tail int) (string, error) {
+	if svc.Container == nil {
+		return "", fmt.Errorf("logs are only available for container services")
+	}
+
+	log, err := svcs.Log(ctx, svc)
+	if err != nil {
+		return "", err
+	}
+	if log == nil {
+		return "", fmt.Errorf("service has not been started")
+	}
+
+	return log.Lines(since, tail), nil
+}
+
+// ExitCode returns the exit code of the latest run of the service, or nil if
+// it is still running or has never run.
+func (svc *Service) ExitCode(ctx context.Context, svcs *Services) (*int, error) {
+	if svc.Container == nil {
+		return nil, fmt.Errorf("exit codes are only available for container services")
+	}
+
+	log, err := svcs.Log(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+	if log == nil {
+		return nil, nil
+	}
+
+	return log.ExitCode(), nil
+}
+
 func (svc *Service) Start(ctx context.Context, bk *buildkit.Client, svcs *Services) (running *RunningService, err error) {
 	switch {
 	case svc.Container != nil:
 		return svc.startContainer(ctx, bk, svcs)
+	case svc.TunnelUpstream != nil:
+		return svc.startTunnel(ctx, bk, svcs)
+	case svc.HostUpstream != "":
+		return svc.startReverseTunnel(ctx, bk, svcs)
 	default:
 		return nil, fmt.Errorf("unknown service type")
 	}
@@ -222,43 +425,11 @@ func (svc *Service) startContainer(ctx context.Context, bk *buildkit.Client, svc
 
 	fullHost := host + "." + network.ClientDomain(clientMetadata.ClientID)
 
-	health := newHealth(bk, fullHost, ctr.Ports)
-
 	pbPlatform := pb.PlatformFromSpec(ctr.Platform)
 
-	mounts := make([]bkgw.Mount, len(execOp.Mounts))
-	for i, m := range execOp.Mounts {
-		mount := bkgw.Mount{
-			Selector:  m.Selector,
-			Dest:      m.Dest,
-			ResultID:  m.ResultID,
-			Readonly:  m.Readonly,
-			MountType: m.MountType,
-			CacheOpt:  m.CacheOpt,
-			SecretOpt: m.SecretOpt,
-			SSHOpt:    m.SSHOpt,
-			// TODO(vito): why is there no TmpfsOpt? PR upstream?
-			// TmpfsOpt  *TmpfsOpt   `protobuf:"bytes,19,opt,name=TmpfsOpt,proto3" json:"TmpfsOpt,omitempty"`
-		}
-
-		if m.Input > -1 {
-			input := execOp.Input(m.Input)
-			def, err := input.Marshal()
-			if err != nil {
-				return nil, fmt.Errorf("marshal mount %s: %w", m.Dest, err)
-			}
-
-			res, err := bk.Solve(ctx, bkgw.SolveRequest{
-				Definition: def,
-			})
-			if err != nil {
-				return nil, fmt.Errorf("solve mount %s: %w", m.Dest, err)
-			}
-
-			mount.Ref = res.Ref
-		}
-
-		mounts[i] = mount
+	mounts, err := execMounts(ctx, bk, execOp)
+	if err != nil {
+		return nil, err
 	}
 
 	gc, err := bk.NewContainer(ctx, bkgw.NewContainerRequest{
@@ -276,30 +447,62 @@ func (svc *Service) startContainer(ctx context.Context, bk *buildkit.Client, svc
 		}
 	}()
 
-	checked := make(chan error, 1)
-	go func() {
-		checked <- health.Check(ctx)
-	}()
+	env := append(execOp.Meta.Env, proxyEnvList(execOp.Meta.ProxyEnv)...)
+
+	log := svcs.newLog(ServiceKey{
+		Digest:   dig,
+		ClientID: clientMetadata.ClientID,
+	})
 
-	outBuf := new(bytes.Buffer)
 	svcProc, err := gc.Start(ctx, bkgw.StartRequest{
 		Args:         execOp.Meta.Args,
-		Env:          append(execOp.Meta.Env, proxyEnvList(execOp.Meta.ProxyEnv)...),
+		Env:          env,
 		Cwd:          execOp.Meta.Cwd,
 		User:         execOp.Meta.User,
 		SecretEnv:    execOp.Secretenv,
 		Tty:          false,
-		Stdout:       nopCloser{io.MultiWriter(vtx.Stdout(), outBuf)},
-		Stderr:       nopCloser{io.MultiWriter(vtx.Stderr(), outBuf)},
+		Stdout:       nopCloser{io.MultiWriter(vtx.Stdout(), log.Writer())},
+		Stderr:       nopCloser{io.MultiWriter(vtx.Stderr(), log.Writer())},
 		SecurityMode: execOp.Security,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("start container: %w", err)
 	}
 
+	var health healthChecker = newHealth(bk, fullHost, ctr.Ports)
+
+	if check := svc.Healthcheck; check != nil {
+		health = &probeHealthchecker{
+			bk:    bk,
+			host:  fullHost,
+			check: check,
+			// NB: the service process must be started first, since the first
+			// process started in a container is its init process
+			exec: func(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+				return runProcess(ctx, gc, bkgw.StartRequest{
+					Args:         args,
+					Env:          env,
+					Cwd:          execOp.Meta.Cwd,
+					User:         execOp.Meta.User,
+					SecretEnv:    execOp.Secretenv,
+					Stdout:       nopCloser{stdout},
+					Stderr:       nopCloser{stderr},
+					SecurityMode: execOp.Security,
+				})
+			},
+		}
+	}
+
+	checked := make(chan error, 1)
+	go func() {
+		checked <- health.Check(ctx)
+	}()
+
 	exited := make(chan error, 1)
 	go func() {
-		exited <- svcProc.Wait()
+		err := svcProc.Wait()
+		log.Exited(exitCode(err))
+		exited <- err
 
 		// detach dependent services when process exits
 		detachDeps()
@@ -343,13 +546,315 @@ func (svc *Service) startContainer(ctx context.Context, bk *buildkit.Client, svc
 		}, nil
 	case err := <-exited:
 		if err != nil {
-			return nil, fmt.Errorf("exited: %w\noutput: %s", err, outBuf.String())
+			return nil, fmt.Errorf("exited: %w\noutput: %s", err, log.Lines(time.Time{}, 0))
 		}
 
 		return nil, fmt.Errorf("service exited before healthcheck")
 	}
 }
 
+func (svc *Service) startTunnel(ctx context.Context, bk *buildkit.Client, svcs *Services) (running *RunningService, err error) {
+	clientMetadata, err := engine.ClientMetadataFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dig, err := svc.Digest()
+	if err != nil {
+		return nil, err
+	}
+
+	upstream, err := svcs.Start(ctx, svc.TunnelUpstream)
+	if err != nil {
+		return nil, fmt.Errorf("start upstream: %w", err)
+	}
+
+	closers := make([]func() error, 0, len(svc.TunnelPorts))
+	stop := func(ctx context.Context) error {
+		var errs error
+		for _, closeListener := range closers {
+			errs = errors.Join(errs, closeListener())
+		}
+
+		// Services is locked while a service stops, so detach afterwards
+		go svcs.Detach(context.Background(), upstream)
+
+		return errs
+	}
+
+	defer func() {
+		if err != nil {
+			stop(context.Background())
+		}
+	}()
+
+	ports := make([]Port, 0, len(svc.TunnelPorts))
+	for _, forward := range svc.TunnelPorts {
+		proto := forward.Protocol
+		if proto == "" {
+			proto = NetworkProtocolTCP
+		}
+
+		addr, closeListener, err := bk.ListenHostToContainer(
+			ctx,
+			fmt.Sprintf("127.0.0.1:%d", forward.Frontend),
+			proto.Network(),
+			fmt.Sprintf("%s:%d", upstream.Host, forward.Backend),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("forward port %d: %w", forward.Backend, err)
+		}
+		closers = append(closers, closeListener)
+
+		_, portStr, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, fmt.Errorf("parse listen address %s: %w", addr, err)
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			return nil, fmt.Errorf("parse listen port %s: %w", portStr, err)
+		}
+
+		desc := fmt.Sprintf("tunnel %s -> %s:%d", addr, upstream.Host, forward.Backend)
+		ports = append(ports, Port{
+			Port:        port,
+			Protocol:    proto,
+			Description: &desc,
+		})
+	}
+
+	return &RunningService{
+		Host:  "localhost",
+		Ports: ports,
+		Key: ServiceKey{
+			Digest:   dig,
+			ClientID: clientMetadata.ClientID,
+		},
+		Stop: stop,
+	}, nil
+}
+
+// tunnelSocketsDir is where the sockets proxying to the host are mounted in a
+// reverse tunnel's container.
+const tunnelSocketsDir = "/tunnels"
+
+func (svc *Service) startReverseTunnel(ctx context.Context, bk *buildkit.Client, svcs *Services) (running *RunningService, err error) {
+	dig, err := svc.Digest()
+	if err != nil {
+		return nil, err
+	}
+
+	host, err := svc.Hostname(ctx, svcs)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := progrock.FromContext(ctx).WithGroup(
+		fmt.Sprintf("service %s", host),
+		progrock.Weak(),
+	)
+
+	clientMetadata, err := engine.ClientMetadataFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fullHost := host + "." + network.ClientDomain(clientMetadata.ClientID)
+
+	ports, err := svc.Ports(ctx, svcs)
+	if err != nil {
+		return nil, err
+	}
+
+	scratchDef, err := llb.Scratch().Marshal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scratchRes, err := bk.Solve(ctx, bkgw.SolveRequest{
+		Definition: scratchDef.ToPB(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	mounts := []bkgw.Mount{
+		{
+			Dest:      "/",
+			MountType: pb.MountType_BIND,
+			Ref:       scratchRes.Ref,
+		},
+	}
+
+	// forward each port to a socket that the client proxies to the host
+	args := []string{"tunnel"}
+	for i, fwd := range svc.HostPorts {
+		proto := fwd.Protocol
+		if proto == "" {
+			proto = NetworkProtocolTCP
+		}
+
+		sockID, err := socket.NewHostIPSocket(
+			proto.Network(),
+			net.JoinHostPort(svc.HostUpstream, strconv.Itoa(fwd.Backend)),
+		).ID()
+		if err != nil {
+			return nil, err
+		}
+
+		sockPath := fmt.Sprintf("%s/%d.sock", tunnelSocketsDir, i)
+		mounts = append(mounts, bkgw.Mount{
+			Dest:      sockPath,
+			MountType: pb.MountType_SSH,
+			SSHOpt: &pb.SSHOpt{
+				ID:   string(sockID),
+				Mode: 0o600,
+			},
+		})
+
+		args = append(args, fmt.Sprintf("%d/%s=%s", fwd.FrontendOrBackendPort(), proto.Network(), sockPath))
+	}
+
+	vtx := rec.Vertex(dig, fmt.Sprintf("tunnel to host %s", svc.HostUpstream))
+	defer func() {
+		if err != nil {
+			vtx.Error(err)
+		}
+	}()
+
+	gc, err := bk.NewContainer(ctx, bkgw.NewContainerRequest{
+		Mounts:   mounts,
+		Hostname: fullHost,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("new container: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			gc.Release(context.Background())
+		}
+	}()
+
+	checked := make(chan error, 1)
+	go func() {
+		checked <- newHealth(bk, fullHost, ports).Check(ctx)
+	}()
+
+	outBuf := new(bytes.Buffer)
+	proc, err := gc.Start(ctx, bkgw.StartRequest{
+		Args:   args,
+		Env:    []string{"_DAGGER_INTERNAL_COMMAND="},
+		Stdout: nopCloser{io.MultiWriter(vtx.Stdout(), outBuf)},
+		Stderr: nopCloser{io.MultiWriter(vtx.Stderr(), outBuf)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("start tunnel: %w", err)
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- proc.Wait()
+	}()
+
+	stop := func(ctx context.Context) (stopErr error) {
+		defer func() {
+			vtx.Done(stopErr)
+		}()
+
+		if err := proc.Signal(ctx, syscall.SIGKILL); err != nil {
+			return fmt.Errorf("signal: %w", err)
+		}
+
+		if err := gc.Release(ctx); err != nil {
+			if !errors.Is(err, context.Canceled) {
+				return fmt.Errorf("release: %w", err)
+			}
+		}
+
+		return nil
+	}
+
+	select {
+	case err := <-checked:
+		if err != nil {
+			return nil, fmt.Errorf("health check errored: %w", err)
+		}
+
+		return &RunningService{
+			Host:  fullHost,
+			Ports: ports,
+			Key: ServiceKey{
+				Digest:   dig,
+				ClientID: clientMetadata.ClientID,
+			},
+			Stop: stop,
+		}, nil
+	case err := <-exited:
+		if err != nil {
+			return nil, fmt.Errorf("exited: %w\noutput: %s", err, outBuf.String())
+		}
+
+		return nil, fmt.Errorf("tunnel exited before healthcheck")
+	}
+}
+
+// exitCode returns the exit code of a process from the error returned by
+// waiting for it.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var exitErr *gwpb.ExitError
+	if errors.As(err, &exitErr) {
+		return int(exitErr.ExitCode)
+	}
+
+	return gwpb.UnknownExitStatus
+}
+
+// execMounts solves the inputs of an exec op into mounts for a gateway
+// container.
+func execMounts(ctx context.Context, bk *buildkit.Client, execOp *execOp) ([]bkgw.Mount, error) {
+	mounts := make([]bkgw.Mount, len(execOp.Mounts))
+	for i, m := range execOp.Mounts {
+		mount := bkgw.Mount{
+			Selector:  m.Selector,
+			Dest:      m.Dest,
+			ResultID:  m.ResultID,
+			Readonly:  m.Readonly,
+			MountType: m.MountType,
+			CacheOpt:  m.CacheOpt,
+			SecretOpt: m.SecretOpt,
+			SSHOpt:    m.SSHOpt,
+			// TODO(vito): why is there no TmpfsOpt? PR upstream?
+			// TmpfsOpt  *TmpfsOpt   `protobuf:"bytes,19,opt,name=TmpfsOpt,proto3" json:"TmpfsOpt,omitempty"`
+		}
+
+		if m.Input > -1 {
+			input := execOp.Input(m.Input)
+			def, err := input.Marshal()
+			if err != nil {
+				return nil, fmt.Errorf("marshal mount %s: %w", m.Dest, err)
+			}
+
+			res, err := bk.Solve(ctx, bkgw.SolveRequest{
+				Definition: def,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("solve mount %s: %w", m.Dest, err)
+			}
+
+			mount.Ref = res.Ref
+		}
+
+		mounts[i] = mount
+	}
+
+	return mounts, nil
+}
+
 func proxyEnvList(p *pb.ProxyEnv) []string {
 	out := []string{}
 	if v := p.HttpProxy; v != "" {
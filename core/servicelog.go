@@ -0,0 +1,126 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServiceLogLimit is the number of lines of output kept for each service;
+// older lines are dropped.
+const ServiceLogLimit = 10000
+
+// ServiceLog records the output of a run of a service line by line, along
+// with its exit code once it exits.
+type ServiceLog struct {
+	lines    []serviceLogLine
+	writers  []*serviceLogWriter
+	exitCode *int
+	l        sync.Mutex
+}
+
+type serviceLogLine struct {
+	time time.Time
+	text string
+}
+
+func NewServiceLog() *ServiceLog {
+	return &ServiceLog{}
+}
+
+// Writer returns a writer for one of the output streams of the service.
+// Each stream needs its own writer so that their lines don't get mixed up.
+func (log *ServiceLog) Writer() io.Writer {
+	log.l.Lock()
+	defer log.l.Unlock()
+	w := &serviceLogWriter{log: log}
+	log.writers = append(log.writers, w)
+	return w
+}
+
+// Exited records the exit code of the service, along with any output left
+// without a trailing newline.
+func (log *ServiceLog) Exited(code int) {
+	log.l.Lock()
+	defer log.l.Unlock()
+	for _, w := range log.writers {
+		if len(w.partial) > 0 {
+			log.add(string(w.partial) + "\n")
+			w.partial = nil
+		}
+	}
+	log.exitCode = &code
+}
+
+// ExitCode returns the exit code of the service, or nil if it hasn't exited.
+func (log *ServiceLog) ExitCode() *int {
+	log.l.Lock()
+	defer log.l.Unlock()
+	return log.exitCode
+}
+
+// Lines returns the output written since the given time, or all of it if it
+// is zero, limited to the last tail lines if tail is positive.
+func (log *ServiceLog) Lines(since time.Time, tail int) string {
+	log.l.Lock()
+	defer log.l.Unlock()
+
+	lines := log.lines
+	if !since.IsZero() {
+		for i, line := range lines {
+			if !line.time.Before(since) {
+				lines = lines[i:]
+				break
+			}
+			if i == len(lines)-1 {
+				lines = nil
+			}
+		}
+	}
+	if tail > 0 && len(lines) > tail {
+		lines = lines[len(lines)-tail:]
+	}
+
+	var out strings.Builder
+	for _, line := range lines {
+		out.WriteString(line.text)
+	}
+	return out.String()
+}
+
+func (log *ServiceLog) add(text string) {
+	log.lines = append(log.lines, serviceLogLine{
+		time: time.Now(),
+		text: text,
+	})
+	if over := len(log.lines) - ServiceLogLimit; over > 0 {
+		// append only copies the remaining lines when it grows the slice
+		log.lines = log.lines[over:]
+	}
+}
+
+type serviceLogWriter struct {
+	log     *ServiceLog
+	partial []byte
+}
+
+func (w *serviceLogWriter) Write(p []byte) (int, error) {
+	w.log.l.Lock()
+	defer w.log.l.Unlock()
+
+	rest := p
+	for {
+		i := bytes.IndexByte(rest, '\n')
+		if i == -1 {
+			break
+		}
+		w.log.add(string(w.partial) + string(rest[:i+1]))
+		w.partial = nil
+		rest = rest[i+1:]
+	}
+	w.partial = append(w.partial, rest...)
+
+	return len(p), nil
+}
@@ -0,0 +1,53 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceLog(t *testing.T) {
+	t.Parallel()
+
+	log := NewServiceLog()
+	stdout := log.Writer()
+	stderr := log.Writer()
+
+	io.WriteString(stdout, "one\ntw")
+	io.WriteString(stderr, "err")
+	io.WriteString(stdout, "o\n")
+	require.Equal(t, "one\ntwo\n", log.Lines(time.Time{}, 0))
+	require.Nil(t, log.ExitCode())
+
+	mid := time.Now()
+	io.WriteString(stdout, "three\n")
+	io.WriteString(stderr, "or\nexiting")
+
+	require.Equal(t, "one\ntwo\nthree\nerror\n", log.Lines(time.Time{}, 0))
+	require.Equal(t, "three\nerror\n", log.Lines(mid, 0))
+	require.Equal(t, "error\n", log.Lines(time.Time{}, 1))
+	require.Equal(t, "", log.Lines(time.Now().Add(time.Minute), 0))
+
+	log.Exited(3)
+	require.Equal(t, "two\nthree\nerror\nexiting\n", log.Lines(time.Time{}, 4))
+	require.Equal(t, 3, *log.ExitCode())
+}
+
+func TestServiceLogLimit(t *testing.T) {
+	t.Parallel()
+
+	log := NewServiceLog()
+	w := log.Writer()
+	for i := 0; i < ServiceLogLimit+10; i++ {
+		fmt.Fprintf(w, "line %d\n", i)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(log.Lines(time.Time{}, 0), "\n"), "\n")
+	require.Len(t, lines, ServiceLogLimit)
+	require.Equal(t, "line 10", lines[0])
+	require.Equal(t, fmt.Sprintf("line %d", ServiceLogLimit+9), lines[len(lines)-1])
+}
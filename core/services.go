@@ -27,6 +27,7 @@ type Services struct {
 	starting map[ServiceKey]*sync.WaitGroup
 	running  map[ServiceKey]*RunningService
 	bindings map[ServiceKey]int
+	logs     map[ServiceKey]*ServiceLog
 	l        sync.Mutex
 }
 
@@ -67,6 +68,7 @@ func NewServices(bk *buildkit.Client) *Services {
 		starting: map[ServiceKey]*sync.WaitGroup{},
 		running:  map[ServiceKey]*RunningService{},
 		bindings: map[ServiceKey]int{},
+		logs:     map[ServiceKey]*ServiceLog{},
 	}
 }
 
@@ -109,6 +111,39 @@ func (ss *Services) Get(ctx context.Context, svc Startable) (*RunningService, er
 	}
 }
 
+// Log returns the log of the latest run of the given service, which is kept
+// after it stops until the client closes, or nil if it hasn't run.
+func (ss *Services) Log(ctx context.Context, svc Startable) (*ServiceLog, error) {
+	clientMetadata, err := engine.ClientMetadataFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dig, err := svc.Digest()
+	if err != nil {
+		return nil, err
+	}
+
+	key := ServiceKey{
+		Digest:   dig,
+		ClientID: clientMetadata.ClientID,
+	}
+
+	ss.l.Lock()
+	defer ss.l.Unlock()
+	return ss.logs[key], nil
+}
+
+// newLog returns a new log for a run of the service with the given key,
+// replacing the log of any previous run.
+func (ss *Services) newLog(key ServiceKey) *ServiceLog {
+	log := NewServiceLog()
+	ss.l.Lock()
+	ss.logs[key] = log
+	ss.l.Unlock()
+	return log
+}
+
 type Startable interface {
 	Digest() (digest.Digest, error)
 
@@ -289,6 +324,12 @@ func (ss *Services) StopClientServices(ctx context.Context, client *engine.Clien
 	ss.l.Lock()
 	defer ss.l.Unlock()
 
+	for key := range ss.logs {
+		if key.ClientID == client.ClientID {
+			delete(ss.logs, key)
+		}
+	}
+
 	eg := new(errgroup.Group)
 	for _, svc := range ss.running {
 		if svc.Key.ClientID != client.ClientID {
@@ -0,0 +1,292 @@
+package core
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/docker/distribution/reference"
+	"github.com/opencontainers/go-digest"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/dagger/dagger/engine/buildkit"
+)
+
+// simpleSigningType is the type of the payload that cosign signs for an
+// image.
+const simpleSigningType = "cosign container image signature"
+
+// PEM block types of the encrypted private keys generated by cosign.
+const (
+	sigstorePrivateKeyPEMType = "ENCRYPTED SIGSTORE PRIVATE KEY"
+	cosignPrivateKeyPEMType   = "ENCRYPTED COSIGN PRIVATE KEY"
+)
+
+// simpleSigningPayload is the payload that's signed to sign an image, in the
+// "simple signing" format used by cosign.
+type simpleSigningPayload struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+	Optional map[string]any `json:"optional"`
+}
+
+// ParseSigningKey parses a PEM-encoded private key for signing images. The
+// key can be a PKCS #8, EC or RSA private key, or a key generated by cosign,
+// which is decrypted with the given password.
+func ParseSigningKey(keyPEM, password []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("signing key is not PEM-encoded")
+	}
+
+	der := block.Bytes
+
+	var key any
+	var err error
+	switch block.Type {
+	case sigstorePrivateKeyPEMType, cosignPrivateKeyPEMType:
+		der, err = decryptCosignKey(der, password)
+		if err != nil {
+			return nil, err
+		}
+		key, err = x509.ParsePKCS8PrivateKey(der)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(der)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(der)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(der)
+	default:
+		return nil, fmt.Errorf("unsupported signing key type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse signing key: %w", err)
+	}
+
+	switch key := key.(type) {
+	case *ecdsa.PrivateKey:
+		return key, nil
+	case *rsa.PrivateKey:
+		return key, nil
+	case ed25519.PrivateKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported signing key algorithm %T", key)
+	}
+}
+
+// decryptCosignKey decrypts a private key generated by cosign, which is
+// encrypted with NaCl secretbox using a key derived from the password with
+// scrypt.
+func decryptCosignKey(data, password []byte) ([]byte, error) {
+	var enc struct {
+		KDF struct {
+			Name   string `json:"name"`
+			Params struct {
+				N int `json:"N"`
+				R int `json:"r"`
+				P int `json:"p"`
+			} `json:"params"`
+			Salt []byte `json:"salt"`
+		} `json:"kdf"`
+		Cipher struct {
+			Name  string `json:"name"`
+			Nonce []byte `json:"nonce"`
+		} `json:"cipher"`
+		Ciphertext []byte `json:"ciphertext"`
+	}
+	if err := json.Unmarshal(data, &enc); err != nil {
+		return nil, fmt.Errorf("parse encrypted signing key: %w", err)
+	}
+	if enc.KDF.Name != "scrypt" || enc.Cipher.Name != "nacl/secretbox" {
+		return nil, fmt.Errorf("unsupported signing key encryption %s/%s", enc.KDF.Name, enc.Cipher.Name)
+	}
+
+	var nonce [24]byte
+	if len(enc.Cipher.Nonce) != len(nonce) {
+		return nil, errors.New("invalid signing key nonce")
+	}
+	copy(nonce[:], enc.Cipher.Nonce)
+
+	derived, err := scrypt.Key(password, enc.KDF.Salt, enc.KDF.Params.N, enc.KDF.Params.R, enc.KDF.Params.P, 32)
+	if err != nil {
+		return nil, fmt.Errorf("derive signing key encryption key: %w", err)
+	}
+	var secret [32]byte
+	copy(secret[:], derived)
+
+	der, ok := secretbox.Open(nil, enc.Ciphertext, &nonce, &secret)
+	if !ok {
+		return nil, errors.New("decrypt signing key: incorrect password")
+	}
+	return der, nil
+}
+
+// ParseVerificationKey parses a PEM-encoded PKIX public key for verifying
+// image signatures.
+func ParseVerificationKey(keyPEM []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("public key is not PEM-encoded")
+	}
+	if block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("unsupported public key type %q", block.Type)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+	return key, nil
+}
+
+// signImage signs the image with the given digest, pushing the signature to
+// its repository alongside it.
+func signImage(ctx context.Context, bk *buildkit.Client, name reference.Named, dgst digest.Digest, signer crypto.Signer) error {
+	payloadBytes, err := imageSignaturePayload(name.Name(), dgst)
+	if err != nil {
+		return err
+	}
+
+	sig, err := signPayload(signer, payloadBytes)
+	if err != nil {
+		return fmt.Errorf("sign image: %w", err)
+	}
+
+	return bk.PushImageSignature(ctx, name, dgst, buildkit.ImageSignature{
+		Payload:   payloadBytes,
+		Signature: base64.StdEncoding.EncodeToString(sig),
+	})
+}
+
+func imageSignaturePayload(name string, dgst digest.Digest) ([]byte, error) {
+	var payload simpleSigningPayload
+	payload.Critical.Identity.DockerReference = name
+	payload.Critical.Image.DockerManifestDigest = dgst.String()
+	payload.Critical.Type = simpleSigningType
+	return json.Marshal(payload)
+}
+
+// VerifySignature checks that the image the container was created from is
+// signed with the private key corresponding to the given public key,
+// returning the container as-is if it is.
+func (container *Container) VerifySignature(ctx context.Context, bk *buildkit.Client, publicKeyPEM []byte) (*Container, error) {
+	if container.ImageRef == "" {
+		return nil, errors.New("container has no image to verify; use from to pull one")
+	}
+
+	pub, err := ParseVerificationKey(publicKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	ref, err := reference.ParseNormalizedNamed(container.ImageRef)
+	if err != nil {
+		return nil, err
+	}
+	digested, ok := ref.(reference.Canonical)
+	if !ok {
+		return nil, fmt.Errorf("image ref %s has no digest", container.ImageRef)
+	}
+
+	sigs, err := bk.ImageSignatures(ctx, ref, digested.Digest())
+	if err != nil {
+		return nil, err
+	}
+	if len(sigs) == 0 {
+		return nil, fmt.Errorf("no signatures found for %s", container.ImageRef)
+	}
+
+	for _, sig := range sigs {
+		if verifyImageSignature(pub, ref, digested.Digest(), sig) == nil {
+			return container, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no signature of %s matches the public key", container.ImageRef)
+}
+
+// verifyImageSignature checks that the signature is valid for the given
+// public key and that its payload refers to the image with the given
+// repository and digest.
+func verifyImageSignature(pub crypto.PublicKey, name reference.Named, dgst digest.Digest, sig buildkit.ImageSignature) error {
+	sigBytes, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil {
+		return fmt.Errorf("decode signature: %w", err)
+	}
+
+	if err := verifyPayload(pub, sig.Payload, sigBytes); err != nil {
+		return err
+	}
+
+	var payload simpleSigningPayload
+	if err := json.Unmarshal(sig.Payload, &payload); err != nil {
+		return fmt.Errorf("parse signature payload: %w", err)
+	}
+	if payload.Critical.Type != simpleSigningType {
+		return fmt.Errorf("unexpected signature payload type %q", payload.Critical.Type)
+	}
+	if payload.Critical.Image.DockerManifestDigest != dgst.String() {
+		return fmt.Errorf("signature is for %s, not %s", payload.Critical.Image.DockerManifestDigest, dgst)
+	}
+
+	// otherwise a signature could be copied over from another repository
+	// that the key signed the same image in
+	identity, err := reference.ParseNormalizedNamed(payload.Critical.Identity.DockerReference)
+	if err != nil {
+		return fmt.Errorf("parse signature identity: %w", err)
+	}
+	if identity.Name() != name.Name() {
+		return fmt.Errorf("signature is for %s, not %s", identity.Name(), name.Name())
+	}
+
+	return nil
+}
+
+func signPayload(signer crypto.Signer, payload []byte) ([]byte, error) {
+	if _, ok := signer.(ed25519.PrivateKey); ok {
+		// ed25519 signs the message itself rather than a hash of it
+		return signer.Sign(rand.Reader, payload, crypto.Hash(0))
+	}
+
+	hash := sha256.Sum256(payload)
+	return signer.Sign(rand.Reader, hash[:], crypto.SHA256)
+}
+
+func verifyPayload(pub crypto.PublicKey, payload, sig []byte) error {
+	hash := sha256.Sum256(payload)
+
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(pub, hash[:], sig) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, hash[:], sig)
+	case ed25519.PublicKey:
+		if !ed25519.Verify(pub, payload, sig) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported public key algorithm %T", pub)
+	}
+}
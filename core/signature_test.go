@@ -0,0 +1,161 @@
+package core
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+
+	"github.com/docker/distribution/reference"
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/dagger/dagger/engine/buildkit"
+)
+
+func TestImageSignature(t *testing.T) {
+	t.Parallel()
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	image, err := reference.ParseNormalizedNamed("registry.example.com/image")
+	require.NoError(t, err)
+	dgst := digest.FromString("image")
+
+	for name, key := range map[string]crypto.Signer{
+		"ecdsa":   ecKey,
+		"rsa":     rsaKey,
+		"ed25519": edKey,
+	} {
+		key := key
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			der, err := x509.MarshalPKCS8PrivateKey(key)
+			require.NoError(t, err)
+			signer, err := ParseSigningKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil)
+			require.NoError(t, err)
+
+			pubDER, err := x509.MarshalPKIXPublicKey(key.Public())
+			require.NoError(t, err)
+			pub, err := ParseVerificationKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}))
+			require.NoError(t, err)
+
+			sig := testImageSignature(t, signer, dgst)
+			require.NoError(t, verifyImageSignature(pub, image, dgst, sig))
+
+			t.Run("other image", func(t *testing.T) {
+				require.ErrorContains(t, verifyImageSignature(pub, image, digest.FromString("other"), sig), "signature is for")
+			})
+
+			t.Run("other repository", func(t *testing.T) {
+				other, err := reference.ParseNormalizedNamed("registry.example.com/other")
+				require.NoError(t, err)
+				require.ErrorContains(t, verifyImageSignature(pub, other, dgst, sig), "signature is for registry.example.com/image")
+			})
+
+			t.Run("tagged identity", func(t *testing.T) {
+				payloadBytes, err := imageSignaturePayload("registry.example.com/image:v1", dgst)
+				require.NoError(t, err)
+				tagged := testSignPayload(t, signer, payloadBytes)
+				require.NoError(t, verifyImageSignature(pub, image, dgst, tagged))
+			})
+
+			t.Run("tampered payload", func(t *testing.T) {
+				tampered := sig
+				tampered.Payload = append([]byte{' '}, sig.Payload...)
+				require.Error(t, verifyImageSignature(pub, image, dgst, tampered))
+			})
+		})
+	}
+
+	t.Run("wrong key", func(t *testing.T) {
+		t.Parallel()
+
+		other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		sig := testImageSignature(t, ecKey, dgst)
+		require.Error(t, verifyImageSignature(other.Public(), image, dgst, sig))
+	})
+}
+
+func TestParseSigningKeyCosign(t *testing.T) {
+	t.Parallel()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	// encrypt the key the same way cosign generate-key-pair does
+	password := []byte("hunter2")
+	salt := make([]byte, 32)
+	_, err = rand.Read(salt)
+	require.NoError(t, err)
+	var nonce [24]byte
+	_, err = rand.Read(nonce[:])
+	require.NoError(t, err)
+
+	derived, err := scrypt.Key(password, salt, 32768, 8, 1, 32)
+	require.NoError(t, err)
+	var secret [32]byte
+	copy(secret[:], derived)
+
+	enc, err := json.Marshal(map[string]any{
+		"kdf": map[string]any{
+			"name":   "scrypt",
+			"params": map[string]int{"N": 32768, "r": 8, "p": 1},
+			"salt":   salt,
+		},
+		"cipher": map[string]any{
+			"name":  "nacl/secretbox",
+			"nonce": nonce[:],
+		},
+		"ciphertext": secretbox.Seal(nil, der, &nonce, &secret),
+	})
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED SIGSTORE PRIVATE KEY", Bytes: enc})
+
+	signer, err := ParseSigningKey(keyPEM, password)
+	require.NoError(t, err)
+	require.True(t, key.Equal(signer))
+
+	_, err = ParseSigningKey(keyPEM, []byte("wrong"))
+	require.ErrorContains(t, err, "incorrect password")
+}
+
+func testImageSignature(t *testing.T, signer crypto.Signer, dgst digest.Digest) buildkit.ImageSignature {
+	t.Helper()
+
+	payloadBytes, err := imageSignaturePayload("registry.example.com/image", dgst)
+	require.NoError(t, err)
+
+	return testSignPayload(t, signer, payloadBytes)
+}
+
+func testSignPayload(t *testing.T, signer crypto.Signer, payloadBytes []byte) buildkit.ImageSignature {
+	t.Helper()
+
+	sig, err := signPayload(signer, payloadBytes)
+	require.NoError(t, err)
+
+	return buildkit.ImageSignature{
+		Payload:   payloadBytes,
+		Signature: base64.StdEncoding.EncodeToString(sig),
+	}
+}
@@ -11,6 +11,9 @@ import (
 
 type Socket struct {
 	HostPath string `json:"host_path,omitempty"`
+
+	HostProtocol string `json:"host_protocol,omitempty"`
+	HostAddr     string `json:"host_addr,omitempty"`
 }
 
 type ID string
@@ -32,18 +35,32 @@ func NewHostSocket(absPath string) *Socket {
 	}
 }
 
+// NewHostIPSocket returns a socket that connects to the given address of the
+// host, e.g. "localhost:5432", over the given network, "tcp" or "udp".
+func NewHostIPSocket(proto string, addr string) *Socket {
+	return &Socket{
+		HostProtocol: proto,
+		HostAddr:     addr,
+	}
+}
+
 func (socket *Socket) ID() (ID, error) {
 	return resourceid.Encode[ID](socket)
 }
 
 func (socket *Socket) IsHost() bool {
-	return socket.HostPath != ""
+	return socket.HostPath != "" || socket.HostAddr != ""
 }
 
 func (socket *Socket) Server() (sshforward.SSHServer, error) {
+	network, addr := "unix", socket.HostPath
+	if socket.HostAddr != "" {
+		network, addr = socket.HostProtocol, socket.HostAddr
+	}
+
 	return &socketProxy{
 		dial: func() (io.ReadWriteCloser, error) {
-			return net.Dial("unix", socket.HostPath)
+			return net.Dial(network, addr)
 		},
 	}, nil
 }
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	fstypes "github.com/tonistiigi/fsutil/types"
+)
+
+// Stat is the metadata of a file or directory.
+type Stat struct {
+	Path          string   `json:"path"`
+	FileType      FileType `json:"fileType"`
+	Size          int64    `json:"size"`
+	Permissions   int      `json:"permissions"`
+	UID           int      `json:"uid"`
+	GID           int      `json:"gid"`
+	ModifiedAt    int64    `json:"modifiedAt"`
+	SymlinkTarget *string  `json:"symlinkTarget"`
+}
+
+// FileType is a string deriving from FileType enum
+type FileType string
+
+const (
+	FileTypeRegular   FileType = "REGULAR"
+	FileTypeDirectory FileType = "DIR"
+	FileTypeSymlink   FileType = "SYMLINK"
+	FileTypeOther     FileType = "OTHER"
+)
+
+// NewStat converts the stat of the file at the given path into its API
+// representation.
+func NewStat(filePath string, stat *fstypes.Stat) *Stat {
+	mode := fs.FileMode(stat.Mode)
+
+	var typ FileType
+	switch {
+	case mode.IsRegular():
+		typ = FileTypeRegular
+	case mode.IsDir():
+		typ = FileTypeDirectory
+	case mode&fs.ModeSymlink != 0:
+		typ = FileTypeSymlink
+	default:
+		typ = FileTypeOther
+	}
+
+	var target *string
+	if typ == FileTypeSymlink {
+		target = &stat.Linkname
+	}
+
+	return &Stat{
+		Path:          filePath,
+		FileType:      typ,
+		Size:          stat.Size_,
+		Permissions:   int(mode.Perm()),
+		UID:           int(stat.Uid),
+		GID:           int(stat.Gid),
+		ModifiedAt:    time.Unix(0, stat.ModTime).Unix(),
+		SymlinkTarget: target,
+	}
+}
+
+// globMatch returns whether name matches pattern, both split into path
+// segments. Each segment of the pattern is matched with path.Match, except
+// for "**", which matches any number of segments, including none.
+//
+// If prefix is true, it instead returns whether anything beneath name could
+// match the pattern.
+func globMatch(pattern, name []string, prefix bool) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			for i := 0; i <= len(name); i++ {
+				if globMatch(rest, name[i:], prefix) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return prefix
+		}
+
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
+
+// splitGlob splits a glob pattern into its path segments, validating each.
+func splitGlob(pattern string) ([]string, error) {
+	pattern = path.Clean(strings.TrimPrefix(pattern, "/"))
+
+	segments := strings.Split(pattern, "/")
+	for _, seg := range segments {
+		if seg == "**" {
+			continue
+		}
+		if _, err := path.Match(seg, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	return segments, nil
+}
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlobMatch(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		pattern string
+		name    string
+		match   bool
+		prefix  bool
+	}{
+		{"*.go", "main.go", true, true},
+		{"*.go", "pkg", false, false},
+		{"*.go", "pkg/main.go", false, false},
+		{"**/*.go", "main.go", true, true},
+		{"**/*.go", "pkg/util/util.go", true, true},
+		{"**/*.go", "pkg", false, true},
+		{"pkg/**", "pkg", true, true},
+		{"pkg/**", "pkg/util/util.go", true, true},
+		{"pkg/**", "web", false, false},
+		{"pkg/*/*_test.go", "pkg/util", false, true},
+		{"pkg/*/*_test.go", "pkg/util/util_test.go", true, true},
+		{"pkg/*/*_test.go", "pkg/util/sub", false, false},
+		{"**", "a/b/c", true, true},
+	} {
+		tc := tc
+		t.Run(tc.pattern+" "+tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			segments, err := splitGlob(tc.pattern)
+			require.NoError(t, err)
+
+			name := strings.Split(tc.name, "/")
+			require.Equal(t, tc.match, globMatch(segments, name, false), "match")
+			require.Equal(t, tc.prefix, globMatch(segments, name, true), "prefix")
+		})
+	}
+}
+
+func TestSplitGlobInvalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := splitGlob("src/[")
+	require.Error(t, err)
+}
@@ -0,0 +1,140 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dagger/dagger/engine/buildkit"
+	bkgw "github.com/moby/buildkit/frontend/gateway/client"
+	"github.com/moby/buildkit/solver/pb"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// DefaultTerminalCmd is the command run by Container.terminal when none is
+// given.
+var DefaultTerminalCmd = []string{"sh"}
+
+// Terminal is an interactive process running in a container that clients
+// can attach to over a websocket.
+type Terminal struct {
+	// ID identifies the terminal by the digest of the exec it runs. Unlike
+	// most IDs it can't be loaded back into a terminal, since it refers to a
+	// running process.
+	ID TerminalID `json:"id"`
+
+	// WebsocketEndpoint is the session endpoint to attach to the terminal at.
+	WebsocketEndpoint string `json:"websocketEndpoint"`
+}
+
+type TerminalID string
+
+func (id TerminalID) String() string {
+	return string(id)
+}
+
+// Terminal starts a container in which args can be run interactively, with
+// the same mounts, env, secrets, sockets and service bindings that a
+// WithExec of args would have.
+//
+// The process is started with a TTY once a client attaches to the returned
+// terminal. The container and any services it binds to are released once the
+// process exits.
+func (container *Container) Terminal(
+	ctx context.Context,
+	bk *buildkit.Client,
+	svcs *Services,
+	progSock string,
+	defaultPlatform specs.Platform,
+	args []string,
+) (_ *Terminal, rerr error) {
+	if len(args) == 0 {
+		args = DefaultTerminalCmd
+	}
+
+	// build the exec the same way WithExec would so that the terminal's
+	// process sees the exact same container
+	execCtr, err := container.WithExec(ctx, bk, progSock, defaultPlatform, ContainerExecOpts{
+		Args:           args,
+		SkipEntrypoint: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dig, err := execCtr.Digest()
+	if err != nil {
+		return nil, err
+	}
+	id := dig.Encoded()
+
+	// reuse the terminal if it's already been started but not exited, since
+	// every field selected on it will resolve it again
+	if _, found := bk.Terminal(id); found {
+		return newTerminal(id), nil
+	}
+
+	dag, err := defToDAG(execCtr.FS)
+	if err != nil {
+		return nil, err
+	}
+	if dag.GetOp() == nil && len(dag.inputs) == 1 {
+		dag = dag.inputs[0]
+	}
+	execOp, ok := dag.AsExec()
+	if !ok {
+		return nil, fmt.Errorf("expected exec op, got %T", dag.GetOp())
+	}
+
+	detachDeps, _, err := svcs.StartBindings(ctx, bk, container.Services)
+	if err != nil {
+		return nil, fmt.Errorf("start dependent services: %w", err)
+	}
+	defer func() {
+		if rerr != nil {
+			detachDeps()
+		}
+	}()
+
+	allMounts, err := execMounts(ctx, bk, execOp)
+	if err != nil {
+		return nil, err
+	}
+
+	mounts := make([]bkgw.Mount, 0, len(allMounts))
+	for _, m := range allMounts {
+		if m.Dest == buildkit.MetaMountDestPath {
+			// leave out the meta mount so that the shim doesn't wrap the
+			// process and capture its output
+			continue
+		}
+		mounts = append(mounts, m)
+	}
+
+	pbPlatform := pb.PlatformFromSpec(execCtr.Platform)
+
+	gc, err := bk.NewContainer(ctx, bkgw.NewContainerRequest{
+		Mounts:   mounts,
+		Platform: &pbPlatform,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("new container: %w", err)
+	}
+
+	bk.NewTerminal(id, gc, bkgw.StartRequest{
+		Args:         execOp.Meta.Args,
+		Env:          append(execOp.Meta.Env, proxyEnvList(execOp.Meta.ProxyEnv)...),
+		Cwd:          execOp.Meta.Cwd,
+		User:         execOp.Meta.User,
+		SecretEnv:    execOp.Secretenv,
+		SecurityMode: execOp.Security,
+	}, detachDeps)
+
+	return newTerminal(id), nil
+}
+
+func newTerminal(id string) *Terminal {
+	return &Terminal{
+		ID:                TerminalID(id),
+		WebsocketEndpoint: "ws://dagger/terminals/" + id,
+	}
+}
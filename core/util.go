@@ -12,6 +12,7 @@ import (
 	"github.com/dagger/dagger/core/reffs"
 	"github.com/dagger/dagger/engine/buildkit"
 	"github.com/moby/buildkit/client/llb"
+	dockerspec "github.com/moby/buildkit/exporter/containerimage/image"
 	"github.com/moby/buildkit/frontend/dockerfile/shell"
 	"github.com/moby/buildkit/solver/pb"
 	"github.com/opencontainers/go-digest"
@@ -255,7 +256,7 @@ func mergeMap[T any](dst, src map[string]T) map[string]T {
 // Only the configurations that have corresponding `WithXXX` and `WithoutXXX`
 // methods in `Container` are added or updated (i.e., `Env`, `Labels` and
 // `ExposedPorts`). Everything else is replaced.
-func mergeImageConfig(dst, src specs.ImageConfig) specs.ImageConfig {
+func mergeImageConfig(dst, src dockerspec.ImageConfig) dockerspec.ImageConfig {
 	res := src
 
 	res.Env = mergeEnv(dst.Env, src.Env)
@@ -19,15 +19,10 @@ func (p *authProxy) Register(srv *grpc.Server) {
 
 // TODO: reduce boilerplate w/ generics?
 
+// Credentials doesn't fall back to the main client like the other methods: the
+// AuthProvider asks it itself, see RegistryAuthProvider.SetHostAuth.
 func (p *authProxy) Credentials(ctx context.Context, req *bkauth.CredentialsRequest) (*bkauth.CredentialsResponse, error) {
-	resp, err := p.c.AuthProvider.Credentials(ctx, req)
-	if err == nil {
-		return resp, nil
-	}
-	if status.Code(err) != codes.NotFound {
-		return nil, err
-	}
-	return bkauth.NewAuthClient(p.c.MainClientCaller.Conn()).Credentials(ctx, req)
+	return p.c.AuthProvider.Credentials(ctx, req)
 }
 
 func (p *authProxy) FetchToken(ctx context.Context, req *bkauth.FetchTokenRequest) (*bkauth.FetchTokenResponse, error) {
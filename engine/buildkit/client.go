@@ -7,7 +7,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/containerd/containerd/remotes/docker"
 	"github.com/dagger/dagger/auth"
+	"github.com/dagger/dagger/engine"
+	"github.com/docker/distribution/reference"
 	bkcache "github.com/moby/buildkit/cache"
 	bkcacheconfig "github.com/moby/buildkit/cache/config"
 	"github.com/moby/buildkit/cache/remotecache"
@@ -27,6 +30,7 @@ import (
 	"github.com/moby/buildkit/util/entitlements"
 	bkworker "github.com/moby/buildkit/worker"
 	"github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc/metadata"
 )
@@ -45,6 +49,16 @@ type Opts struct {
 	AuthProvider          *auth.RegistryAuthProvider
 	PrivilegedExecEnabled bool
 	UpstreamCacheImports  []bkgw.CacheOptionsEntry
+	// HostNetworkEnabled allows Dockerfile builds to use the engine's network.
+	HostNetworkEnabled bool
+	// ImageLock, if set, pins the digests that image references resolve to.
+	ImageLock *engine.ImageLock
+	// PulledImages remembers the images the engine has resolved, for pull
+	// policies that avoid contacting the registry.
+	PulledImages *PulledImages
+	// RegistryHosts configures access to registries, for pushes and pulls the
+	// engine makes outside of buildkit's own image exporter and source.
+	RegistryHosts docker.RegistryHosts
 	// MainClientCaller is the caller who initialized the server associated with this
 	// client. It is special in that when it shuts down, the client will be closed and
 	// that registry auth and sockets are currently only ever sourced from this caller,
@@ -69,6 +83,10 @@ type Client struct {
 	containers   map[bkgw.Container]struct{}
 	containersMu sync.Mutex
 
+	terminals     map[string]*Terminal
+	debugWatchers []*debugTerminalWatcher
+	terminalsMu   sync.Mutex
+
 	closeCtx context.Context
 	cancel   context.CancelFunc
 	closeMu  sync.RWMutex
@@ -81,6 +99,7 @@ func NewClient(ctx context.Context, opts Opts) (*Client, error) {
 		clientIDToSecretToken: make(map[string]string),
 		refs:                  make(map[*ref]struct{}),
 		containers:            make(map[bkgw.Container]struct{}),
+		terminals:             make(map[string]*Terminal),
 		closeCtx:              closeCtx,
 		cancel:                cancel,
 	}
@@ -102,6 +121,9 @@ func NewClient(ctx context.Context, opts Opts) (*Client, error) {
 	if opts.PrivilegedExecEnabled {
 		entitlementSet[entitlements.EntitlementSecurityInsecure] = struct{}{}
 	}
+	if opts.HostNetworkEnabled {
+		entitlementSet[entitlements.EntitlementNetworkHost] = struct{}{}
+	}
 	client.job.SetValue(entitlementsJobKey, entitlementSet)
 
 	client.llbBridge = client.LLBSolver.Bridge(client.job)
@@ -192,7 +214,7 @@ func (c *Client) Solve(ctx context.Context, req bkgw.SolveRequest) (_ *Result, r
 
 	llbRes, err := c.llbBridge.Solve(ctx, req, c.ID())
 	if err != nil {
-		return nil, wrapError(ctx, err, c.ID())
+		return nil, wrapError(ctx, err, c)
 	}
 	res, err := solverresult.ConvertResult(llbRes, func(rp bksolver.ResultProxy) (*ref, error) {
 		return newRef(rp, c), nil
@@ -222,7 +244,103 @@ func (c *Client) ResolveImageConfig(ctx context.Context, ref string, opt llb.Res
 	}
 	defer cancel()
 	ctx = withOutgoingContext(ctx)
-	return c.llbBridge.ResolveImageConfig(ctx, ref, opt)
+
+	var resolvedRef string
+	var dgst digest.Digest
+	var cfg []byte
+	if c.ImageLock != nil {
+		resolvedRef, dgst, cfg, err = c.resolveLockedImageConfig(ctx, ref, opt)
+	} else {
+		resolvedRef, dgst, cfg, err = c.llbBridge.ResolveImageConfig(ctx, ref, opt)
+	}
+	if err != nil {
+		return "", "", nil, err
+	}
+	if c.PulledImages != nil && opt.Platform != nil {
+		c.PulledImages.record(ref, *opt.Platform, dgst)
+	}
+	return resolvedRef, dgst, cfg, nil
+}
+
+// PulledImageConfig returns the digest and config of the image that the
+// reference last resolved to on this engine, if the engine still holds it,
+// without contacting the registry.
+//
+// With an image lock, the image the reference is pinned to is looked for
+// instead, and the reference is pinned to the image otherwise.
+func (c *Client) PulledImageConfig(ctx context.Context, ref string, platform specs.Platform) (digest.Digest, []byte, bool, error) {
+	if c.PulledImages == nil {
+		return "", nil, false, nil
+	}
+
+	dgst, found := c.PulledImages.resolved(ref, platform)
+
+	var lockRef string
+	if c.ImageLock != nil {
+		named, err := reference.ParseNormalizedNamed(ref)
+		if err != nil {
+			return "", nil, false, err
+		}
+		if _, ok := named.(reference.Canonical); !ok {
+			lockRef = reference.TagNameOnly(named).String()
+
+			pinned, pinnedFound, err := c.ImageLock.Pinned(lockRef)
+			if err != nil {
+				return "", nil, false, err
+			}
+			if pinnedFound {
+				dgst, found = pinned, true
+				lockRef = ""
+			}
+		}
+	}
+	if !found {
+		return "", nil, false, nil
+	}
+
+	cfg, found, err := c.PulledImages.config(ctx, dgst, platform)
+	if err != nil || !found {
+		return "", nil, false, err
+	}
+	if lockRef != "" {
+		c.ImageLock.Record(lockRef, dgst)
+	}
+	return dgst, cfg, true, nil
+}
+
+// resolveLockedImageConfig resolves the image reference to the digest it's
+// pinned to in the image lock, if any, recording the digest it resolves to
+// otherwise.
+func (c *Client) resolveLockedImageConfig(ctx context.Context, ref string, opt llb.ResolveImageConfigOpt) (string, digest.Digest, []byte, error) {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if _, ok := named.(reference.Canonical); ok {
+		// already pinned
+		return c.llbBridge.ResolveImageConfig(ctx, ref, opt)
+	}
+
+	lockRef := reference.TagNameOnly(named).String()
+
+	pinned, found, err := c.ImageLock.Pinned(lockRef)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if found {
+		digested, err := reference.WithDigest(named, pinned)
+		if err != nil {
+			return "", "", nil, err
+		}
+		return c.llbBridge.ResolveImageConfig(ctx, digested.String(), opt)
+	}
+
+	resolvedRef, dgst, cfg, err := c.llbBridge.ResolveImageConfig(ctx, ref, opt)
+	if err != nil {
+		return "", "", nil, err
+	}
+	c.ImageLock.Record(lockRef, dgst)
+	return resolvedRef, dgst, cfg, nil
 }
 
 func (c *Client) NewContainer(ctx context.Context, req bkgw.NewContainerRequest) (bkgw.Container, error) {
@@ -4,29 +4,79 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"path"
 	"strings"
 
+	"github.com/containerd/containerd/images"
 	"github.com/containerd/containerd/platforms"
+	"github.com/docker/distribution/reference"
 	bkcache "github.com/moby/buildkit/cache"
 	bkclient "github.com/moby/buildkit/client"
 	"github.com/moby/buildkit/exporter/containerimage/exptypes"
+	dockerspec "github.com/moby/buildkit/exporter/containerimage/image"
 	bkgw "github.com/moby/buildkit/frontend/gateway/client"
+	gwpb "github.com/moby/buildkit/frontend/gateway/pb"
+	"github.com/moby/buildkit/session/filesync"
 	bksolverpb "github.com/moby/buildkit/solver/pb"
 	solverresult "github.com/moby/buildkit/solver/result"
+	"github.com/moby/buildkit/util/contentutil"
 	specs "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
 type ContainerExport struct {
 	Definition *bksolverpb.Definition
-	Config     specs.ImageConfig
+	Config     dockerspec.ImageConfig
+
+	// History of the image's layers, which the exporter extends with entries
+	// for any layers it adds.
+	History []specs.History
+
+	// Attestations to attach to the image.
+	Attestations []ContainerAttestation
+}
+
+// ContainerAttestation is an in-toto attestation to attach to an exported
+// image, with the image as its subject.
+type ContainerAttestation struct {
+	// PredicateType is the in-toto predicate type, e.g.
+	// https://slsa.dev/provenance/v0.2.
+	PredicateType string
+
+	// Content is the predicate itself. If it's not set, the predicate is read
+	// from Path in the result of Definition instead.
+	Content []byte
+
+	Definition *bksolverpb.Definition
+	Path       string
+
+	// Bundle indicates that Definition is a directory of attestations
+	// generated by a scanner, rather than a single predicate.
+	Bundle bool
+
+	// Metadata is passed along to the exporter, e.g. to indicate the reason
+	// for the attestation.
+	Metadata map[string][]byte
+}
+
+// PublishResponse is the outcome of publishing an image to one of the names
+// passed to PublishContainerImages.
+type PublishResponse struct {
+	Name string
+	// Response is the exporter's response, set if the push succeeded.
+	Response map[string]string
+	Err      error
 }
 
-func (c *Client) PublishContainerImage(
+// PublishContainerImages pushes the container image to each of the given
+// names. The image is only solved once, and a failure to push to one name
+// doesn't stop it from being pushed to the others.
+func (c *Client) PublishContainerImages(
 	ctx context.Context,
 	inputByPlatform map[string]ContainerExport,
+	names []string,
 	opts map[string]string, // TODO: make this an actual type, this leaks too much untyped buildkit api
-) (map[string]string, error) {
+) ([]PublishResponse, error) {
 	ctx, cancel, err := c.withClientCloseCancel(ctx)
 	if err != nil {
 		return nil, err
@@ -43,19 +93,35 @@ func (c *Client) PublishContainerImage(
 		return nil, err
 	}
 
-	expInstance, err := exporter.Resolve(ctx, opts)
-	if err != nil {
-		return nil, fmt.Errorf("failed to resolve exporter: %s", err)
-	}
+	resps := make([]PublishResponse, len(names))
+	for i, name := range names {
+		nameOpts := map[string]string{}
+		for k, v := range opts {
+			nameOpts[k] = v
+		}
+		nameOpts[string(exptypes.OptKeyName)] = name
 
-	resp, descRef, err := expInstance.Export(ctx, combinedResult, c.ID())
-	if err != nil {
-		return nil, fmt.Errorf("failed to export: %s", err)
-	}
-	if descRef != nil {
-		descRef.Release()
+		resps[i] = PublishResponse{Name: name}
+
+		expInstance, err := exporter.Resolve(ctx, nameOpts)
+		if err != nil {
+			resps[i].Err = fmt.Errorf("failed to resolve exporter: %s", err)
+			continue
+		}
+
+		// blobs that were already pushed to the registry by a previous name are
+		// skipped, so this only pushes what each registry is missing
+		resp, descRef, err := expInstance.Export(ctx, combinedResult, c.ID())
+		if err != nil {
+			resps[i].Err = fmt.Errorf("failed to export: %s", err)
+			continue
+		}
+		if descRef != nil {
+			descRef.Release()
+		}
+		resps[i].Response = resp
 	}
-	return resp, nil
+	return resps, nil
 }
 
 func (c *Client) ExportContainerImage(
@@ -111,6 +177,91 @@ func (c *Client) ExportContainerImage(
 	return resp, nil
 }
 
+// ExportContainerImageTarball is like ExportContainerImage, but always
+// exports an OCI tarball and writes it to w rather than to the caller's host.
+func (c *Client) ExportContainerImageTarball(
+	ctx context.Context,
+	inputByPlatform map[string]ContainerExport,
+	w io.Writer,
+	opts map[string]string,
+) (map[string]string, error) {
+	ctx, cancel, err := c.withClientCloseCancel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	combinedResult, err := c.getContainerResult(ctx, inputByPlatform)
+	if err != nil {
+		return nil, err
+	}
+
+	exporter, err := c.Worker.Exporter(bkclient.ExporterOCI, c.SessionManager)
+	if err != nil {
+		return nil, err
+	}
+
+	expInstance, err := exporter.Resolve(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve exporter: %s", err)
+	}
+
+	sess, err := c.newEphemeralSession(ctx, filesync.NewFSSyncTarget(func(map[string]string) (io.WriteCloser, error) {
+		return nopWriteCloser{w}, nil
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, descRef, err := expInstance.Export(ctx, combinedResult, sess.ID())
+	if err != nil {
+		return nil, fmt.Errorf("failed to export: %s", err)
+	}
+	if descRef != nil {
+		descRef.Release()
+	}
+	return resp, nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// ImageManifest fetches the manifest of the image for the given platform
+// from its registry.
+func (c *Client) ImageManifest(ctx context.Context, ref string, platform specs.Platform) (*specs.Manifest, error) {
+	ctx, cancel, err := c.withClientCloseCancel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	r := c.resolver(named, "pull")
+	name, desc, err := r.Resolve(ctx, named.String())
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", ref, err)
+	}
+	fetcher, err := r.Fetcher(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	mfst, err := images.Manifest(ctx, contentutil.FromFetcher(fetcher), desc, platforms.Only(platform))
+	if err != nil {
+		return nil, fmt.Errorf("fetch manifest of %s: %w", ref, err)
+	}
+	return &mfst, nil
+}
+
 func (c *Client) getContainerResult(
 	ctx context.Context,
 	inputByPlatform map[string]ContainerExport,
@@ -141,12 +292,15 @@ func (c *Client) getContainerResult(
 		if err != nil {
 			return nil, err
 		}
-		cfgBytes, err := json.Marshal(specs.Image{
-			Platform: specs.Platform{
-				Architecture: platform.Architecture,
-				OS:           platform.OS,
-				OSVersion:    platform.OSVersion,
-				OSFeatures:   platform.OSFeatures,
+		cfgBytes, err := json.Marshal(dockerspec.Image{
+			Image: specs.Image{
+				Platform: specs.Platform{
+					Architecture: platform.Architecture,
+					OS:           platform.OS,
+					OSVersion:    platform.OSVersion,
+					OSFeatures:   platform.OSFeatures,
+				},
+				History: input.History,
 			},
 			Config: input.Config,
 		})
@@ -157,6 +311,13 @@ func (c *Client) getContainerResult(
 		if len(inputByPlatform) == 1 {
 			combinedResult.AddMeta(exptypes.ExporterImageConfigKey, cfgBytes)
 			combinedResult.SetRef(ref)
+
+			// the exporter keys a single platform by the one in its config
+			ps, err := exptypes.ParsePlatforms(combinedResult.Metadata)
+			if err != nil {
+				return nil, err
+			}
+			platformString = ps.Platforms[0].ID
 		} else {
 			expPlatforms.Platforms[len(combinedResult.Refs)] = exptypes.Platform{
 				ID:       platformString,
@@ -164,6 +325,14 @@ func (c *Client) getContainerResult(
 			}
 			combinedResult.AddRef(platformString, ref)
 		}
+
+		for _, att := range input.Attestations {
+			bkAtt, err := c.containerAttestation(ctx, att)
+			if err != nil {
+				return nil, fmt.Errorf("failed to attach %s attestation: %w", att.PredicateType, err)
+			}
+			combinedResult.AddAttestation(platformString, bkAtt)
+		}
 	}
 
 	if len(combinedResult.Refs) > 1 {
@@ -176,3 +345,45 @@ func (c *Client) getContainerResult(
 
 	return combinedResult, nil
 }
+
+func (c *Client) containerAttestation(ctx context.Context, att ContainerAttestation) (solverresult.Attestation[bkcache.ImmutableRef], error) {
+	bkAtt := solverresult.Attestation[bkcache.ImmutableRef]{
+		Kind:     gwpb.AttestationKindInToto,
+		Metadata: att.Metadata,
+		InToto: solverresult.InTotoAttestation{
+			PredicateType: att.PredicateType,
+		},
+	}
+
+	if att.Content != nil {
+		content := att.Content
+		bkAtt.ContentFunc = func() ([]byte, error) {
+			return content, nil
+		}
+		return bkAtt, nil
+	}
+
+	res, err := c.Solve(ctx, bkgw.SolveRequest{
+		Definition: att.Definition,
+		Evaluate:   true,
+	})
+	if err != nil {
+		return bkAtt, err
+	}
+	cacheRes, err := ConvertToWorkerCacheResult(ctx, res)
+	if err != nil {
+		return bkAtt, fmt.Errorf("failed to convert result: %s", err)
+	}
+	ref, err := cacheRes.SingleRef()
+	if err != nil {
+		return bkAtt, err
+	}
+
+	bkAtt.Ref = ref
+	bkAtt.Path = att.Path
+	if att.Bundle {
+		bkAtt.Kind = gwpb.AttestationKindBundle
+	}
+
+	return bkAtt, nil
+}
@@ -1,5 +1,11 @@
 package buildkit
 
+import (
+	"fmt"
+
+	"github.com/opencontainers/go-digest"
+)
+
 // ExecError is an error that occurred while executing an `Op_Exec`.
 type ExecError struct {
 	original error
@@ -7,9 +13,23 @@ type ExecError struct {
 	ExitCode int
 	Stdout   string
 	Stderr   string
+
+	// LimitExceeded is the resource limit that the exec was killed for
+	// exceeding, e.g. "timeout" or "memory", if any.
+	LimitExceeded string
+
+	// Vertex is the digest of the exec's op.
+	Vertex digest.Digest
+
+	// Output is everything the exec wrote to stdout and stderr, in the order
+	// it was written.
+	Output []ExecOutputChunk
 }
 
 func (e *ExecError) Error() string {
+	if e.LimitExceeded != "" {
+		return fmt.Sprintf("exceeded %s limit: %s", e.LimitExceeded, e.original)
+	}
 	return e.original.Error()
 }
 
@@ -19,10 +39,11 @@ func (e *ExecError) Unwrap() error {
 
 func (e *ExecError) Extensions() map[string]interface{} {
 	return map[string]interface{}{
-		"_type":    "EXEC_ERROR",
-		"cmd":      e.Cmd,
-		"exitCode": e.ExitCode,
-		"stdout":   e.Stdout,
-		"stderr":   e.Stderr,
+		"_type":         "EXEC_ERROR",
+		"cmd":           e.Cmd,
+		"exitCode":      e.ExitCode,
+		"stdout":        e.Stdout,
+		"stderr":        e.Stderr,
+		"limitExceeded": e.LimitExceeded,
 	}
 }
@@ -0,0 +1,88 @@
+package buildkit
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/vito/progrock"
+)
+
+// ExecOutputMetaFile is the meta file that an exec's output is recorded to,
+// interleaved as it was written.
+const ExecOutputMetaFile = "output"
+
+// execOutputHeaderSize is the size of the header before each chunk: the
+// stream followed by the length of the chunk as a big-endian uint32.
+const execOutputHeaderSize = 5
+
+// ExecOutputChunk is a chunk of what an exec wrote to one of its streams.
+type ExecOutputChunk struct {
+	Stream progrock.LogStream
+	Data   []byte
+}
+
+// ExecOutputWriter records what an exec writes to its stdout and stderr in
+// the order it's written, so that the two can be replayed interleaved as they
+// were.
+type ExecOutputWriter struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+func NewExecOutputWriter(w io.Writer) *ExecOutputWriter {
+	return &ExecOutputWriter{w: w}
+}
+
+// Stream returns a writer that records what's written to it as output of
+// the given stream.
+func (w *ExecOutputWriter) Stream(stream progrock.LogStream) io.Writer {
+	return execOutputStream{w: w, stream: stream}
+}
+
+type execOutputStream struct {
+	w      *ExecOutputWriter
+	stream progrock.LogStream
+}
+
+func (s execOutputStream) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	chunk := make([]byte, execOutputHeaderSize+len(p))
+	chunk[0] = byte(s.stream)
+	binary.BigEndian.PutUint32(chunk[1:execOutputHeaderSize], uint32(len(p)))
+	copy(chunk[execOutputHeaderSize:], p)
+
+	s.w.mu.Lock()
+	defer s.w.mu.Unlock()
+	if _, err := s.w.w.Write(chunk); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// ParseExecOutput parses output recorded by an ExecOutputWriter into its
+// chunks, in the order they were written.
+func ParseExecOutput(data []byte) ([]ExecOutputChunk, error) {
+	var chunks []ExecOutputChunk
+	for len(data) > 0 {
+		if len(data) < execOutputHeaderSize {
+			return nil, errors.New("truncated exec output chunk header")
+		}
+		stream := progrock.LogStream(data[0])
+		size := int(binary.BigEndian.Uint32(data[1:execOutputHeaderSize]))
+		data = data[execOutputHeaderSize:]
+		if len(data) < size {
+			return nil, errors.New("truncated exec output chunk")
+		}
+		chunks = append(chunks, ExecOutputChunk{
+			Stream: stream,
+			Data:   data[:size],
+		})
+		data = data[size:]
+	}
+	return chunks, nil
+}
@@ -0,0 +1,37 @@
+package buildkit
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vito/progrock"
+)
+
+func TestExecOutput(t *testing.T) {
+	t.Parallel()
+
+	buf := new(bytes.Buffer)
+	output := NewExecOutputWriter(buf)
+	stdout := output.Stream(progrock.LogStream_STDOUT)
+	stderr := output.Stream(progrock.LogStream_STDERR)
+
+	fmt.Fprintln(stdout, "hello")
+	fmt.Fprintln(stderr, "goodbye")
+	fmt.Fprint(stdout, "")
+	fmt.Fprintln(stdout, "done")
+
+	chunks, err := ParseExecOutput(buf.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, []ExecOutputChunk{
+		{Stream: progrock.LogStream_STDOUT, Data: []byte("hello\n")},
+		{Stream: progrock.LogStream_STDERR, Data: []byte("goodbye\n")},
+		{Stream: progrock.LogStream_STDOUT, Data: []byte("done\n")},
+	}, chunks)
+
+	_, err = ParseExecOutput(buf.Bytes()[:buf.Len()-1])
+	require.Error(t, err)
+	_, err = ParseExecOutput(buf.Bytes()[:3])
+	require.Error(t, err)
+}
@@ -398,12 +398,20 @@ func (c *Client) newFileSendServerProxySession(ctx context.Context, destPath str
 	if err != nil {
 		return nil, fmt.Errorf("failed to get requester client metadata: %s", err)
 	}
+	proxy := &fileSendServerProxy{c: c, destClientID: clientMetadata.ClientID, destPath: destPath}
+	return c.newEphemeralSession(ctx, proxy)
+}
+
+// newEphemeralSession starts a session that serves the given attachables until
+// ctx is canceled, for exporters to send their output to.
+func (c *Client) newEphemeralSession(ctx context.Context, attachables ...bksession.Attachable) (*bksession.Session, error) {
 	sess, err := bksession.NewSession(ctx, identity.NewID(), "")
 	if err != nil {
 		return nil, err
 	}
-	proxy := &fileSendServerProxy{c: c, destClientID: clientMetadata.ClientID, destPath: destPath}
-	sess.Allow(proxy)
+	for _, a := range attachables {
+		sess.Allow(a)
+	}
 
 	clientConn, serverConn := net.Pipe()
 	dialer := func(ctx context.Context, proto string, meta map[string][]string) (net.Conn, error) { // nolint: unparam
@@ -0,0 +1,90 @@
+package buildkit
+
+import (
+	"context"
+	"sync"
+
+	"github.com/containerd/containerd/content"
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/platforms"
+	"github.com/moby/buildkit/util/imageutil"
+	"github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// PulledImages remembers what the image references resolved on the engine
+// pointed to, so they can be used again without contacting the registry.
+//
+// An image only counts as present while the engine's content store still
+// holds it; what a reference resolved to is just where to look.
+type PulledImages struct {
+	store content.Provider
+
+	mu      sync.Mutex
+	digests map[string]digest.Digest
+}
+
+// NewPulledImages returns a PulledImages that looks for images in the given
+// content store. It's shared by every session on the engine.
+func NewPulledImages(store content.Provider) *PulledImages {
+	return &PulledImages{
+		store:   store,
+		digests: make(map[string]digest.Digest),
+	}
+}
+
+// resolved returns the digest the image reference last resolved to, if any.
+func (p *PulledImages) resolved(ref string, platform specs.Platform) (digest.Digest, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	dgst, found := p.digests[pulledImageKey(ref, platform)]
+	return dgst, found
+}
+
+func (p *PulledImages) record(ref string, platform specs.Platform, dgst digest.Digest) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.digests[pulledImageKey(ref, platform)] = dgst
+}
+
+// config returns the config of the image with the given digest for the
+// platform, or false if the content store doesn't hold it.
+func (p *PulledImages) config(ctx context.Context, dgst digest.Digest, platform specs.Platform) ([]byte, bool, error) {
+	desc := specs.Descriptor{Digest: dgst}
+
+	ra, err := p.store.ReaderAt(ctx, desc)
+	if err != nil {
+		if cerrdefs.IsNotFound(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	desc.Size = ra.Size()
+	desc.MediaType, err = imageutil.DetectManifestMediaType(ra)
+	ra.Close()
+	if err != nil {
+		return nil, false, err
+	}
+
+	configDesc, err := images.Config(ctx, p.store, desc, platforms.Only(platform))
+	if err != nil {
+		if cerrdefs.IsNotFound(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	cfg, err := content.ReadBlob(ctx, p.store, configDesc)
+	if err != nil {
+		if cerrdefs.IsNotFound(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return cfg, true, nil
+}
+
+func pulledImageKey(ref string, platform specs.Platform) string {
+	return ref + "@" + platforms.Format(platform)
+}
@@ -0,0 +1,156 @@
+package buildkit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/content/local"
+	"github.com/dagger/dagger/engine"
+	"github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPulledImageConfig(t *testing.T) {
+	t.Parallel()
+
+	const ref = "docker.io/library/alpine:3.18"
+	platform := specs.Platform{OS: "linux", Architecture: "amd64"}
+	ctx := context.Background()
+
+	store, err := local.NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	writeBlob := func(mediaType string, v any) specs.Descriptor {
+		dt, err := json.Marshal(v)
+		require.NoError(t, err)
+		desc := specs.Descriptor{
+			MediaType: mediaType,
+			Digest:    digest.FromBytes(dt),
+			Size:      int64(len(dt)),
+		}
+		require.NoError(t, content.WriteBlob(ctx, store, desc.Digest.String(), bytes.NewReader(dt), desc))
+		return desc
+	}
+
+	writeImage := func(name string) (digest.Digest, []byte) {
+		cfg := specs.Image{Platform: platform, Author: name}
+		cfgDesc := writeBlob(specs.MediaTypeImageConfig, cfg)
+		mfstDesc := writeBlob(specs.MediaTypeImageManifest, specs.Manifest{
+			MediaType: specs.MediaTypeImageManifest,
+			Config:    cfgDesc,
+		})
+		cfgBytes, err := json.Marshal(cfg)
+		require.NoError(t, err)
+		return mfstDesc.Digest, cfgBytes
+	}
+
+	pulledDigest, pulledConfig := writeImage("pulled")
+	lockedDigest, lockedConfig := writeImage("locked")
+	missingDigest := digest.FromString("missing")
+
+	newClient := func(pulled digest.Digest, mode engine.ImageLockMode, locked map[string]digest.Digest) *Client {
+		images := NewPulledImages(store)
+		if pulled != "" {
+			images.record(ref, platform, pulled)
+		}
+
+		var lock *engine.ImageLock
+		if mode != "" {
+			lock = &engine.ImageLock{Mode: mode, Images: locked}
+		}
+		return &Client{Opts: Opts{PulledImages: images, ImageLock: lock}}
+	}
+
+	t.Run("pulled", func(t *testing.T) {
+		t.Parallel()
+
+		dgst, cfg, found, err := newClient(pulledDigest, "", nil).PulledImageConfig(ctx, ref, platform)
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, pulledDigest, dgst)
+		require.Equal(t, pulledConfig, cfg)
+	})
+
+	t.Run("never pulled", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, found, err := newClient("", "", nil).PulledImageConfig(ctx, ref, platform)
+		require.NoError(t, err)
+		require.False(t, found)
+	})
+
+	t.Run("no longer in the content store", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, found, err := newClient(missingDigest, "", nil).PulledImageConfig(ctx, ref, platform)
+		require.NoError(t, err)
+		require.False(t, found)
+	})
+
+	t.Run("for another platform", func(t *testing.T) {
+		t.Parallel()
+
+		c := newClient("", "", nil)
+		c.PulledImages.record(ref, specs.Platform{OS: "linux", Architecture: "arm64"}, pulledDigest)
+		_, _, found, err := c.PulledImageConfig(ctx, ref, platform)
+		require.NoError(t, err)
+		require.False(t, found)
+	})
+
+	t.Run("locked to the pulled image", func(t *testing.T) {
+		t.Parallel()
+
+		c := newClient(pulledDigest, engine.ImageLockModeStrict, map[string]digest.Digest{ref: pulledDigest})
+		dgst, _, found, err := c.PulledImageConfig(ctx, ref, platform)
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, pulledDigest, dgst)
+	})
+
+	t.Run("locked to another image in the content store", func(t *testing.T) {
+		t.Parallel()
+
+		c := newClient(pulledDigest, engine.ImageLockModeRecord, map[string]digest.Digest{ref: lockedDigest})
+		dgst, cfg, found, err := c.PulledImageConfig(ctx, ref, platform)
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, lockedDigest, dgst)
+		require.Equal(t, lockedConfig, cfg)
+	})
+
+	t.Run("locked to an image not in the content store", func(t *testing.T) {
+		t.Parallel()
+
+		c := newClient(pulledDigest, engine.ImageLockModeRecord, map[string]digest.Digest{ref: missingDigest})
+		_, _, found, err := c.PulledImageConfig(ctx, ref, platform)
+		require.NoError(t, err)
+		require.False(t, found)
+	})
+
+	t.Run("not locked", func(t *testing.T) {
+		t.Parallel()
+
+		c := newClient(pulledDigest, engine.ImageLockModeRecord, map[string]digest.Digest{})
+		dgst, _, found, err := c.PulledImageConfig(ctx, ref, platform)
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, pulledDigest, dgst)
+
+		pinned, pinnedFound, err := c.ImageLock.Pinned(ref)
+		require.NoError(t, err)
+		require.True(t, pinnedFound)
+		require.Equal(t, pulledDigest, pinned)
+	})
+
+	t.Run("not locked in strict mode", func(t *testing.T) {
+		t.Parallel()
+
+		c := newClient(pulledDigest, engine.ImageLockModeStrict, map[string]digest.Digest{})
+		_, _, _, err := c.PulledImageConfig(ctx, ref, platform)
+		require.ErrorContains(t, err, "not in the lockfile")
+	})
+}
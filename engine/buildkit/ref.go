@@ -21,6 +21,7 @@ import (
 	solverresult "github.com/moby/buildkit/solver/result"
 	"github.com/moby/buildkit/util/bklog"
 	bkworker "github.com/moby/buildkit/worker"
+	"github.com/opencontainers/go-digest"
 	fstypes "github.com/tonistiigi/fsutil/types"
 )
 
@@ -155,7 +156,7 @@ func (r *ref) Result(ctx context.Context) (bksolver.CachedResult, error) {
 	ctx = withOutgoingContext(ctx)
 	res, err := r.resultProxy.Result(ctx)
 	if err != nil {
-		return nil, wrapError(ctx, err, r.c.ID())
+		return nil, wrapError(ctx, err, r.c)
 	}
 	return res, nil
 }
@@ -174,7 +175,7 @@ func ConvertToWorkerCacheResult(ctx context.Context, res *solverresult.Result[*r
 	})
 }
 
-func wrapError(ctx context.Context, baseErr error, sessionID string) error {
+func wrapError(ctx context.Context, baseErr error, c *Client) error {
 	var slowCacheErr *bksolver.SlowCacheError
 	if errors.As(baseErr, &slowCacheErr) {
 		if slowCacheErr.Result != nil {
@@ -233,7 +234,7 @@ func wrapError(ctx context.Context, baseErr error, sessionID string) error {
 	if !ok {
 		return errors.Join(baseErr, fmt.Errorf("invalid ref type: %T", metaMountResult.Sys()))
 	}
-	mntable, err := workerRef.ImmutableRef.Mount(ctx, true, bksession.NewGroup(sessionID))
+	mntable, err := workerRef.ImmutableRef.Mount(ctx, true, bksession.NewGroup(c.ID()))
 	if err != nil {
 		return errors.Join(err, baseErr)
 	}
@@ -259,13 +260,53 @@ func wrapError(ctx context.Context, baseErr error, sessionID string) error {
 		}
 	}
 
-	return &ExecError{
-		original: baseErr,
-		Cmd:      execOp.Exec.Meta.Args,
-		ExitCode: exitCode,
-		Stdout:   strings.TrimSpace(string(stdoutBytes)),
-		Stderr:   strings.TrimSpace(string(stderrBytes)),
+	limitBytes, err := getExecMetaFile(ctx, mntable, "limitExceeded")
+	if err != nil {
+		return errors.Join(err, baseErr)
+	}
+
+	output, err := getExecOutput(ctx, mntable)
+	if err != nil {
+		return errors.Join(err, baseErr)
+	}
+
+	opBytes, err := op.Marshal()
+	if err != nil {
+		return errors.Join(err, baseErr)
+	}
+
+	wrapped := &ExecError{
+		original:      baseErr,
+		Cmd:           execOp.Exec.Meta.Args,
+		ExitCode:      exitCode,
+		Stdout:        strings.TrimSpace(string(stdoutBytes)),
+		Stderr:        strings.TrimSpace(string(stderrBytes)),
+		LimitExceeded: string(limitBytes),
+		Vertex:        digest.FromBytes(opBytes),
+		Output:        output,
+	}
+
+	// give anyone watching a chance to inspect the failed exec before its
+	// mounts are released
+	c.debugExecError(ctx, op, execErr.Mounts, wrapped)
+
+	return wrapped
+}
+
+// getExecOutput reads the exec's interleaved output in full, since unlike the
+// other meta files it can't be parsed once truncated.
+func getExecOutput(ctx context.Context, mntable snapshot.Mountable) ([]ExecOutputChunk, error) {
+	ctx = withOutgoingContext(ctx)
+	filePath := path.Join(MetaSourcePath, ExecOutputMetaFile)
+	if _, err := cacheutil.StatFile(ctx, mntable, filePath); err != nil {
+		bklog.G(ctx).Debugf("getExecOutput: failed to stat file: %v", err)
+		return nil, nil
+	}
+	contents, err := cacheutil.ReadFile(ctx, mntable, cacheutil.ReadRequest{Filename: filePath})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q: %w", filePath, err)
 	}
+	return ParseExecOutput(contents)
 }
 
 func getExecMetaFile(ctx context.Context, mntable snapshot.Mountable, fileName string) ([]byte, error) {
@@ -0,0 +1,220 @@
+package buildkit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/docker/distribution/reference"
+	bksession "github.com/moby/buildkit/session"
+	"github.com/moby/buildkit/util/contentutil"
+	"github.com/moby/buildkit/util/push"
+	"github.com/moby/buildkit/util/resolver"
+	"github.com/opencontainers/go-digest"
+	specsgo "github.com/opencontainers/image-spec/specs-go"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Media type and annotation that cosign uses for image signatures, which are
+// stored as layers of a manifest tagged after the signed image's digest.
+const (
+	SignatureMediaType  = "application/vnd.dev.cosign.simplesigning.v1+json"
+	SignatureAnnotation = "dev.cosignproject.cosign/signature"
+
+	// maximum size of a signature manifest or payload we'll fetch
+	maxSignatureBlobSize = 4 << 20
+)
+
+// ImageSignature is a signed payload stored alongside an image.
+type ImageSignature struct {
+	Payload []byte
+	// Signature is the base64-encoded signature of the payload.
+	Signature string
+}
+
+// SignatureRef returns the reference that the signatures of the image with
+// the given digest are stored under, using cosign's convention of tagging
+// them after the digest.
+func SignatureRef(name reference.Named, dgst digest.Digest) (reference.NamedTagged, error) {
+	return reference.WithTag(reference.TrimNamed(name), fmt.Sprintf("%s-%s.sig", dgst.Algorithm(), dgst.Encoded()))
+}
+
+// PushImageSignature pushes a signature for the image with the given digest
+// to its repository. Any signatures already stored for the image are kept.
+func (c *Client) PushImageSignature(ctx context.Context, name reference.Named, dgst digest.Digest, sig ImageSignature) error {
+	ctx, cancel, err := c.withClientCloseCancel(ctx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	sigRef, err := SignatureRef(name, dgst)
+	if err != nil {
+		return err
+	}
+
+	buf := contentutil.NewBuffer()
+
+	existing, err := c.fetchSignatureManifest(ctx, sigRef)
+	if err != nil {
+		return err
+	}
+
+	var layers []specs.Descriptor
+	var diffIDs []digest.Digest
+	if existing != nil {
+		// the registry already has these blobs, so the pusher skips them
+		for _, layer := range existing.Layers {
+			layers = append(layers, layer)
+			diffIDs = append(diffIDs, layer.Digest)
+		}
+	}
+
+	layer, err := writeBlob(ctx, buf, SignatureMediaType, sig.Payload)
+	if err != nil {
+		return err
+	}
+	layer.Annotations = map[string]string{
+		SignatureAnnotation: sig.Signature,
+	}
+	layers = append(layers, layer)
+	diffIDs = append(diffIDs, layer.Digest)
+
+	cfgBytes, err := json.Marshal(specs.Image{
+		RootFS: specs.RootFS{
+			Type:    "layers",
+			DiffIDs: diffIDs,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	cfg, err := writeBlob(ctx, buf, specs.MediaTypeImageConfig, cfgBytes)
+	if err != nil {
+		return err
+	}
+
+	mfstBytes, err := json.Marshal(specs.Manifest{
+		Versioned: specsgo.Versioned{SchemaVersion: 2},
+		MediaType: specs.MediaTypeImageManifest,
+		Config:    cfg,
+		Layers:    layers,
+	})
+	if err != nil {
+		return err
+	}
+	mfst, err := writeBlob(ctx, buf, specs.MediaTypeImageManifest, mfstBytes)
+	if err != nil {
+		return err
+	}
+
+	return push.Push(ctx, c.SessionManager, c.ID(), buf, buf, mfst.Digest, sigRef.String(), false, c.RegistryHosts, false, nil)
+}
+
+// ImageSignatures returns the signatures stored for the image with the given
+// digest in its repository.
+func (c *Client) ImageSignatures(ctx context.Context, name reference.Named, dgst digest.Digest) ([]ImageSignature, error) {
+	ctx, cancel, err := c.withClientCloseCancel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	sigRef, err := SignatureRef(name, dgst)
+	if err != nil {
+		return nil, err
+	}
+
+	mfst, err := c.fetchSignatureManifest(ctx, sigRef)
+	if err != nil {
+		return nil, err
+	}
+	if mfst == nil {
+		return nil, nil
+	}
+
+	var sigs []ImageSignature
+	for _, layer := range mfst.Layers {
+		if layer.MediaType != SignatureMediaType {
+			continue
+		}
+		payload, err := c.fetchBlob(ctx, sigRef, layer)
+		if err != nil {
+			return nil, err
+		}
+		sigs = append(sigs, ImageSignature{
+			Payload:   payload,
+			Signature: layer.Annotations[SignatureAnnotation],
+		})
+	}
+	return sigs, nil
+}
+
+// fetchSignatureManifest returns the manifest stored at the given signature
+// ref, or nil if there isn't one.
+func (c *Client) fetchSignatureManifest(ctx context.Context, sigRef reference.Named) (*specs.Manifest, error) {
+	_, desc, err := c.resolver(sigRef, "pull").Resolve(ctx, sigRef.String())
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("resolve %s: %w", sigRef, err)
+	}
+
+	dt, err := c.fetchBlob(ctx, sigRef, desc)
+	if err != nil {
+		return nil, err
+	}
+
+	var mfst specs.Manifest
+	if err := json.Unmarshal(dt, &mfst); err != nil {
+		return nil, fmt.Errorf("parse signature manifest: %w", err)
+	}
+	return &mfst, nil
+}
+
+func (c *Client) fetchBlob(ctx context.Context, ref reference.Named, desc specs.Descriptor) ([]byte, error) {
+	if desc.Size > maxSignatureBlobSize {
+		return nil, fmt.Errorf("blob %s is too large: %d bytes", desc.Digest, desc.Size)
+	}
+
+	fetcher, err := c.resolver(ref, "pull").Fetcher(ctx, ref.String())
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", desc.Digest, err)
+	}
+	defer rc.Close()
+
+	dt, err := io.ReadAll(io.LimitReader(rc, maxSignatureBlobSize))
+	if err != nil {
+		return nil, err
+	}
+	if desc.Digest != "" && digest.FromBytes(dt) != desc.Digest {
+		return nil, fmt.Errorf("fetch %s: digest mismatch", desc.Digest)
+	}
+	return dt, nil
+}
+
+func (c *Client) resolver(ref reference.Named, scope string) *resolver.Resolver {
+	return resolver.DefaultPool.GetResolver(c.RegistryHosts, ref.String(), scope, c.SessionManager, bksession.NewGroup(c.ID()))
+}
+
+func writeBlob(ctx context.Context, buf contentutil.Buffer, mediaType string, dt []byte) (specs.Descriptor, error) {
+	desc := specs.Descriptor{
+		MediaType: mediaType,
+		Digest:    digest.FromBytes(dt),
+		Size:      int64(len(dt)),
+	}
+	if err := content.WriteBlob(ctx, buf, desc.Digest.String(), bytes.NewReader(dt), desc); err != nil {
+		return specs.Descriptor{}, err
+	}
+	return desc, nil
+}
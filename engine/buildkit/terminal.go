@@ -0,0 +1,331 @@
+package buildkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/dagger/dagger/engine"
+	bkgw "github.com/moby/buildkit/frontend/gateway/client"
+	bkcontainer "github.com/moby/buildkit/frontend/gateway/container"
+	gwpb "github.com/moby/buildkit/frontend/gateway/pb"
+	"github.com/moby/buildkit/identity"
+	bksession "github.com/moby/buildkit/session"
+	bksolver "github.com/moby/buildkit/solver"
+	bksolverpb "github.com/moby/buildkit/solver/pb"
+	"github.com/moby/buildkit/util/bklog"
+	bkworker "github.com/moby/buildkit/worker"
+)
+
+// DebugShell is the command run in debug terminals opened on failed execs.
+var DebugShell = []string{"/bin/sh"}
+
+// Terminal is a process in a container that a client can attach to
+// interactively over its session connection.
+type Terminal struct {
+	ID        string
+	Container bkgw.Container
+
+	// Process is started with a TTY each time a client attaches. Its
+	// Stdin, Stdout and Stderr are ignored.
+	Process bkgw.StartRequest
+
+	release  func()
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+// NewTerminal registers a terminal with the given ID running proc in ctr,
+// which must have been created with NewContainer. Clients can attach to it at
+// /terminals/<id>.
+//
+// The container is released when the terminal is closed, after which
+// onRelease is called, if set. If a terminal is already registered with the
+// same ID, ctr is released right away and the existing terminal is returned.
+func (c *Client) NewTerminal(id string, ctr bkgw.Container, proc bkgw.StartRequest, onRelease func()) *Terminal {
+	t := &Terminal{
+		ID:        id,
+		Container: ctr,
+		Process:   proc,
+		done:      make(chan struct{}),
+	}
+	t.release = func() {
+		c.releaseTerminal(t)
+		if onRelease != nil {
+			onRelease()
+		}
+	}
+
+	c.terminalsMu.Lock()
+	existing, found := c.terminals[id]
+	if !found {
+		c.terminals[id] = t
+	}
+	c.terminalsMu.Unlock()
+
+	if found {
+		t.release()
+		return existing
+	}
+
+	return t
+}
+
+// Attach starts the terminal's process with a TTY, streaming its I/O and
+// applying any resize events until it exits. It returns the process's exit
+// code.
+func (t *Terminal) Attach(
+	ctx context.Context,
+	stdin io.ReadCloser,
+	stdout, stderr io.WriteCloser,
+	resize <-chan bkgw.WinSize,
+) (int, error) {
+	req := t.Process
+	req.Tty = true
+	req.Stdin = stdin
+	req.Stdout = stdout
+	req.Stderr = stderr
+
+	proc, err := t.Container.Start(ctx, req)
+	if err != nil {
+		return -1, fmt.Errorf("start terminal process: %w", err)
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case size, ok := <-resize:
+				if !ok {
+					return
+				}
+				if err := proc.Resize(ctx, size); err != nil {
+					bklog.G(ctx).WithError(err).Debug("failed to resize terminal")
+				}
+			}
+		}
+	}()
+
+	err = proc.Wait()
+	if err == nil {
+		return 0, nil
+	}
+
+	var exitErr *gwpb.ExitError
+	if errors.As(err, &exitErr) {
+		return int(exitErr.ExitCode), nil
+	}
+
+	return -1, err
+}
+
+// Close marks the terminal session as finished and releases its container.
+func (t *Terminal) Close() {
+	t.doneOnce.Do(func() {
+		close(t.done)
+		t.release()
+	})
+}
+
+// Terminal returns the terminal registered with the given ID.
+func (c *Client) Terminal(id string) (*Terminal, bool) {
+	c.terminalsMu.Lock()
+	defer c.terminalsMu.Unlock()
+	t, ok := c.terminals[id]
+	return t, ok
+}
+
+func (c *Client) releaseTerminal(t *Terminal) {
+	c.terminalsMu.Lock()
+	if c.terminals[t.ID] == t {
+		delete(c.terminals, t.ID)
+	}
+	c.terminalsMu.Unlock()
+
+	c.containersMu.Lock()
+	if c.containers != nil {
+		delete(c.containers, t.Container)
+	}
+	c.containersMu.Unlock()
+
+	if err := t.Container.Release(context.Background()); err != nil {
+		bklog.G(context.Background()).WithError(err).Error("failed to release terminal container")
+	}
+}
+
+type debugTerminalWatcher struct {
+	ctx context.Context
+	ch  chan engine.DebugTerminal
+}
+
+// WatchDebugTerminals registers the caller as interested in failed execs.
+//
+// While at least one watcher is registered, an exec that fails will not
+// return its error immediately. Instead, a terminal is opened into the state
+// of the failed exec and sent to a watcher, and the exec blocks until the
+// terminal is closed so that nothing is torn down while it's being inspected.
+//
+// The watcher is unregistered when ctx is canceled.
+func (c *Client) WatchDebugTerminals(ctx context.Context) <-chan engine.DebugTerminal {
+	w := &debugTerminalWatcher{
+		ctx: ctx,
+		ch:  make(chan engine.DebugTerminal),
+	}
+
+	c.terminalsMu.Lock()
+	c.debugWatchers = append(c.debugWatchers, w)
+	c.terminalsMu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		c.terminalsMu.Lock()
+		defer c.terminalsMu.Unlock()
+		for i, other := range c.debugWatchers {
+			if other == w {
+				c.debugWatchers = append(c.debugWatchers[:i], c.debugWatchers[i+1:]...)
+				break
+			}
+		}
+	}()
+
+	return w.ch
+}
+
+func (c *Client) debugTerminalWatcher() *debugTerminalWatcher {
+	c.terminalsMu.Lock()
+	defer c.terminalsMu.Unlock()
+	if len(c.debugWatchers) == 0 {
+		return nil
+	}
+	return c.debugWatchers[0]
+}
+
+// debugExecError opens a terminal into the state of a failed exec if anyone
+// is watching for them, blocking until the terminal is closed.
+//
+// The mounts must not be released until this returns.
+func (c *Client) debugExecError(ctx context.Context, op *bksolverpb.Op, mounts []bksolver.Result, execErr *ExecError) {
+	watcher := c.debugTerminalWatcher()
+	if watcher == nil {
+		return
+	}
+
+	term, err := c.newDebugTerminal(ctx, op, mounts)
+	if err != nil {
+		bklog.G(ctx).WithError(err).Error("failed to open debug terminal")
+		return
+	}
+	defer term.Close()
+
+	select {
+	case watcher.ch <- engine.DebugTerminal{
+		ID:       term.ID,
+		Cmd:      execErr.Cmd,
+		ExitCode: execErr.ExitCode,
+	}:
+	case <-watcher.ctx.Done():
+		return
+	case <-ctx.Done():
+		return
+	}
+
+	select {
+	case <-term.done:
+	case <-watcher.ctx.Done():
+	case <-ctx.Done():
+	}
+}
+
+func (c *Client) newDebugTerminal(ctx context.Context, op *bksolverpb.Op, mounts []bksolver.Result) (*Terminal, error) {
+	execOp := op.GetExec()
+	if execOp == nil {
+		return nil, fmt.Errorf("expected exec op, got %T", op.Op)
+	}
+
+	extraHosts, err := bkcontainer.ParseExtraHosts(execOp.Meta.ExtraHosts)
+	if err != nil {
+		return nil, err
+	}
+
+	ctrReq := bkcontainer.NewContainerRequest{
+		ContainerID: identity.NewID(),
+		NetMode:     execOp.Network,
+		Hostname:    execOp.Meta.Hostname,
+		ExtraHosts:  extraHosts,
+		Platform:    op.Platform,
+	}
+
+	for i, m := range execOp.Mounts {
+		if m.Dest == MetaMountDestPath {
+			// leave out the meta mount so that the shim doesn't wrap the
+			// terminal and capture its output
+			continue
+		}
+
+		var workerRef *bkworker.WorkerRef
+		if i < len(mounts) && mounts[i] != nil {
+			var ok bool
+			workerRef, ok = mounts[i].Sys().(*bkworker.WorkerRef)
+			if !ok {
+				return nil, fmt.Errorf("invalid ref type: %T", mounts[i].Sys())
+			}
+		}
+
+		ctrReq.Mounts = append(ctrReq.Mounts, bkcontainer.Mount{
+			WorkerRef: workerRef,
+			Mount: &bksolverpb.Mount{
+				Dest:      m.Dest,
+				Selector:  m.Selector,
+				Readonly:  m.Readonly,
+				MountType: m.MountType,
+				CacheOpt:  m.CacheOpt,
+				SecretOpt: m.SecretOpt,
+				SSHOpt:    m.SSHOpt,
+				TmpfsOpt:  m.TmpfsOpt,
+			},
+		})
+	}
+
+	// using context.Background so it continues running until the terminal is
+	// released or when c.Close() is called
+	ctr, err := bkcontainer.NewContainer(
+		context.Background(),
+		c.Worker,
+		c.SessionManager,
+		bksession.NewGroup(c.ID()),
+		ctrReq,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("new container: %w", err)
+	}
+
+	c.containersMu.Lock()
+	if c.containers == nil {
+		c.containersMu.Unlock()
+		if err := ctr.Release(context.Background()); err != nil {
+			return nil, fmt.Errorf("release after close: %w", err)
+		}
+		return nil, errors.New("client closed")
+	}
+	c.containers[ctr] = struct{}{}
+	c.containersMu.Unlock()
+
+	env := append([]string{}, execOp.Meta.Env...)
+	if proxy := execOp.Meta.ProxyEnv; proxy != nil && proxy.FtpProxy != "" {
+		// carries the exec's uncached metadata, which the shim needs to set up
+		// e.g. service search domains
+		env = append(env, "ftp_proxy="+proxy.FtpProxy)
+	}
+
+	return c.NewTerminal(identity.NewID(), ctr, bkgw.StartRequest{
+		Args:         DebugShell,
+		Env:          env,
+		SecretEnv:    execOp.Secretenv,
+		User:         execOp.Meta.User,
+		Cwd:          execOp.Meta.Cwd,
+		SecurityMode: execOp.Security,
+	}, nil), nil
+}
@@ -0,0 +1,90 @@
+package buildkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/dagger/dagger/engine"
+	"github.com/moby/buildkit/session/sshforward"
+	"github.com/moby/buildkit/util/bklog"
+)
+
+// ListenHostToContainer listens on the given address of the main client's
+// host and forwards every connection made to it through the session to the
+// upstream address, which is dialed from the engine.
+//
+// It returns the address the client listens on, which differs from the given
+// one when it has a 0 port, and a function that stops listening.
+func (c *Client) ListenHostToContainer(
+	ctx context.Context,
+	hostListenAddr, proto, upstream string,
+) (string, func() error, error) {
+	// the listener outlives the request that opens it
+	listenCtx, cancel := context.WithCancel(context.Background())
+
+	sshClient := sshforward.NewSSHClient(c.MainClientCaller.Conn())
+
+	listener, err := sshClient.ForwardAgent(engine.TunnelOpts{
+		Protocol:   proto,
+		ListenAddr: hostListenAddr,
+	}.AppendToOutgoingContext(listenCtx))
+	if err != nil {
+		cancel()
+		return "", nil, fmt.Errorf("open listener stream: %w", err)
+	}
+
+	msg := &sshforward.BytesMessage{}
+	if err := listener.RecvMsg(msg); err != nil {
+		cancel()
+		return "", nil, fmt.Errorf("listen on host %s: %w", hostListenAddr, err)
+	}
+	addr := string(msg.Data)
+
+	go func() {
+		for {
+			msg := &sshforward.BytesMessage{}
+			if err := listener.RecvMsg(msg); err != nil {
+				if !errors.Is(err, io.EOF) && listenCtx.Err() == nil {
+					bklog.G(ctx).WithError(err).Warnf("host listener %s failed", addr)
+				}
+				return
+			}
+
+			go c.forwardHostConn(listenCtx, sshClient, proto, string(msg.Data), upstream)
+		}
+	}()
+
+	return addr, func() error {
+		cancel()
+		return nil
+	}, nil
+}
+
+func (c *Client) forwardHostConn(ctx context.Context, sshClient sshforward.SSHClient, proto, connID, upstream string) {
+	// cancelling the stream closes the host's end of the connection
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := sshClient.ForwardAgent(engine.TunnelOpts{
+		Protocol: proto,
+		ConnID:   connID,
+	}.AppendToOutgoingContext(ctx))
+	if err != nil {
+		bklog.G(ctx).WithError(err).Debugf("open stream for host connection %s", connID)
+		return
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, proto, upstream)
+	if err != nil {
+		bklog.G(ctx).WithError(err).Debugf("dial %s for host connection %s", upstream, connID)
+		return
+	}
+
+	if err := sshforward.Copy(ctx, conn, stream, stream.CloseSend); err != nil {
+		bklog.G(ctx).WithError(err).Debugf("forward host connection %s", connID)
+	}
+}
@@ -31,9 +31,11 @@ import (
 	"github.com/tonistiigi/fsutil"
 	fstypes "github.com/tonistiigi/fsutil/types"
 	"github.com/vito/progrock"
+	"golang.org/x/net/http/httpguts"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
 
+	"github.com/dagger/dagger/auth"
 	"github.com/dagger/dagger/core/pipeline"
 	"github.com/dagger/dagger/engine"
 	"github.com/dagger/dagger/telemetry"
@@ -57,6 +59,22 @@ type Params struct {
 
 	DisableHostRW bool
 
+	// DockerConfigAuth opts in to serving the registry credentials of the
+	// host's Docker config with auth.DockerConfigAuthProvider, which also
+	// reads DOCKER_AUTH_CONFIG and only looks up each registry's credentials
+	// when the engine first needs them. Otherwise, they are served from the
+	// Docker config file as loaded when connecting.
+	//
+	// Either way, credentials set with Container.withRegistryAuth take
+	// precedence.
+	DockerConfigAuth bool
+
+	// ImageLockPath is the path of the lockfile that pins the digests image
+	// references resolve to, used if ImageLockMode is set. Nested sessions
+	// can't set it; they share the lock of the outermost session.
+	ImageLockPath string
+	ImageLockMode engine.ImageLockMode
+
 	JournalFile        string
 	ProgrockWriter     progrock.Writer
 	EngineNameCallback func(string)
@@ -79,6 +97,7 @@ type Client struct {
 	bkClient             *bkclient.Client
 	bkSession            *bksession.Session
 	upstreamCacheOptions []*controlapi.CacheOptionsEntry
+	imageLock            *engine.ImageLock
 
 	hostname string
 
@@ -130,6 +149,10 @@ func Connect(ctx context.Context, params Params) (_ *Client, _ context.Context,
 
 	nestedSessionPortVal, isNestedSession := os.LookupEnv("DAGGER_SESSION_PORT")
 	if isNestedSession {
+		if c.ImageLockMode != "" {
+			// nested sessions share the image lock of the outermost one
+			return nil, nil, errors.New("image lock can only be set for the outermost session")
+		}
 		nestedSessionPort, err := strconv.Atoi(nestedSessionPortVal)
 		if err != nil {
 			return nil, nil, fmt.Errorf("parse DAGGER_SESSION_PORT: %w", err)
@@ -167,6 +190,16 @@ func Connect(ctx context.Context, params Params) (_ *Client, _ context.Context,
 		}}
 	}
 
+	if c.ImageLockMode != "" {
+		if c.ImageLockPath == "" {
+			c.ImageLockPath = engine.ImageLockFile
+		}
+		c.imageLock, err = engine.ReadImageLock(c.ImageLockPath, c.ImageLockMode)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read image lock: %w", err)
+		}
+	}
+
 	remote, err := url.Parse(c.RunnerHost)
 	if err != nil {
 		return nil, nil, fmt.Errorf("parse runner host: %w", err)
@@ -242,12 +275,14 @@ func Connect(ctx context.Context, params Params) (_ *Client, _ context.Context,
 	}
 
 	// sockets
-	bkSession.Allow(SocketProvider{
-		EnableHostNetworkAccess: !c.DisableHostRW,
-	})
+	bkSession.Allow(NewSocketProvider(!c.DisableHostRW))
 
 	// registry auth
-	bkSession.Allow(authprovider.NewDockerAuthProvider(config.LoadDefaultConfigFile(os.Stderr), nil))
+	if c.DockerConfigAuth {
+		bkSession.Allow(auth.NewDockerConfigAuthProvider(os.Stderr))
+	} else {
+		bkSession.Allow(authprovider.NewDockerAuthProvider(config.LoadDefaultConfigFile(os.Stderr), nil))
+	}
 
 	// connect to the server, registering our session attachables and starting the server if not
 	// already started
@@ -262,6 +297,7 @@ func Connect(ctx context.Context, params Params) (_ *Client, _ context.Context,
 				ClientHostname:      hostname,
 				UpstreamCacheConfig: c.upstreamCacheOptions,
 				Labels:              c.labels,
+				ImageLock:           c.imageLock,
 			}.AppendToMD(meta))
 		})
 	})
@@ -318,15 +354,24 @@ func (c *Client) Close() (rerr error) {
 	default:
 	}
 
-	if len(c.upstreamCacheOptions) > 0 {
+	writeImageLock := c.imageLock != nil && c.imageLock.Mode.Writes()
+	if len(c.upstreamCacheOptions) > 0 || writeImageLock {
 		cacheExportCtx, cacheExportCancel := context.WithTimeout(c.internalCtx, 600*time.Second)
 		defer cacheExportCancel()
-		_, err := c.bkClient.ControlClient().Solve(cacheExportCtx, &controlapi.SolveRequest{
+		resp, err := c.bkClient.ControlClient().Solve(cacheExportCtx, &controlapi.SolveRequest{
 			Cache: controlapi.CacheOptions{
 				Exports: c.upstreamCacheOptions,
 			},
 		})
 		rerr = errors.Join(rerr, err)
+
+		if err == nil && writeImageLock {
+			if lock, ok := resp.ExporterResponse[engine.ImageLockExporterResponseKey]; ok {
+				if err := os.WriteFile(c.ImageLockPath, []byte(lock), 0o644); err != nil {
+					rerr = errors.Join(rerr, fmt.Errorf("write image lock: %w", err))
+				}
+			}
+		}
 	}
 
 	c.closeRequests()
@@ -521,6 +566,11 @@ func (c *Client) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	if httpguts.HeaderValuesContainsToken(r.Header["Connection"], "upgrade") {
+		// e.g. terminal websockets, which need the raw connection
+		c.serveUpgrade(w, r)
+		return
+	}
 	resp, err := c.httpClient.Do(&http.Request{
 		Method: r.Method,
 		URL: &url.URL{
@@ -541,12 +591,82 @@ func (c *Client) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		w.Header()[k] = v
 	}
 	w.WriteHeader(resp.StatusCode)
-	_, err = io.Copy(w, resp.Body)
+	var dst io.Writer = w
+	if flusher, ok := w.(http.Flusher); ok {
+		// flush as we go so that streamed responses, e.g. logs, aren't held up
+		dst = flushWriter{w, flusher}
+	}
+	_, err = io.Copy(dst, resp.Body)
 	if err != nil {
+		if ctx.Err() != nil {
+			// the request was canceled, e.g. a client stopped following logs
+			return
+		}
 		panic(err) // don't write header because we already wrote to the body, which isn't allowed
 	}
 }
 
+type flushWriter struct {
+	io.Writer
+	http.Flusher
+}
+
+func (w flushWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	w.Flush()
+	return n, err
+}
+
+// serveUpgrade proxies a request that upgrades its connection by forwarding
+// the raw connection to the server.
+func (c *Client) serveUpgrade(w http.ResponseWriter, r *http.Request) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("connection upgrade not supported"))
+		return
+	}
+
+	dial := c.DialContext
+	if c.nestedSessionPort != 0 {
+		dial = c.NestedDialContext
+	}
+	serverConn, err := dial(r.Context(), "", "")
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("dial: " + err.Error()))
+		return
+	}
+	defer serverConn.Close()
+
+	r.Host = "dagger"
+	if err := r.Write(serverConn); err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("write request: " + err.Error()))
+		return
+	}
+
+	clientConn, clientBuf, err := hijacker.Hijack()
+	if err != nil {
+		panic(err) // don't write header because the connection may be in an unknown state
+	}
+	defer clientConn.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(serverConn, clientBuf)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(clientConn, serverConn)
+		done <- struct{}{}
+	}()
+	select {
+	case <-done:
+	case <-r.Context().Done():
+	}
+}
+
 // Local dir imports
 type AnyDirSource struct{}
 
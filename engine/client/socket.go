@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/dagger/dagger/core/socket"
+	"github.com/dagger/dagger/engine"
 	"github.com/moby/buildkit/session/sshforward"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -13,6 +14,15 @@ import (
 
 type SocketProvider struct {
 	EnableHostNetworkAccess bool
+
+	tunnels *tunnelConns
+}
+
+func NewSocketProvider(enableHostNetworkAccess bool) SocketProvider {
+	return SocketProvider{
+		EnableHostNetworkAccess: enableHostNetworkAccess,
+		tunnels:                 newTunnelConns(),
+	}
 }
 
 func (p SocketProvider) Register(server *grpc.Server) {
@@ -44,6 +54,13 @@ func (p SocketProvider) ForwardAgent(stream sshforward.SSH_ForwardAgentServer) e
 	if !ok {
 		return status.Errorf(codes.InvalidArgument, "no metadata")
 	}
+	tunnelOpts, err := engine.TunnelOptsFromContext(stream.Context())
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid tunnel options: %v", err)
+	}
+	if tunnelOpts != nil {
+		return p.tunnels.forward(stream, tunnelOpts)
+	}
 	var id string
 	if v, ok := opts[sshforward.KeySSHID]; ok && len(v) > 0 && v[0] != "" {
 		id = v[0]
@@ -0,0 +1,162 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/dagger/dagger/engine"
+	"golang.org/x/net/websocket"
+)
+
+// WatchDebugTerminals calls fn for every exec that fails while it's running,
+// until ctx is canceled. The failed exec is held open until the terminal
+// sent to fn is attached to and exits.
+func (c *Client) WatchDebugTerminals(ctx context.Context, fn func(context.Context, engine.DebugTerminal) error) error {
+	ctx, cancel, err := c.withClientCloseCancel(ctx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://dagger/debug/terminals", nil)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	req.SetBasicAuth(c.SecretToken, "")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("watch debug terminals: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("watch debug terminals: %s: %s", resp.Status, body)
+	}
+
+	dec := json.NewDecoder(bufio.NewReader(resp.Body))
+	for {
+		var term engine.DebugTerminal
+		if err := dec.Decode(&term); err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("decode debug terminal: %w", err)
+		}
+		if err := fn(ctx, term); err != nil {
+			return err
+		}
+	}
+}
+
+// AttachTerminal attaches to the terminal with the given ID, streaming stdin
+// to it and its output to stdout and stderr. Every size received on resize is
+// forwarded to the terminal. It returns the exit code of the terminal's
+// process.
+func (c *Client) AttachTerminal(
+	ctx context.Context,
+	id string,
+	stdin io.Reader,
+	stdout, stderr io.Writer,
+	resize <-chan engine.TerminalSize,
+) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	dial := c.DialContext
+	if c.nestedSessionPort != 0 {
+		dial = c.NestedDialContext
+	}
+	conn, err := dial(ctx, "", "")
+	if err != nil {
+		return -1, fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+
+	cfg, err := websocket.NewConfig("ws://dagger/terminals/"+id, "http://dagger")
+	if err != nil {
+		return -1, err
+	}
+	cfg.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(c.SecretToken+":")))
+
+	ws, err := websocket.NewClient(cfg, conn)
+	if err != nil {
+		return -1, fmt.Errorf("attach terminal: %w", err)
+	}
+	defer ws.Close()
+
+	go func() {
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := stdin.Read(buf)
+			if n > 0 {
+				msg := append([]byte{engine.TerminalStdin}, buf[:n]...)
+				if err := websocket.Message.Send(ws, msg); err != nil {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case size, ok := <-resize:
+				if !ok {
+					return
+				}
+				payload, err := json.Marshal(size)
+				if err != nil {
+					return
+				}
+				msg := append([]byte{engine.TerminalResize}, payload...)
+				if err := websocket.Message.Send(ws, msg); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		ws.Close()
+	}()
+
+	for {
+		var msg []byte
+		if err := websocket.Message.Receive(ws, &msg); err != nil {
+			if ctx.Err() != nil {
+				return -1, ctx.Err()
+			}
+			return -1, fmt.Errorf("terminal closed without exit code: %w", err)
+		}
+		if len(msg) == 0 {
+			continue
+		}
+		switch msg[0] {
+		case engine.TerminalStdout:
+			if _, err := stdout.Write(msg[1:]); err != nil {
+				return -1, err
+			}
+		case engine.TerminalStderr:
+			if _, err := stderr.Write(msg[1:]); err != nil {
+				return -1, err
+			}
+		case engine.TerminalExit:
+			return strconv.Atoi(string(msg[1:]))
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package client
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/dagger/dagger/engine"
+	"github.com/moby/buildkit/identity"
+	"github.com/moby/buildkit/session/sshforward"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// tunnelConns holds the connections accepted by tunnel listeners until the
+// engine opens a stream to carry them.
+type tunnelConns struct {
+	conns map[string]net.Conn
+	l     sync.Mutex
+}
+
+func newTunnelConns() *tunnelConns {
+	return &tunnelConns{
+		conns: map[string]net.Conn{},
+	}
+}
+
+func (t *tunnelConns) add(conn net.Conn) string {
+	id := identity.NewID()
+	t.l.Lock()
+	t.conns[id] = conn
+	t.l.Unlock()
+	return id
+}
+
+func (t *tunnelConns) take(id string) (net.Conn, bool) {
+	t.l.Lock()
+	defer t.l.Unlock()
+	conn, found := t.conns[id]
+	delete(t.conns, id)
+	return conn, found
+}
+
+func (t *tunnelConns) forward(stream sshforward.SSH_ForwardAgentServer, opts *engine.TunnelOpts) error {
+	if opts.ConnID != "" {
+		conn, found := t.take(opts.ConnID)
+		if !found {
+			return status.Errorf(codes.NotFound, "unknown tunnel connection %s", opts.ConnID)
+		}
+		return sshforward.Copy(stream.Context(), conn, stream, nil)
+	}
+
+	switch opts.Protocol {
+	case "tcp":
+		return t.listenTCP(stream, opts.ListenAddr)
+	case "udp":
+		return t.listenUDP(stream, opts.ListenAddr)
+	default:
+		return status.Errorf(codes.InvalidArgument, "unsupported tunnel protocol %q", opts.Protocol)
+	}
+}
+
+func (t *tunnelConns) listenTCP(stream sshforward.SSH_ForwardAgentServer, addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "listen: %v", err)
+	}
+	defer l.Close()
+
+	if err := stream.SendMsg(&sshforward.BytesMessage{Data: []byte(l.Addr().String())}); err != nil {
+		return err
+	}
+
+	go closeOnStreamEnd(stream, l)
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		id := t.add(conn)
+		if err := stream.SendMsg(&sshforward.BytesMessage{Data: []byte(id)}); err != nil {
+			if conn, found := t.take(id); found {
+				conn.Close()
+			}
+			return err
+		}
+	}
+}
+
+func (t *tunnelConns) listenUDP(stream sshforward.SSH_ForwardAgentServer, addr string) error {
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "listen: %v", err)
+	}
+	defer pc.Close()
+
+	if err := stream.SendMsg(&sshforward.BytesMessage{Data: []byte(pc.LocalAddr().String())}); err != nil {
+		return err
+	}
+
+	go closeOnStreamEnd(stream, pc)
+
+	// UDP has no connections, so each peer that sends a packet to the listener
+	// gets one, which lasts until the engine closes its stream.
+	peers := map[string]*udpPeerConn{}
+	var peersL sync.Mutex
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, peer, err := pc.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		peersL.Lock()
+		conn, found := peers[peer.String()]
+		if !found {
+			conn = &udpPeerConn{
+				pc:      pc,
+				peer:    peer,
+				packets: make(chan []byte, 64),
+				closed:  make(chan struct{}),
+			}
+			conn.onClose = func() {
+				peersL.Lock()
+				delete(peers, peer.String())
+				peersL.Unlock()
+			}
+			peers[peer.String()] = conn
+		}
+		peersL.Unlock()
+
+		if !found {
+			id := t.add(conn)
+			if err := stream.SendMsg(&sshforward.BytesMessage{Data: []byte(id)}); err != nil {
+				return err
+			}
+		}
+
+		conn.deliver(append([]byte(nil), buf[:n]...))
+	}
+}
+
+// closeOnStreamEnd closes the listener once the engine stops listening, which
+// it does by closing or cancelling the stream.
+func closeOnStreamEnd(stream sshforward.SSH_ForwardAgentServer, l interface{ Close() error }) {
+	defer l.Close()
+	for {
+		if err := stream.RecvMsg(&sshforward.BytesMessage{}); err != nil {
+			return
+		}
+	}
+}
+
+// udpPeerConn is a net.Conn for the packets exchanged with one peer of a UDP
+// tunnel listener.
+type udpPeerConn struct {
+	pc      net.PacketConn
+	peer    net.Addr
+	packets chan []byte
+
+	closed    chan struct{}
+	closeOnce sync.Once
+	onClose   func()
+}
+
+var _ net.Conn = (*udpPeerConn)(nil)
+
+func (c *udpPeerConn) deliver(packet []byte) {
+	select {
+	case c.packets <- packet:
+	case <-c.closed:
+	default:
+		// drop the packet, as the network would
+	}
+}
+
+func (c *udpPeerConn) Read(b []byte) (int, error) {
+	select {
+	case packet := <-c.packets:
+		return copy(b, packet), nil
+	case <-c.closed:
+		return 0, net.ErrClosed
+	}
+}
+
+func (c *udpPeerConn) Write(b []byte) (int, error) {
+	return c.pc.WriteTo(b, c.peer)
+}
+
+func (c *udpPeerConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.onClose()
+	})
+	return nil
+}
+
+func (c *udpPeerConn) LocalAddr() net.Addr {
+	return c.pc.LocalAddr()
+}
+
+func (c *udpPeerConn) RemoteAddr() net.Addr {
+	return c.peer
+}
+
+func (c *udpPeerConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *udpPeerConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *udpPeerConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/opencontainers/go-digest"
+)
+
+// ImageLockFile is the default name of the file that image references are
+// locked in.
+const ImageLockFile = "dagger.lock"
+
+// ImageLockExporterResponseKey is the key of the updated lock in the response
+// to the Solve request that a client makes when it closes.
+const ImageLockExporterResponseKey = "dagger.imagelock"
+
+type ImageLockMode string
+
+const (
+	// ImageLockModeRecord pins image references to the digests in the lock,
+	// recording the digests of any that aren't in it yet.
+	ImageLockModeRecord ImageLockMode = "record"
+
+	// ImageLockModeStrict pins image references to the digests in the lock and
+	// refuses to resolve any that aren't in it.
+	ImageLockModeStrict ImageLockMode = "strict"
+
+	// ImageLockModeUpdate resolves every image reference anew, recording the
+	// digests it resolves to.
+	ImageLockModeUpdate ImageLockMode = "update"
+)
+
+// ParseImageLockMode parses a lock mode, where "off" or "" means no locking.
+func ParseImageLockMode(mode string) (ImageLockMode, error) {
+	switch m := ImageLockMode(mode); m {
+	case "", "off":
+		return "", nil
+	case ImageLockModeRecord, ImageLockModeStrict, ImageLockModeUpdate:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown lock mode %q (must be off, record, strict or update)", mode)
+	}
+}
+
+// Writes returns whether the lock is written back when a session ends.
+func (mode ImageLockMode) Writes() bool {
+	return mode == ImageLockModeRecord || mode == ImageLockModeUpdate
+}
+
+// ImageLock records the digests that image references resolve to, so that
+// the same references resolve to the same images in later sessions.
+type ImageLock struct {
+	Mode ImageLockMode `json:"mode,omitempty"`
+
+	// Images maps normalized image references to digests.
+	Images map[string]digest.Digest `json:"images"`
+
+	mu sync.Mutex
+}
+
+// ReadImageLock reads a lock from a file, returning an empty lock if it
+// doesn't exist.
+func ReadImageLock(path string, mode ImageLockMode) (*ImageLock, error) {
+	lock := &ImageLock{
+		Mode:   mode,
+		Images: map[string]digest.Digest{},
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return lock, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, lock); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	// the mode isn't stored in the file
+	lock.Mode = mode
+	if lock.Images == nil {
+		lock.Images = map[string]digest.Digest{}
+	}
+	return lock, nil
+}
+
+// Pinned returns the digest that the image reference is pinned to, if any.
+func (lock *ImageLock) Pinned(ref string) (digest.Digest, bool, error) {
+	lock.mu.Lock()
+	defer lock.mu.Unlock()
+
+	if lock.Mode == ImageLockModeUpdate {
+		return "", false, nil
+	}
+
+	dgst, found := lock.Images[ref]
+	if !found && lock.Mode == ImageLockModeStrict {
+		return "", false, fmt.Errorf("image %s is not in the lockfile; run in %s mode to add it", ref, ImageLockModeRecord)
+	}
+	return dgst, found, nil
+}
+
+// Record records the digest that the image reference resolved to.
+func (lock *ImageLock) Record(ref string, dgst digest.Digest) {
+	lock.mu.Lock()
+	defer lock.mu.Unlock()
+	lock.Images[ref] = dgst
+}
+
+// Refs returns the locked image references, sorted.
+func (lock *ImageLock) Refs() []string {
+	lock.mu.Lock()
+	defer lock.mu.Unlock()
+
+	refs := make([]string, 0, len(lock.Images))
+	for ref := range lock.Images {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// MarshalFile returns the lock's file contents.
+func (lock *ImageLock) MarshalFile() ([]byte, error) {
+	lock.mu.Lock()
+	defer lock.mu.Unlock()
+
+	content, err := json.MarshalIndent(struct {
+		Images map[string]digest.Digest `json:"images"`
+	}{lock.Images}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(content, '\n'), nil
+}
@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageLock(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), ImageLockFile)
+
+	const alpine = "docker.io/library/alpine:3.18"
+	const golang = "docker.io/library/golang:1.20"
+	alpineDigest := digest.FromString("alpine")
+	golangDigest := digest.FromString("golang")
+
+	lock, err := ReadImageLock(path, ImageLockModeRecord)
+	require.NoError(t, err)
+	require.Empty(t, lock.Refs())
+
+	_, found, err := lock.Pinned(alpine)
+	require.NoError(t, err)
+	require.False(t, found)
+	lock.Record(alpine, alpineDigest)
+
+	content, err := lock.MarshalFile()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, content, 0o644))
+
+	t.Run("record", func(t *testing.T) {
+		t.Parallel()
+
+		lock, err := ReadImageLock(path, ImageLockModeRecord)
+		require.NoError(t, err)
+		require.Equal(t, []string{alpine}, lock.Refs())
+
+		dgst, found, err := lock.Pinned(alpine)
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, alpineDigest, dgst)
+
+		_, found, err = lock.Pinned(golang)
+		require.NoError(t, err)
+		require.False(t, found)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		t.Parallel()
+
+		lock, err := ReadImageLock(path, ImageLockModeStrict)
+		require.NoError(t, err)
+
+		dgst, found, err := lock.Pinned(alpine)
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, alpineDigest, dgst)
+
+		_, _, err = lock.Pinned(golang)
+		require.ErrorContains(t, err, "not in the lockfile")
+	})
+
+	t.Run("update", func(t *testing.T) {
+		t.Parallel()
+
+		lock, err := ReadImageLock(path, ImageLockModeUpdate)
+		require.NoError(t, err)
+
+		_, found, err := lock.Pinned(alpine)
+		require.NoError(t, err)
+		require.False(t, found)
+
+		lock.Record(golang, golangDigest)
+		require.Equal(t, []string{alpine, golang}, lock.Refs())
+	})
+}
+
+func TestParseImageLockMode(t *testing.T) {
+	t.Parallel()
+
+	for _, mode := range []string{"", "off"} {
+		parsed, err := ParseImageLockMode(mode)
+		require.NoError(t, err)
+		require.Empty(t, parsed)
+	}
+
+	parsed, err := ParseImageLockMode("strict")
+	require.NoError(t, err)
+	require.Equal(t, ImageLockModeStrict, parsed)
+	require.False(t, parsed.Writes())
+
+	_, err = ParseImageLockMode("bogus")
+	require.Error(t, err)
+}
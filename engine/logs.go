@@ -0,0 +1,38 @@
+package engine
+
+// LogsRequest is the JSON body of a request to stream a container's output
+// from /logs.
+type LogsRequest struct {
+	// Container is the ID of the container whose last exec's output is
+	// streamed.
+	Container string `json:"container"`
+
+	// Follow streams output until the request is closed instead of
+	// evaluating the container, which is useful for tailing the logs of a
+	// container that's running as a service.
+	Follow bool `json:"follow,omitempty"`
+}
+
+// LogMessage is streamed as newline-delimited JSON in response to a
+// LogsRequest. Every message is either a line of output or, when not
+// following, the final message reporting how the exec finished.
+type LogMessage struct {
+	// Stream is either "stdout" or "stderr".
+	Stream string `json:"stream,omitempty"`
+
+	// Line is a line of output, without its trailing newline.
+	Line string `json:"line,omitempty"`
+
+	// ExitCode is set on the final message to the exit code of the exec.
+	ExitCode *int `json:"exitCode,omitempty"`
+
+	// Error is set on the final message if the exec failed. It has the same
+	// shape as a GraphQL error.
+	Error *LogError `json:"error,omitempty"`
+}
+
+// LogError is the error that ended a stream of logs.
+type LogError struct {
+	Message    string         `json:"message"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
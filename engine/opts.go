@@ -18,6 +18,7 @@ const (
 	clientMetadataMetaKey  = "x-dagger-client-metadata"
 	localImportOptsMetaKey = "x-dagger-local-import-opts"
 	localExportOptsMetaKey = "x-dagger-local-export-opts"
+	tunnelOptsMetaKey      = "x-dagger-tunnel-opts"
 
 	// local dir import (set by buildkit, can't change)
 	localDirImportDirNameMetaKey         = "dir-name"
@@ -62,6 +63,10 @@ type ClientMetadata struct {
 
 	// Import configuration for Buildkit's remote cache
 	UpstreamCacheConfig []*controlapi.CacheOptionsEntry
+
+	// (Optional) Lock of image references to pin image resolution to for the
+	// whole server
+	ImageLock *ImageLock `json:"image_lock,omitempty"`
 }
 
 // ClientIDs returns the ClientID followed by ParentClientIDs.
@@ -195,6 +200,52 @@ func LocalExportOptsFromContext(ctx context.Context) (*LocalExportOpts, error) {
 	return opts, nil
 }
 
+// TunnelOpts configures a stream opened by the engine on a client's socket
+// provider to tunnel network connections through the session.
+//
+// A stream with a ListenAddr asks the client to listen on its host; the
+// client replies with the address it listens on, and then with the ID of
+// every connection it accepts. A stream with a ConnID then carries the data
+// of that connection.
+type TunnelOpts struct {
+	Protocol   string `json:"protocol"`
+	ListenAddr string `json:"listen_addr,omitempty"`
+	ConnID     string `json:"conn_id,omitempty"`
+}
+
+func (o TunnelOpts) ToGRPCMD() metadata.MD {
+	return encodeMeta(tunnelOptsMetaKey, o)
+}
+
+func (o TunnelOpts) AppendToOutgoingContext(ctx context.Context) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok {
+		md = make(metadata.MD)
+	}
+	for k, v := range o.ToGRPCMD() {
+		md[k] = append(md[k], v...)
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// TunnelOptsFromContext returns the tunnel options of a stream, or nil if the
+// stream isn't a tunnel.
+func TunnelOptsFromContext(ctx context.Context) (*TunnelOpts, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+	if _, ok := md[tunnelOptsMetaKey]; !ok {
+		return nil, nil
+	}
+
+	opts := &TunnelOpts{}
+	if err := decodeMeta(md, tunnelOptsMetaKey, opts); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
 func contextWithMD(ctx context.Context, mds ...metadata.MD) context.Context {
 	incomingMD, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/containerd/containerd/remotes/docker"
 	"github.com/dagger/dagger/auth"
 	"github.com/dagger/dagger/core"
 	"github.com/dagger/dagger/core/pipeline"
@@ -21,6 +22,7 @@ import (
 	"github.com/moby/buildkit/frontend"
 	bkgw "github.com/moby/buildkit/frontend/gateway/client"
 	"github.com/moby/buildkit/session"
+	bkauth "github.com/moby/buildkit/session/auth"
 	"github.com/moby/buildkit/session/grpchijack"
 	containerdsnapshot "github.com/moby/buildkit/snapshot/containerd"
 	"github.com/moby/buildkit/solver"
@@ -51,6 +53,11 @@ type BuildkitController struct {
 	cacheManager          solver.CacheManager
 	worker                bkworker.Worker
 	privilegedExecEnabled bool
+	hostNetworkEnabled    bool
+
+	// pulledImages is shared by every server, so that an image pulled in one
+	// session can be used by the pull policies of the next
+	pulledImages *buildkit.PulledImages
 
 	// server id -> server
 	servers  map[string]*DaggerServer
@@ -72,6 +79,7 @@ type BuildkitControllerOpts struct {
 	TraceCollector         trace.SpanExporter
 	UpstreamCacheExporters map[string]remotecache.ResolveCacheExporterFunc
 	UpstreamCacheImporters map[string]remotecache.ResolveCacheImporterFunc
+	RegistryHosts          docker.RegistryHosts
 }
 
 func NewBuildkitController(opts BuildkitControllerOpts) (*BuildkitController, error) {
@@ -105,12 +113,16 @@ func NewBuildkitController(opts BuildkitControllerOpts) (*BuildkitController, er
 		genericSolver:          genericSolver,
 		cacheManager:           opts.CacheManager,
 		worker:                 w,
+		pulledImages:           buildkit.NewPulledImages(opts.ContentStore),
 		servers:                make(map[string]*DaggerServer),
 	}
 
 	for _, entitlementStr := range opts.Entitlements {
-		if entitlementStr == string(entitlements.EntitlementSecurityInsecure) {
+		switch entitlementStr {
+		case string(entitlements.EntitlementSecurityInsecure):
 			e.privilegedExecEnabled = true
+		case string(entitlements.EntitlementNetworkHost):
+			e.hostNetworkEnabled = true
 		}
 	}
 
@@ -207,6 +219,7 @@ func (e *BuildkitController) Session(stream controlapi.Control_SessionServer) (r
 
 		secretStore := core.NewSecretStore()
 		authProvider := auth.NewRegistryAuthProvider()
+		authProvider.SetHostAuth(bkauth.NewAuthClient(caller.Conn()))
 
 		var cacheImporterCfgs []bkgw.CacheOptionsEntry
 		for _, cacheImportCfg := range opts.UpstreamCacheConfig {
@@ -229,7 +242,11 @@ func (e *BuildkitController) Session(stream controlapi.Control_SessionServer) (r
 			SecretStore:           secretStore,
 			AuthProvider:          authProvider,
 			PrivilegedExecEnabled: e.privilegedExecEnabled,
+			HostNetworkEnabled:    e.hostNetworkEnabled,
 			UpstreamCacheImports:  cacheImporterCfgs,
+			RegistryHosts:         e.RegistryHosts,
+			PulledImages:          e.pulledImages,
+			ImageLock:             opts.ImageLock,
 			MainClientCaller:      caller,
 		})
 		if err != nil {
@@ -331,7 +348,19 @@ func (e *BuildkitController) Solve(ctx context.Context, req *controlapi.SolveReq
 		}
 		bklog.G(ctx).Debugf("done running cache export for client %s", opts.ClientID)
 	}
-	return &controlapi.SolveResponse{}, nil
+
+	resp := &controlapi.SolveResponse{}
+	if lock := srv.bkClient.ImageLock; lock != nil && lock.Mode.Writes() {
+		// send the lock back for the client to write
+		lockBytes, err := lock.MarshalFile()
+		if err != nil {
+			return nil, err
+		}
+		resp.ExporterResponse = map[string]string{
+			engine.ImageLockExporterResponseKey: string(lockBytes),
+		}
+	}
+	return resp, nil
 }
 
 func (e *BuildkitController) DiskUsage(ctx context.Context, r *controlapi.DiskUsageRequest) (*controlapi.DiskUsageResponse, error) {
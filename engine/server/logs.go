@@ -0,0 +1,398 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/dagger/dagger/core"
+	"github.com/dagger/dagger/engine"
+	"github.com/dagger/dagger/engine/buildkit"
+	"github.com/moby/buildkit/util/bklog"
+	"github.com/opencontainers/go-digest"
+	"github.com/vito/progrock"
+)
+
+// serveLogs streams the output of a container's last exec as
+// newline-delimited JSON engine.LogMessages while it runs.
+//
+// Unless following, the container is evaluated and the stream ends with a
+// message carrying the exec's exit code, or the error it failed with. Any
+// output that didn't make it through the progress pipeline by then, e.g.
+// because the exec was cached, is replayed from the exec's recorded output.
+func (srv *DaggerServer) serveLogs(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	var logsReq engine.LogsRequest
+	if err := json.NewDecoder(req.Body).Decode(&logsReq); err != nil {
+		http.Error(w, fmt.Sprintf("decode request: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	ctr, err := core.ContainerID(logsReq.Container).ToContainer()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("load container: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	execVtx, err := ctr.ExecVertex()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// a container running as a service logs under the service's vertex
+	// instead
+	svcVtx, err := core.NewContainerService(ctr).Digest()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	sub := srv.logs.Subscribe(execVtx, svcVtx)
+	defer srv.logs.Unsubscribe(sub)
+
+	ctx := req.Context()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	lines := newLogLineWriter(w, flusher, execVtx)
+
+	var evalDone chan error
+	if !logsReq.Follow {
+		evalDone = make(chan error, 1)
+		go func() {
+			evalDone <- ctr.Evaluate(ctx, srv.bkClient, srv.schema.Services())
+		}()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sub.notify:
+			if err := lines.Write(sub.Drain()); err != nil {
+				bklog.G(ctx).WithError(err).Debug("failed to send logs")
+				return
+			}
+		case evalErr := <-evalDone:
+			if err := srv.finishLogs(ctx, ctr, execVtx, sub, lines, evalErr); err != nil {
+				bklog.G(ctx).WithError(err).Debug("failed to send logs")
+			}
+			return
+		}
+	}
+}
+
+func (srv *DaggerServer) finishLogs(
+	ctx context.Context,
+	ctr *core.Container,
+	execVtx digest.Digest,
+	sub *logSubscription,
+	lines *logLineWriter,
+	evalErr error,
+) error {
+	if err := lines.Write(sub.Drain()); err != nil {
+		return err
+	}
+
+	var final engine.LogMessage
+	if evalErr != nil {
+		final.Error = &engine.LogError{Message: evalErr.Error()}
+
+		var execErr *buildkit.ExecError
+		if errors.As(evalErr, &execErr) {
+			final.ExitCode = &execErr.ExitCode
+			final.Error.Extensions = execErr.Extensions()
+
+			// the error might be from an exec the container depends on
+			// rather than its own
+			if execErr.Vertex == execVtx {
+				if err := lines.Replay(execErr.Output); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := lines.Flush(); err != nil {
+			return err
+		}
+		return lines.Send(final)
+	}
+
+	svcs := srv.schema.Services()
+
+	recorded, err := ctr.MetaFileContents(ctx, srv.bkClient, svcs, "", buildkit.ExecOutputMetaFile)
+	if err != nil {
+		return err
+	}
+	output, err := buildkit.ParseExecOutput([]byte(recorded))
+	if err != nil {
+		return err
+	}
+	if err := lines.Replay(output); err != nil {
+		return err
+	}
+	if err := lines.Flush(); err != nil {
+		return err
+	}
+
+	exitCode, err := ctr.ExitCode(ctx, srv.bkClient, svcs, "")
+	if err != nil {
+		return err
+	}
+	final.ExitCode = &exitCode
+
+	return lines.Send(final)
+}
+
+// logLineWriter splits logs into lines and sends them as engine.LogMessages.
+type logLineWriter struct {
+	enc     *json.Encoder
+	flusher http.Flusher
+
+	// vertex is the exec whose output is tracked in sent
+	vertex string
+
+	// sent is how much of each of the exec's streams has been received from
+	// the progress pipeline, so that replaying its recorded output can skip
+	// it
+	sent map[progrock.LogStream]int
+
+	// partial holds the output of each stream that's not yet been ended by
+	// a newline
+	partial map[progrock.LogStream][]byte
+}
+
+func newLogLineWriter(w http.ResponseWriter, flusher http.Flusher, vertex digest.Digest) *logLineWriter {
+	return &logLineWriter{
+		enc:     json.NewEncoder(w),
+		flusher: flusher,
+		vertex:  vertex.String(),
+		sent:    map[progrock.LogStream]int{},
+		partial: map[progrock.LogStream][]byte{},
+	}
+}
+
+// Write sends every complete line in logs, holding on to the rest until the
+// line is ended or Flush is called.
+func (l *logLineWriter) Write(logs []*progrock.VertexLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+
+	for _, log := range logs {
+		if log.Vertex == l.vertex {
+			l.sent[log.Stream] += len(log.Data)
+		}
+		if err := l.write(log.Stream, log.Data); err != nil {
+			return err
+		}
+	}
+
+	l.flusher.Flush()
+	return nil
+}
+
+// Replay sends the exec's recorded output in the order it was written,
+// skipping whatever was already received from the progress pipeline.
+func (l *logLineWriter) Replay(output []buildkit.ExecOutputChunk) error {
+	skip := map[progrock.LogStream]int{}
+	for stream, n := range l.sent {
+		skip[stream] = n
+	}
+
+	for _, chunk := range output {
+		data := chunk.Data
+		if n := skip[chunk.Stream]; n > 0 {
+			if n > len(data) {
+				n = len(data)
+			}
+			skip[chunk.Stream] -= n
+			data = data[n:]
+		}
+		if len(data) == 0 {
+			continue
+		}
+		l.sent[chunk.Stream] += len(data)
+		if err := l.write(chunk.Stream, data); err != nil {
+			return err
+		}
+	}
+
+	l.flusher.Flush()
+	return nil
+}
+
+// Flush sends any output that hasn't been ended by a newline.
+func (l *logLineWriter) Flush() error {
+	for _, stream := range []progrock.LogStream{progrock.LogStream_STDOUT, progrock.LogStream_STDERR} {
+		if len(l.partial[stream]) == 0 {
+			continue
+		}
+		if err := l.sendLine(stream, l.partial[stream]); err != nil {
+			return err
+		}
+		delete(l.partial, stream)
+	}
+
+	l.flusher.Flush()
+	return nil
+}
+
+// Send sends a message as-is.
+func (l *logLineWriter) Send(msg engine.LogMessage) error {
+	if err := l.enc.Encode(msg); err != nil {
+		return err
+	}
+	l.flusher.Flush()
+	return nil
+}
+
+func (l *logLineWriter) write(stream progrock.LogStream, data []byte) error {
+	buf := append(l.partial[stream], data...)
+	for {
+		idx := bytes.IndexByte(buf, '\n')
+		if idx == -1 {
+			break
+		}
+		if err := l.sendLine(stream, buf[:idx]); err != nil {
+			return err
+		}
+		buf = buf[idx+1:]
+	}
+	l.partial[stream] = append([]byte(nil), buf...)
+	return nil
+}
+
+func (l *logLineWriter) sendLine(stream progrock.LogStream, line []byte) error {
+	var name string
+	switch stream {
+	case progrock.LogStream_STDOUT:
+		name = "stdout"
+	case progrock.LogStream_STDERR:
+		name = "stderr"
+	default:
+		return nil
+	}
+
+	return l.enc.Encode(engine.LogMessage{
+		Stream: name,
+		Line:   string(bytes.TrimSuffix(line, []byte("\r"))),
+	})
+}
+
+// logTap is a progrock.Writer that lets requests follow the logs of specific
+// vertices as they're written.
+type logTap struct {
+	subs map[*logSubscription]struct{}
+	mu   sync.Mutex
+}
+
+var _ progrock.Writer = (*logTap)(nil)
+
+func newLogTap() *logTap {
+	return &logTap{
+		subs: map[*logSubscription]struct{}{},
+	}
+}
+
+// Subscribe starts collecting the logs of the given vertices.
+func (t *logTap) Subscribe(vertices ...digest.Digest) *logSubscription {
+	sub := &logSubscription{
+		vertices: map[string]struct{}{},
+		notify:   make(chan struct{}, 1),
+	}
+	for _, vtx := range vertices {
+		sub.vertices[vtx.String()] = struct{}{}
+	}
+
+	t.mu.Lock()
+	t.subs[sub] = struct{}{}
+	t.mu.Unlock()
+
+	return sub
+}
+
+// Unsubscribe stops collecting logs for the subscription.
+func (t *logTap) Unsubscribe(sub *logSubscription) {
+	t.mu.Lock()
+	delete(t.subs, sub)
+	t.mu.Unlock()
+}
+
+func (t *logTap) WriteStatus(status *progrock.StatusUpdate) error {
+	if len(status.Logs) == 0 {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for sub := range t.subs {
+		sub.record(status)
+	}
+
+	return nil
+}
+
+func (t *logTap) Close() error {
+	return nil
+}
+
+// logSubscription buffers the logs of the vertices it's subscribed to until
+// they're drained, so that a slow reader never holds up the progress
+// pipeline.
+type logSubscription struct {
+	vertices map[string]struct{}
+
+	// notify receives a value whenever there's something new to drain
+	notify chan struct{}
+
+	logs []*progrock.VertexLog
+	mu   sync.Mutex
+}
+
+func (sub *logSubscription) record(status *progrock.StatusUpdate) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	var changed bool
+	for _, log := range status.Logs {
+		if _, ok := sub.vertices[log.Vertex]; ok {
+			sub.logs = append(sub.logs, log)
+			changed = true
+		}
+	}
+
+	if changed {
+		select {
+		case sub.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Drain returns the logs collected since the last call.
+func (sub *logSubscription) Drain() []*progrock.VertexLog {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	logs := sub.logs
+	sub.logs = nil
+	return logs
+}
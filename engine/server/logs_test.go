@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dagger/dagger/engine"
+	"github.com/dagger/dagger/engine/buildkit"
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+	"github.com/vito/progrock"
+)
+
+func TestLogLineWriterReplay(t *testing.T) {
+	t.Parallel()
+
+	vtx := digest.FromString("exec")
+	output := []buildkit.ExecOutputChunk{
+		{Stream: progrock.LogStream_STDOUT, Data: []byte("hello\n")},
+		{Stream: progrock.LogStream_STDERR, Data: []byte("goodbye\n")},
+		{Stream: progrock.LogStream_STDOUT, Data: []byte("done")},
+	}
+
+	readLines := func(t *testing.T, rec *httptest.ResponseRecorder) []engine.LogMessage {
+		t.Helper()
+		var msgs []engine.LogMessage
+		dec := json.NewDecoder(rec.Body)
+		for dec.More() {
+			var msg engine.LogMessage
+			require.NoError(t, dec.Decode(&msg))
+			msgs = append(msgs, msg)
+		}
+		return msgs
+	}
+
+	t.Run("replays everything in order when nothing was received", func(t *testing.T) {
+		t.Parallel()
+
+		rec := httptest.NewRecorder()
+		lines := newLogLineWriter(rec, rec, vtx)
+		require.NoError(t, lines.Replay(output))
+		require.NoError(t, lines.Flush())
+
+		require.Equal(t, []engine.LogMessage{
+			{Stream: "stdout", Line: "hello"},
+			{Stream: "stderr", Line: "goodbye"},
+			{Stream: "stdout", Line: "done"},
+		}, readLines(t, rec))
+	})
+
+	t.Run("skips what was already received", func(t *testing.T) {
+		t.Parallel()
+
+		rec := httptest.NewRecorder()
+		lines := newLogLineWriter(rec, rec, vtx)
+		require.NoError(t, lines.Write([]*progrock.VertexLog{
+			{Vertex: vtx.String(), Stream: progrock.LogStream_STDOUT, Data: []byte("hello\ndo")},
+			// logs of other vertices, e.g. the container's service, don't
+			// count towards the exec's output
+			{Vertex: "svc", Stream: progrock.LogStream_STDERR, Data: []byte("listening\n")},
+		}))
+		require.NoError(t, lines.Replay(output))
+		require.NoError(t, lines.Flush())
+
+		require.Equal(t, []engine.LogMessage{
+			{Stream: "stdout", Line: "hello"},
+			{Stream: "stderr", Line: "listening"},
+			{Stream: "stderr", Line: "goodbye"},
+			{Stream: "stdout", Line: "done"},
+		}, readLines(t, rec))
+	})
+}
@@ -35,6 +35,7 @@ type DaggerServer struct {
 	schema      *schema.MergedSchemas
 	recorder    *progrock.Recorder
 	progCleanup func() error
+	logs        *logTap
 
 	doneCh    chan struct{}
 	closeOnce sync.Once
@@ -58,6 +59,7 @@ func NewDaggerServer(
 		bkClient: bkClient,
 		worker:   worker,
 		doneCh:   make(chan struct{}, 1),
+		logs:     newLogTap(),
 	}
 
 	clientConn := caller.Conn()
@@ -71,6 +73,7 @@ func NewDaggerServer(
 	progWriter, progCleanup, err := buildkit.ProgrockForwarder(progSockPath, progrock.MultiWriter{
 		progrock.NewRPCWriter(clientConn, progUpdates),
 		buildkit.ProgrockLogrusWriter{},
+		srv.logs,
 	})
 	if err != nil {
 		return nil, err
@@ -231,6 +234,9 @@ func (srv *DaggerServer) HTTPHandlerForClient(clientMetadata *engine.ClientMetad
 		mux.Handle("/query", NewHandler(&HandlerConfig{
 			Schema: srv.schema.Schema(),
 		}))
+		mux.Handle("/logs", http.HandlerFunc(srv.serveLogs))
+		mux.Handle("/debug/terminals", http.HandlerFunc(srv.serveDebugTerminals))
+		mux.Handle("/terminals/", http.HandlerFunc(srv.serveTerminal))
 		mux.Handle("/shutdown", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 			ctx := req.Context()
 			bklog.G(ctx).Debugf("shutting down client %s", clientMetadata.ClientID)
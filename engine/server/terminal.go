@@ -0,0 +1,134 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dagger/dagger/engine"
+	bkgw "github.com/moby/buildkit/frontend/gateway/client"
+	"github.com/moby/buildkit/util/bklog"
+	"golang.org/x/net/websocket"
+)
+
+// serveDebugTerminals streams a newline-delimited JSON engine.DebugTerminal
+// for every exec that fails while the request is open.
+func (srv *DaggerServer) serveDebugTerminals(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	ctx := req.Context()
+	terms := srv.bkClient.WatchDebugTerminals(ctx)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case term := <-terms:
+			if err := enc.Encode(term); err != nil {
+				bklog.G(ctx).WithError(err).Debug("failed to send debug terminal")
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// serveTerminal attaches a websocket to the terminal at /terminals/<id>.
+func (srv *DaggerServer) serveTerminal(w http.ResponseWriter, req *http.Request) {
+	id := strings.TrimPrefix(req.URL.Path, "/terminals/")
+	term, ok := srv.bkClient.Terminal(id)
+	if !ok {
+		http.Error(w, fmt.Sprintf("terminal %q not found", id), http.StatusNotFound)
+		return
+	}
+
+	websocket.Server{
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+			defer term.Close()
+
+			ctx := req.Context()
+
+			stdinR, stdinW := io.Pipe()
+			resize := make(chan bkgw.WinSize, 1)
+			go func() {
+				defer close(resize)
+				for {
+					var msg []byte
+					if err := websocket.Message.Receive(ws, &msg); err != nil {
+						stdinW.CloseWithError(err)
+						return
+					}
+					if len(msg) == 0 {
+						continue
+					}
+					switch msg[0] {
+					case engine.TerminalStdin:
+						if _, err := stdinW.Write(msg[1:]); err != nil {
+							return
+						}
+					case engine.TerminalResize:
+						var size engine.TerminalSize
+						if err := json.Unmarshal(msg[1:], &size); err != nil {
+							bklog.G(ctx).WithError(err).Debug("invalid terminal resize")
+							continue
+						}
+						select {
+						case resize <- bkgw.WinSize{Rows: uint32(size.Height), Cols: uint32(size.Width)}:
+						case <-ctx.Done():
+							return
+						}
+					}
+				}
+			}()
+
+			exitCode, err := term.Attach(ctx,
+				stdinR,
+				&terminalWriter{ws: ws, channel: engine.TerminalStdout},
+				&terminalWriter{ws: ws, channel: engine.TerminalStderr},
+				resize,
+			)
+			if err != nil {
+				bklog.G(ctx).WithError(err).Error("terminal failed")
+				fmt.Fprintf(&terminalWriter{ws: ws, channel: engine.TerminalStderr}, "%s\r\n", err)
+			}
+
+			exitMsg := append([]byte{engine.TerminalExit}, strconv.Itoa(exitCode)...)
+			if err := websocket.Message.Send(ws, exitMsg); err != nil && !errors.Is(err, io.EOF) {
+				bklog.G(ctx).WithError(err).Debug("failed to send terminal exit code")
+			}
+		},
+	}.ServeHTTP(w, req)
+}
+
+// terminalWriter sends everything written to it over a terminal websocket
+// on the given channel.
+type terminalWriter struct {
+	ws      *websocket.Conn
+	channel byte
+}
+
+func (w *terminalWriter) Write(p []byte) (int, error) {
+	msg := append([]byte{w.channel}, p...)
+	if err := websocket.Message.Send(w.ws, msg); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *terminalWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,36 @@
+package engine
+
+// Every message sent over a terminal websocket is prefixed by one of these
+// channel bytes, loosely following Kubernetes' channel.k8s.io protocol.
+const (
+	// TerminalStdin carries input from the client to the process.
+	TerminalStdin byte = iota
+	// TerminalStdout carries output from the process to the client.
+	TerminalStdout
+	// TerminalStderr carries error output from the process to the client.
+	TerminalStderr
+	// TerminalResize carries a JSON encoded TerminalSize from the client.
+	TerminalResize
+	// TerminalExit carries the decimal exit code of the process once it has
+	// exited. It is always the last message sent by the server.
+	TerminalExit
+)
+
+// TerminalSize is sent by the client whenever its terminal changes size.
+type TerminalSize struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// DebugTerminal is sent to clients watching for failed execs when a debug
+// terminal has been opened into the state of the failed exec.
+type DebugTerminal struct {
+	// ID of the terminal, used to attach to it at /terminals/<id>.
+	ID string `json:"id"`
+
+	// Cmd is the command of the exec that failed.
+	Cmd []string `json:"cmd"`
+
+	// ExitCode is the exit code of the exec that failed.
+	ExitCode int `json:"exit_code"`
+}
@@ -49,9 +49,15 @@ type ProjectID string
 // A unique identifier for a secret.
 type SecretID string
 
+// A unique service identifier.
+type ServiceID string
+
 // A content-addressed socket identifier.
 type SocketID string
 
+// A unique identifier for a terminal.
+type TerminalID string
+
 // Key value object that represents a build argument.
 type BuildArg struct {
 	// The build argument name.
@@ -61,6 +67,18 @@ type BuildArg struct {
 	Value string `json:"value"`
 }
 
+// A named build context of a Dockerfile build.
+type BuildContext struct {
+	// The directory to use as the context.
+	Directory *Directory `json:"directory"`
+
+	// The image reference to use as the context.
+	Image string `json:"image"`
+
+	// The name of the context, as referenced in the Dockerfile.
+	Name string `json:"name"`
+}
+
 // Key value object that represents a Pipeline label.
 type PipelineLabel struct {
 	// Label name.
@@ -70,6 +88,44 @@ type PipelineLabel struct {
 	Value string `json:"value"`
 }
 
+// Port forwarding rules for tunneling network traffic.
+type PortForward struct {
+	// The port to forward connections to.
+	Backend int `json:"backend"`
+
+	// The port to listen on. If 0 or unset, a random port is chosen.
+	Frontend int `json:"frontend"`
+
+	// The transport layer network protocol to use.
+	Protocol NetworkProtocol `json:"protocol"`
+}
+
+// Limits on the resources available to a command.
+//
+// A command that exceeds its memory, pids or timeout limit fails, whatever its expected return type.
+type ResourceLimits struct {
+	// Maximum memory available to the command, in bytes or with a unit suffix (e.g., "512MiB", "2g").
+	Memory string `json:"memory"`
+
+	// CPU time available to the command, in thousandths of a CPU (e.g., 1500 for 1.5 CPUs).
+	MilliCPUs int `json:"milliCPUs"`
+
+	// Maximum number of processes the command can run at once.
+	Pids int `json:"pids"`
+
+	// Number of seconds after which the command is killed.
+	Timeout int `json:"timeout"`
+}
+
+// Key value object that represents a variable available to a file template.
+type TemplateVariable struct {
+	// The variable name.
+	Name string `json:"name"`
+
+	// The variable value.
+	Value string `json:"value"`
+}
+
 // A directory whose contents persist across runs.
 type CacheVolume struct {
 	q *querybuilder.Selection
@@ -109,6 +165,71 @@ func (r *CacheVolume) XXX_GraphQLID(ctx context.Context) (string, error) {
 	return string(id), nil
 }
 
+// A change to a file or subdirectory between two directories.
+type Change struct {
+	q *querybuilder.Selection
+	c graphql.Client
+
+	afterDigest  *string
+	beforeDigest *string
+	kind         *ChangeKind
+	path         *string
+}
+
+// The digest of the file or subdirectory after the change, unless it was
+// deleted. It's the same as its digest with ignoreTimestamps set.
+func (r *Change) AfterDigest(ctx context.Context) (string, error) {
+	if r.afterDigest != nil {
+		return *r.afterDigest, nil
+	}
+	q := r.q.Select("afterDigest")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The digest of the file or subdirectory before the change, unless it was
+// added. It's the same as its digest with ignoreTimestamps set.
+func (r *Change) BeforeDigest(ctx context.Context) (string, error) {
+	if r.beforeDigest != nil {
+		return *r.beforeDigest, nil
+	}
+	q := r.q.Select("beforeDigest")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The kind of change.
+func (r *Change) Kind(ctx context.Context) (ChangeKind, error) {
+	if r.kind != nil {
+		return *r.kind, nil
+	}
+	q := r.q.Select("kind")
+
+	var response ChangeKind
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The path of the file or subdirectory, relative to the directories compared.
+func (r *Change) Path(ctx context.Context) (string, error) {
+	if r.path != nil {
+		return *r.path, nil
+	}
+	q := r.q.Select("path")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
 // An OCI-compatible container, also known as a docker container.
 type Container struct {
 	q *querybuilder.Selection
@@ -116,6 +237,7 @@ type Container struct {
 
 	endpoint    *string
 	envVariable *string
+	exitCode    *int
 	export      *bool
 	hostname    *string
 	id          *ContainerID
@@ -125,6 +247,7 @@ type Container struct {
 	publish     *string
 	stderr      *string
 	stdout      *string
+	stopSignal  *string
 	sync        *ContainerID
 	user        *string
 	workdir     *string
@@ -138,6 +261,73 @@ func (r *Container) With(f WithContainerFunc) *Container {
 	return f(r)
 }
 
+// ContainerAsOCILayoutOpts contains options for Container.AsOCILayout
+type ContainerAsOCILayoutOpts struct {
+	// Identifiers for other platform specific containers.
+	// Used for multi-platform image.
+	PlatformVariants []*Container
+	// Force each layer of the image to use the specified compression algorithm.
+	// If this is unset, then if a layer already has a compressed blob in the engine's
+	// cache, that will be used (this can result in a mix of compression algorithms for
+	// different layers). If this is unset and a layer has no compressed blob in the
+	// engine's cache, then it will be compressed using Gzip.
+	ForcedCompression ImageLayerCompression
+	// Use the specified media types for the image's layers. Defaults to OCI, which
+	// is largely compatible with most recent container runtimes, but Docker may be needed
+	// for older runtimes without OCI support.
+	MediaTypes ImageMediaTypes
+	// Attach an SLSA provenance attestation recording the pipeline that built the
+	// image, its platform, base image and any build arguments.
+	Provenance bool
+	// Scan the image and attach the resulting SPDX SBOM as an attestation.
+	Sbom bool
+}
+
+// Returns the container as an OCI image layout directory, with the same
+// contents as the tarball written by export, for the specified platform variants.
+func (r *Container) AsOCILayout(opts ...ContainerAsOCILayoutOpts) *Directory {
+	q := r.q.Select("asOCILayout")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `platformVariants` optional argument
+		if !querybuilder.IsZeroValue(opts[i].PlatformVariants) {
+			q = q.Arg("platformVariants", opts[i].PlatformVariants)
+		}
+		// `forcedCompression` optional argument
+		if !querybuilder.IsZeroValue(opts[i].ForcedCompression) {
+			q = q.Arg("forcedCompression", opts[i].ForcedCompression)
+		}
+		// `mediaTypes` optional argument
+		if !querybuilder.IsZeroValue(opts[i].MediaTypes) {
+			q = q.Arg("mediaTypes", opts[i].MediaTypes)
+		}
+		// `provenance` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Provenance) {
+			q = q.Arg("provenance", opts[i].Provenance)
+		}
+		// `sbom` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Sbom) {
+			q = q.Arg("sbom", opts[i].Sbom)
+		}
+	}
+
+	return &Directory{
+		q: q,
+		c: r.c,
+	}
+}
+
+// Turns the container into a service.
+//
+// Its default command is run if it has not been given one with withExec.
+func (r *Container) AsService() *Service {
+	q := r.q.Select("asService")
+
+	return &Service{
+		q: q,
+		c: r.c,
+	}
+}
+
 // ContainerBuildOpts contains options for Container.Build
 type ContainerBuildOpts struct {
 	// Path to the Dockerfile to use.
@@ -156,6 +346,20 @@ type ContainerBuildOpts struct {
 	// and mount path /run/secrets/[secret-name]
 	// e.g. RUN --mount=type=secret,id=my-secret curl url?token=$(cat /run/secrets/my-secret)"
 	Secrets []*Secret
+	// Additional named build contexts, available to the Dockerfile as
+	// stage names (e.g., COPY --from=[name]).
+	BuildContexts []BuildContext
+	// Socket to forward to the build.
+	//
+	// It is used for every SSH mount in the Dockerfile
+	// (e.g., RUN --mount=type=ssh git clone git@github.com:org/repo).
+	SSH *Socket
+	// Image references to import build cache from.
+	CacheFrom []string
+	// Network mode of the build's RUN instructions: "default", "none" or
+	// "host". The host network requires the engine's network.host
+	// entitlement.
+	Network string
 }
 
 // Initializes this container from a Dockerfile build.
@@ -179,6 +383,22 @@ func (r *Container) Build(context *Directory, opts ...ContainerBuildOpts) *Conta
 		if !querybuilder.IsZeroValue(opts[i].Secrets) {
 			q = q.Arg("secrets", opts[i].Secrets)
 		}
+		// `buildContexts` optional argument
+		if !querybuilder.IsZeroValue(opts[i].BuildContexts) {
+			q = q.Arg("buildContexts", opts[i].BuildContexts)
+		}
+		// `ssh` optional argument
+		if !querybuilder.IsZeroValue(opts[i].SSH) {
+			q = q.Arg("ssh", opts[i].SSH)
+		}
+		// `cacheFrom` optional argument
+		if !querybuilder.IsZeroValue(opts[i].CacheFrom) {
+			q = q.Arg("cacheFrom", opts[i].CacheFrom)
+		}
+		// `network` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Network) {
+			q = q.Arg("network", opts[i].Network)
+		}
 	}
 	q = q.Arg("context", context)
 
@@ -304,6 +524,24 @@ func (r *Container) EnvVariables(ctx context.Context) ([]EnvVariable, error) {
 	return convert(response), nil
 }
 
+// The exit code of the last executed command.
+//
+// With an expect of FAILURE or ANY, this is the code the command exited with,
+// even though the exec succeeded.
+//
+// Will execute default command if none is set, or error if there's no default.
+func (r *Container) ExitCode(ctx context.Context) (int, error) {
+	if r.exitCode != nil {
+		return *r.exitCode, nil
+	}
+	q := r.q.Select("exitCode")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
 // ContainerExportOpts contains options for Container.Export
 type ContainerExportOpts struct {
 	// Identifiers for other platform specific containers.
@@ -319,6 +557,11 @@ type ContainerExportOpts struct {
 	// is largely compatible with most recent container runtimes, but Docker may be needed
 	// for older runtimes without OCI support.
 	MediaTypes ImageMediaTypes
+	// Attach an SLSA provenance attestation recording the pipeline that built the
+	// exported image, its platform, base image and any build arguments.
+	Provenance bool
+	// Scan the exported image and attach the resulting SPDX SBOM as an attestation.
+	Sbom bool
 }
 
 // Writes the container as an OCI tarball to the destination file path on the host for the specified platform variants.
@@ -343,6 +586,14 @@ func (r *Container) Export(ctx context.Context, path string, opts ...ContainerEx
 		if !querybuilder.IsZeroValue(opts[i].MediaTypes) {
 			q = q.Arg("mediaTypes", opts[i].MediaTypes)
 		}
+		// `provenance` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Provenance) {
+			q = q.Arg("provenance", opts[i].Provenance)
+		}
+		// `sbom` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Sbom) {
+			q = q.Arg("sbom", opts[i].Sbom)
+		}
 	}
 	q = q.Arg("path", path)
 
@@ -403,9 +654,24 @@ func (r *Container) File(path string) *File {
 	}
 }
 
+// ContainerFromOpts contains options for Container.From
+type ContainerFromOpts struct {
+	// When to contact the registry to resolve the address.
+	//
+	// Defaults to resolving it every time, reusing the layers the engine
+	// already has.
+	PullPolicy ImagePullPolicy
+}
+
 // Initializes this container from a pulled base image.
-func (r *Container) From(address string) *Container {
+func (r *Container) From(address string, opts ...ContainerFromOpts) *Container {
 	q := r.q.Select("from")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `pullPolicy` optional argument
+		if !querybuilder.IsZeroValue(opts[i].PullPolicy) {
+			q = q.Arg("pullPolicy", opts[i].PullPolicy)
+		}
+	}
 	q = q.Arg("address", address)
 
 	return &Container{
@@ -414,6 +680,53 @@ func (r *Container) From(address string) *Container {
 	}
 }
 
+// Retrieves the command that checks whether the container is healthy, if any.
+func (r *Container) Healthcheck() *Healthcheck {
+	q := r.q.Select("healthcheck")
+
+	return &Healthcheck{
+		q: q,
+		c: r.c,
+	}
+}
+
+// Retrieves the history of the container's image, as recorded in its config.
+//
+// Publishing or exporting the container extends it with the layers added since.
+func (r *Container) History(ctx context.Context) ([]ImageHistory, error) {
+	q := r.q.Select("history")
+
+	q = q.Select("author comment created createdBy emptyLayer")
+
+	type history struct {
+		Author     string
+		Comment    string
+		Created    string
+		CreatedBy  string
+		EmptyLayer bool
+	}
+
+	convert := func(fields []history) []ImageHistory {
+		out := []ImageHistory{}
+
+		for i := range fields {
+			out = append(out, ImageHistory{author: &fields[i].Author, comment: &fields[i].Comment, created: &fields[i].Created, createdBy: &fields[i].CreatedBy, emptyLayer: &fields[i].EmptyLayer})
+		}
+
+		return out
+	}
+	var response []history
+
+	q = q.Bind(&response)
+
+	err := q.Execute(ctx, r.c)
+	if err != nil {
+		return nil, err
+	}
+
+	return convert(response), nil
+}
+
 // Retrieves a hostname which can be used by clients to reach this container.
 //
 // Currently experimental; set _EXPERIMENTAL_DAGGER_SERVICES_DNS=0 to disable.
@@ -548,6 +861,41 @@ func (r *Container) Labels(ctx context.Context) ([]Label, error) {
 	return convert(response), nil
 }
 
+// Retrieves the layers of the image the container was pulled or imported from.
+//
+// Returns no layers if the container's filesystem has changed since.
+func (r *Container) Layers(ctx context.Context) ([]ImageLayer, error) {
+	q := r.q.Select("layers")
+
+	q = q.Select("digest mediaType size")
+
+	type layers struct {
+		Digest    string
+		MediaType string
+		Size      int
+	}
+
+	convert := func(fields []layers) []ImageLayer {
+		out := []ImageLayer{}
+
+		for i := range fields {
+			out = append(out, ImageLayer{digest: &fields[i].Digest, mediaType: &fields[i].MediaType, size: &fields[i].Size})
+		}
+
+		return out
+	}
+	var response []layers
+
+	q = q.Bind(&response)
+
+	err := q.Execute(ctx, r.c)
+	if err != nil {
+		return nil, err
+	}
+
+	return convert(response), nil
+}
+
 // Retrieves the list of paths where a directory is mounted.
 func (r *Container) Mounts(ctx context.Context) ([]string, error) {
 	q := r.q.Select("mounts")
@@ -558,6 +906,16 @@ func (r *Container) Mounts(ctx context.Context) ([]string, error) {
 	return response, q.Execute(ctx, r.c)
 }
 
+// Retrieves the ONBUILD triggers of the container's image.
+func (r *Container) OnBuild(ctx context.Context) ([]string, error) {
+	q := r.q.Select("onBuild")
+
+	var response []string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
 // ContainerPipelineOpts contains options for Container.Pipeline
 type ContainerPipelineOpts struct {
 	// Pipeline description.
@@ -615,6 +973,19 @@ type ContainerPublishOpts struct {
 	// is largely compatible with most recent registries, but Docker may be needed for older
 	// registries without OCI support.
 	MediaTypes ImageMediaTypes
+	// Attach an SLSA provenance attestation recording the pipeline that built the
+	// published image, its platform, base image and any build arguments.
+	Provenance bool
+	// Scan the published image and attach the resulting SPDX SBOM as an attestation.
+	Sbom bool
+	// Sign the published image with this PEM-encoded private key, pushing a
+	// cosign-compatible signature to the same repository.
+	//
+	// Keys generated by cosign are supported, as are unencrypted PKCS #8, EC and
+	// RSA keys.
+	SigningKey *Secret
+	// The password that the signing key is encrypted with, if any.
+	SigningPassword *Secret
 }
 
 // Publishes this container as a new image to the specified address.
@@ -639,6 +1010,22 @@ func (r *Container) Publish(ctx context.Context, address string, opts ...Contain
 		if !querybuilder.IsZeroValue(opts[i].MediaTypes) {
 			q = q.Arg("mediaTypes", opts[i].MediaTypes)
 		}
+		// `provenance` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Provenance) {
+			q = q.Arg("provenance", opts[i].Provenance)
+		}
+		// `sbom` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Sbom) {
+			q = q.Arg("sbom", opts[i].Sbom)
+		}
+		// `signingKey` optional argument
+		if !querybuilder.IsZeroValue(opts[i].SigningKey) {
+			q = q.Arg("signingKey", opts[i].SigningKey)
+		}
+		// `signingPassword` optional argument
+		if !querybuilder.IsZeroValue(opts[i].SigningPassword) {
+			q = q.Arg("signingPassword", opts[i].SigningPassword)
+		}
 	}
 	q = q.Arg("address", address)
 
@@ -648,6 +1035,97 @@ func (r *Container) Publish(ctx context.Context, address string, opts ...Contain
 	return response, q.Execute(ctx, r.c)
 }
 
+// ContainerPublishAllOpts contains options for Container.PublishAll
+type ContainerPublishAllOpts struct {
+	// Identifiers for other platform specific containers.
+	// Used for multi-platform image.
+	PlatformVariants []*Container
+	// Force each layer of the published image to use the specified compression algorithm.
+	// See publish for details.
+	ForcedCompression ImageLayerCompression
+	// Use the specified media types for the published image's layers. Defaults to OCI.
+	MediaTypes ImageMediaTypes
+	// Attach an SLSA provenance attestation to the published image.
+	Provenance bool
+	// Scan the published image and attach the resulting SPDX SBOM as an attestation.
+	Sbom bool
+	// Sign the published image with this PEM-encoded private key, pushing a
+	// cosign-compatible signature to each repository.
+	SigningKey *Secret
+	// The password that the signing key is encrypted with, if any.
+	SigningPassword *Secret
+}
+
+// Publishes this container as a new image to each of the specified addresses,
+// building it only once.
+//
+// Returns the outcome for each address, in order. Publishing isn't atomic: if
+// publishing to some addresses fails, the image is still published to the
+// others, and each failure is reported in the error of its result. It only
+// fails if the image couldn't be published to any address.
+func (r *Container) PublishAll(ctx context.Context, addresses []string, opts ...ContainerPublishAllOpts) ([]PublishResult, error) {
+	q := r.q.Select("publishAll")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `platformVariants` optional argument
+		if !querybuilder.IsZeroValue(opts[i].PlatformVariants) {
+			q = q.Arg("platformVariants", opts[i].PlatformVariants)
+		}
+		// `forcedCompression` optional argument
+		if !querybuilder.IsZeroValue(opts[i].ForcedCompression) {
+			q = q.Arg("forcedCompression", opts[i].ForcedCompression)
+		}
+		// `mediaTypes` optional argument
+		if !querybuilder.IsZeroValue(opts[i].MediaTypes) {
+			q = q.Arg("mediaTypes", opts[i].MediaTypes)
+		}
+		// `provenance` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Provenance) {
+			q = q.Arg("provenance", opts[i].Provenance)
+		}
+		// `sbom` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Sbom) {
+			q = q.Arg("sbom", opts[i].Sbom)
+		}
+		// `signingKey` optional argument
+		if !querybuilder.IsZeroValue(opts[i].SigningKey) {
+			q = q.Arg("signingKey", opts[i].SigningKey)
+		}
+		// `signingPassword` optional argument
+		if !querybuilder.IsZeroValue(opts[i].SigningPassword) {
+			q = q.Arg("signingPassword", opts[i].SigningPassword)
+		}
+	}
+	q = q.Arg("addresses", addresses)
+
+	q = q.Select("address digest error")
+
+	type publishAll struct {
+		Address string
+		Digest  string
+		Error   string
+	}
+
+	convert := func(fields []publishAll) []PublishResult {
+		out := []PublishResult{}
+
+		for i := range fields {
+			out = append(out, PublishResult{address: &fields[i].Address, digest: &fields[i].Digest, error: &fields[i].Error})
+		}
+
+		return out
+	}
+	var response []publishAll
+
+	q = q.Bind(&response)
+
+	err := q.Execute(ctx, r.c)
+	if err != nil {
+		return nil, err
+	}
+
+	return convert(response), nil
+}
+
 // Retrieves this container's root filesystem. Mounts are not included.
 func (r *Container) Rootfs() *Directory {
 	q := r.q.Select("rootfs")
@@ -658,6 +1136,29 @@ func (r *Container) Rootfs() *Directory {
 	}
 }
 
+// Retrieves the shell used by shell-form commands of Dockerfiles built from the image.
+func (r *Container) Shell(ctx context.Context) ([]string, error) {
+	q := r.q.Select("shell")
+
+	var response []string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// Retrieves this container with its root filesystem collapsed into a single
+// layer, including the layers of the image it was created from.
+//
+// Each operation that led to the filesystem is still cached on its own.
+func (r *Container) Squash() *Container {
+	q := r.q.Select("squash")
+
+	return &Container{
+		q: q,
+		c: r.c,
+	}
+}
+
 // The error stream of the last executed command.
 //
 // Will execute default command if none is set, or error if there's no default.
@@ -688,6 +1189,19 @@ func (r *Container) Stdout(ctx context.Context) (string, error) {
 	return response, q.Execute(ctx, r.c)
 }
 
+// Retrieves the signal sent to stop the container, if set (e.g., "SIGTERM").
+func (r *Container) StopSignal(ctx context.Context) (string, error) {
+	if r.stopSignal != nil {
+		return *r.stopSignal, nil
+	}
+	q := r.q.Select("stopSignal")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
 // Forces evaluation of the pipeline in the engine.
 //
 // It doesn't run the default command if no exec has been set.
@@ -697,6 +1211,32 @@ func (r *Container) Sync(ctx context.Context) (*Container, error) {
 	return r, q.Execute(ctx, r.c)
 }
 
+// ContainerTerminalOpts contains options for Container.Terminal
+type ContainerTerminalOpts struct {
+	// Command to run in the terminal (e.g., ["psql", "-h", "db"]).
+	//
+	// If empty, "sh" is used.
+	Cmd []string
+}
+
+// Starts an interactive terminal in this container that can be attached to over a websocket.
+//
+// The command runs with the same mounts, environment, secrets, sockets and service bindings as withExec.
+func (r *Container) Terminal(opts ...ContainerTerminalOpts) *Terminal {
+	q := r.q.Select("terminal")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `cmd` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Cmd) {
+			q = q.Arg("cmd", opts[i].Cmd)
+		}
+	}
+
+	return &Terminal{
+		q: q,
+		c: r.c,
+	}
+}
+
 // Retrieves the user to be set for all commands.
 func (r *Container) User(ctx context.Context) (string, error) {
 	if r.user != nil {
@@ -710,6 +1250,63 @@ func (r *Container) User(ctx context.Context) (string, error) {
 	return response, q.Execute(ctx, r.c)
 }
 
+// Verifies that the image this container was pulled from is signed with the
+// private key matching the given public key, as with `cosign verify`.
+//
+// Returns the container unchanged, or an error if no valid signature is found.
+func (r *Container) VerifySignature(publicKey *File) *Container {
+	assertNotNil("publicKey", publicKey)
+	q := r.q.Select("verifySignature")
+	q = q.Arg("publicKey", publicKey)
+
+	return &Container{
+		q: q,
+		c: r.c,
+	}
+}
+
+// Retrieves the paths declared as volumes in the container's image.
+func (r *Container) Volumes(ctx context.Context) ([]string, error) {
+	q := r.q.Select("volumes")
+
+	var response []string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// Retrieves this container with an in-toto attestation to attach to its image
+// when it's published or exported.
+func (r *Container) WithAttestation(predicateType string, predicate *File) *Container {
+	assertNotNil("predicate", predicate)
+	q := r.q.Select("withAttestation")
+	q = q.Arg("predicateType", predicateType)
+	q = q.Arg("predicate", predicate)
+
+	return &Container{
+		q: q,
+		c: r.c,
+	}
+}
+
+// Establish a runtime dependency on a service, such as one from
+// Container.asService or Host.service.
+//
+// Like withServiceBinding, the service will be started automatically when
+// needed and detached when it is no longer needed, and will be reachable from
+// the container via the provided hostname alias.
+func (r *Container) WithBoundService(alias string, service *Service) *Container {
+	assertNotNil("service", service)
+	q := r.q.Select("withBoundService")
+	q = q.Arg("alias", alias)
+	q = q.Arg("service", service)
+
+	return &Container{
+		q: q,
+		c: r.c,
+	}
+}
+
 // ContainerWithDefaultArgsOpts contains options for Container.WithDefaultArgs
 type ContainerWithDefaultArgsOpts struct {
 	// Arguments to prepend to future executions (e.g., ["-v", "--no-cache"]).
@@ -829,6 +1426,16 @@ type ContainerWithExecOpts struct {
 	// does not provide any security guarantees when using this option. It should only be used
 	// when absolutely necessary and only with trusted commands.
 	InsecureRootCapabilities bool
+	// Exit codes that the command may exit with without failing the exec (default SUCCESS).
+	//
+	// Use FAILURE or ANY along with exitCode to inspect commands that are expected to fail.
+	//
+	// If the exec fails because the command didn't exit as expected, e.g. it
+	// succeeded when FAILURE was expected, the exit code in the exec error is
+	// the one the exec failed with (1), not the command's.
+	Expect ReturnType
+	// Resource limits for the command, overriding any set with withResourceLimits.
+	Limits ResourceLimits
 }
 
 // Retrieves this container after executing the specified command inside it.
@@ -859,6 +1466,14 @@ func (r *Container) WithExec(args []string, opts ...ContainerWithExecOpts) *Cont
 		if !querybuilder.IsZeroValue(opts[i].InsecureRootCapabilities) {
 			q = q.Arg("insecureRootCapabilities", opts[i].InsecureRootCapabilities)
 		}
+		// `expect` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Expect) {
+			q = q.Arg("expect", opts[i].Expect)
+		}
+		// `limits` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Limits) {
+			q = q.Arg("limits", opts[i].Limits)
+		}
 	}
 	q = q.Arg("args", args)
 
@@ -951,6 +1566,57 @@ func (r *Container) WithFocus() *Container {
 	}
 }
 
+// ContainerWithHealthcheckOpts contains options for Container.WithHealthcheck
+type ContainerWithHealthcheckOpts struct {
+	// The time to wait between checks.
+	Interval string
+	// The time to wait before considering a check to have hung.
+	Timeout string
+	// The time to give the container to start before failed checks count.
+	StartPeriod string
+	// The time to wait between checks during the start period.
+	StartInterval string
+	// The number of consecutive failed checks after which the container is unhealthy.
+	Retries int
+}
+
+// Retrieves this container with the given healthcheck, as with Dockerfile's
+// HEALTHCHECK instruction.
+//
+// Durations are formatted like "30s" or "1m30s". Unset durations and retries
+// use the runtime's defaults.
+func (r *Container) WithHealthcheck(test []string, opts ...ContainerWithHealthcheckOpts) *Container {
+	q := r.q.Select("withHealthcheck")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `interval` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Interval) {
+			q = q.Arg("interval", opts[i].Interval)
+		}
+		// `timeout` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Timeout) {
+			q = q.Arg("timeout", opts[i].Timeout)
+		}
+		// `startPeriod` optional argument
+		if !querybuilder.IsZeroValue(opts[i].StartPeriod) {
+			q = q.Arg("startPeriod", opts[i].StartPeriod)
+		}
+		// `startInterval` optional argument
+		if !querybuilder.IsZeroValue(opts[i].StartInterval) {
+			q = q.Arg("startInterval", opts[i].StartInterval)
+		}
+		// `retries` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Retries) {
+			q = q.Arg("retries", opts[i].Retries)
+		}
+	}
+	q = q.Arg("test", test)
+
+	return &Container{
+		q: q,
+		c: r.c,
+	}
+}
+
 // Retrieves this container plus the given label.
 func (r *Container) WithLabel(name string, value string) *Container {
 	q := r.q.Select("withLabel")
@@ -963,6 +1629,21 @@ func (r *Container) WithLabel(name string, value string) *Container {
 	}
 }
 
+// Retrieves this container with the changes to its root filesystem since the
+// last layer boundary grouped into a single layer.
+//
+// The image the container was created from (e.g., with from, build or import)
+// is the first boundary, so its layers are kept as they are. Each operation
+// that led to the filesystem is still cached on its own.
+func (r *Container) WithLayerBoundary() *Container {
+	q := r.q.Select("withLayerBoundary")
+
+	return &Container{
+		q: q,
+		c: r.c,
+	}
+}
+
 // ContainerWithMountedCacheOpts contains options for Container.WithMountedCache
 type ContainerWithMountedCacheOpts struct {
 	// Identifier of the directory to use as the cache volume's root.
@@ -1156,7 +1837,25 @@ func (r *Container) WithNewFile(path string, opts ...ContainerWithNewFileOpts) *
 	}
 }
 
+// Retrieves this container with the given ONBUILD triggers, which Dockerfiles
+// using the image as a base run after their FROM instruction.
+func (r *Container) WithOnBuild(triggers []string) *Container {
+	q := r.q.Select("withOnBuild")
+	q = q.Arg("triggers", triggers)
+
+	return &Container{
+		q: q,
+		c: r.c,
+	}
+}
+
 // Retrieves this container with a registry authentication for a given address.
+//
+// It takes precedence over the credentials of the host's Docker config, which
+// are used for registries without one: those of ~/.docker/config.json and its
+// credential helpers, or with the CLI's --docker-config-auth flag, those of
+// DOCKER_AUTH_CONFIG, then ~/.docker/config.json and its credential helpers,
+// looked up per registry when needed.
 func (r *Container) WithRegistryAuth(address string, username string, secret *Secret) *Container {
 	assertNotNil("secret", secret)
 	q := r.q.Select("withRegistryAuth")
@@ -1170,6 +1869,19 @@ func (r *Container) WithRegistryAuth(address string, username string, secret *Se
 	}
 }
 
+// Retrieves this container with resource limits applied to every command executed in it, including when it's run as a service.
+//
+// Limits that aren't set are left as they were.
+func (r *Container) WithResourceLimits(limits ResourceLimits) *Container {
+	q := r.q.Select("withResourceLimits")
+	q = q.Arg("limits", limits)
+
+	return &Container{
+		q: q,
+		c: r.c,
+	}
+}
+
 // Initializes this container from this DirectoryID.
 func (r *Container) WithRootfs(directory *Directory) *Container {
 	assertNotNil("directory", directory)
@@ -1217,6 +1929,29 @@ func (r *Container) WithServiceBinding(alias string, service *Container) *Contai
 	}
 }
 
+// Retrieves this container with the given shell, as with Dockerfile's SHELL
+// instruction.
+func (r *Container) WithShell(args []string) *Container {
+	q := r.q.Select("withShell")
+	q = q.Arg("args", args)
+
+	return &Container{
+		q: q,
+		c: r.c,
+	}
+}
+
+// Retrieves this container with the given stop signal.
+func (r *Container) WithStopSignal(signal string) *Container {
+	q := r.q.Select("withStopSignal")
+	q = q.Arg("signal", signal)
+
+	return &Container{
+		q: q,
+		c: r.c,
+	}
+}
+
 // ContainerWithUnixSocketOpts contains options for Container.WithUnixSocket
 type ContainerWithUnixSocketOpts struct {
 	// A user:group to set for the mounted socket.
@@ -1257,6 +1992,18 @@ func (r *Container) WithUser(name string) *Container {
 	}
 }
 
+// Retrieves this container plus the given path declared as a volume, as with
+// Dockerfile's VOLUME instruction.
+func (r *Container) WithVolume(path string) *Container {
+	q := r.q.Select("withVolume")
+	q = q.Arg("path", path)
+
+	return &Container{
+		q: q,
+		c: r.c,
+	}
+}
+
 // Retrieves this container with a different working directory.
 func (r *Container) WithWorkdir(path string) *Container {
 	q := r.q.Select("withWorkdir")
@@ -1317,6 +2064,16 @@ func (r *Container) WithoutFocus() *Container {
 	}
 }
 
+// Retrieves this container without a healthcheck.
+func (r *Container) WithoutHealthcheck() *Container {
+	q := r.q.Select("withoutHealthcheck")
+
+	return &Container{
+		q: q,
+		c: r.c,
+	}
+}
+
 // Retrieves this container minus the given environment label.
 func (r *Container) WithoutLabel(name string) *Container {
 	q := r.q.Select("withoutLabel")
@@ -1361,6 +2118,17 @@ func (r *Container) WithoutUnixSocket(path string) *Container {
 	}
 }
 
+// Retrieves this container minus the given volume.
+func (r *Container) WithoutVolume(path string) *Container {
+	q := r.q.Select("withoutVolume")
+	q = q.Arg("path", path)
+
+	return &Container{
+		q: q,
+		c: r.c,
+	}
+}
+
 // Retrieves the working directory for all commands.
 func (r *Container) Workdir(ctx context.Context) (string, error) {
 	if r.workdir != nil {
@@ -1379,6 +2147,7 @@ type Directory struct {
 	q *querybuilder.Selection
 	c graphql.Client
 
+	digest *string
 	export *bool
 	id     *DirectoryID
 	sync   *DirectoryID
@@ -1392,6 +2161,87 @@ func (r *Directory) With(f WithDirectoryFunc) *Directory {
 	return f(r)
 }
 
+// DirectoryAsTarballOpts contains options for Directory.AsTarball
+type DirectoryAsTarballOpts struct {
+	// Compression to apply to the tarball. Defaults to uncompressed.
+	//
+	// EStarGZ is not supported.
+	Compression ImageLayerCompression
+}
+
+// Packs the contents of this directory into a tarball.
+//
+// The tarball is created in the engine, without needing an image with tar.
+func (r *Directory) AsTarball(opts ...DirectoryAsTarballOpts) *File {
+	q := r.q.Select("asTarball")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `compression` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Compression) {
+			q = q.Arg("compression", opts[i].Compression)
+		}
+	}
+
+	return &File{
+		q: q,
+		c: r.c,
+	}
+}
+
+// Packs the contents of this directory into a zip archive.
+//
+// The archive is created in the engine, without needing an image with zip.
+func (r *Directory) AsZip() *File {
+	q := r.q.Select("asZip")
+
+	return &File{
+		q: q,
+		c: r.c,
+	}
+}
+
+// Lists the files and subdirectories added, modified or deleted in another
+// directory relative to this one, sorted by path. Subdirectories' paths end
+// with a slash.
+//
+// Files are compared by their type, permissions, ownership and contents, and
+// symlinks by their targets, ignoring modification times. Subdirectories are
+// only modified when their own permissions or ownership change; changes to
+// what's in them are listed on their own.
+func (r *Directory) Changes(ctx context.Context, other *Directory) ([]Change, error) {
+	assertNotNil("other", other)
+	q := r.q.Select("changes")
+	q = q.Arg("other", other)
+
+	q = q.Select("afterDigest beforeDigest kind path")
+
+	type changes struct {
+		AfterDigest  string
+		BeforeDigest string
+		Kind         ChangeKind
+		Path         string
+	}
+
+	convert := func(fields []changes) []Change {
+		out := []Change{}
+
+		for i := range fields {
+			out = append(out, Change{afterDigest: &fields[i].AfterDigest, beforeDigest: &fields[i].BeforeDigest, kind: &fields[i].Kind, path: &fields[i].Path})
+		}
+
+		return out
+	}
+	var response []changes
+
+	q = q.Bind(&response)
+
+	err := q.Execute(ctx, r.c)
+	if err != nil {
+		return nil, err
+	}
+
+	return convert(response), nil
+}
+
 // Gets the difference between this directory and an another directory.
 func (r *Directory) Diff(other *Directory) *Directory {
 	assertNotNil("other", other)
@@ -1404,6 +2254,35 @@ func (r *Directory) Diff(other *Directory) *Directory {
 	}
 }
 
+// DirectoryDigestOpts contains options for Directory.Digest
+type DirectoryDigestOpts struct {
+	// Leave modification times out of the digest.
+	IgnoreTimestamps bool
+}
+
+// Computes a digest of the paths, contents, permissions and ownership of
+// everything in this directory, e.g. to key an external cache on.
+//
+// Unlike the ID, the digest only depends on the directory's contents, not on
+// how they were produced.
+func (r *Directory) Digest(ctx context.Context, opts ...DirectoryDigestOpts) (string, error) {
+	if r.digest != nil {
+		return *r.digest, nil
+	}
+	q := r.q.Select("digest")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `ignoreTimestamps` optional argument
+		if !querybuilder.IsZeroValue(opts[i].IgnoreTimestamps) {
+			q = q.Arg("ignoreTimestamps", opts[i].IgnoreTimestamps)
+		}
+	}
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
 // Retrieves a directory at the given path.
 func (r *Directory) Directory(path string) *Directory {
 	q := r.q.Select("directory")
@@ -1431,6 +2310,20 @@ type DirectoryDockerBuildOpts struct {
 	//
 	// They will be mounted at /run/secrets/[secret-name].
 	Secrets []*Secret
+	// Additional named build contexts, available to the Dockerfile as
+	// stage names (e.g., COPY --from=[name]).
+	BuildContexts []BuildContext
+	// Socket to forward to the build.
+	//
+	// It is used for every SSH mount in the Dockerfile
+	// (e.g., RUN --mount=type=ssh git clone git@github.com:org/repo).
+	SSH *Socket
+	// Image references to import build cache from.
+	CacheFrom []string
+	// Network mode of the build's RUN instructions: "default", "none" or
+	// "host". The host network requires the engine's network.host
+	// entitlement.
+	Network string
 }
 
 // Builds a new Docker container from this directory.
@@ -1457,6 +2350,22 @@ func (r *Directory) DockerBuild(opts ...DirectoryDockerBuildOpts) *Container {
 		if !querybuilder.IsZeroValue(opts[i].Secrets) {
 			q = q.Arg("secrets", opts[i].Secrets)
 		}
+		// `buildContexts` optional argument
+		if !querybuilder.IsZeroValue(opts[i].BuildContexts) {
+			q = q.Arg("buildContexts", opts[i].BuildContexts)
+		}
+		// `ssh` optional argument
+		if !querybuilder.IsZeroValue(opts[i].SSH) {
+			q = q.Arg("ssh", opts[i].SSH)
+		}
+		// `cacheFrom` optional argument
+		if !querybuilder.IsZeroValue(opts[i].CacheFrom) {
+			q = q.Arg("cacheFrom", opts[i].CacheFrom)
+		}
+		// `network` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Network) {
+			q = q.Arg("network", opts[i].Network)
+		}
 	}
 
 	return &Container{
@@ -1512,6 +2421,19 @@ func (r *Directory) File(path string) *File {
 	}
 }
 
+// Returns a list of files and directories that match the given pattern, relative to this directory.
+//
+// Each path segment of the pattern is matched like a shell glob, and "**" matches any number of directories (e.g., "**/*.go").
+func (r *Directory) Glob(ctx context.Context, pattern string) ([]string, error) {
+	q := r.q.Select("glob")
+	q = q.Arg("pattern", pattern)
+
+	var response []string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
 // The content-addressed identifier of the directory.
 func (r *Directory) ID(ctx context.Context) (DirectoryID, error) {
 	if r.id != nil {
@@ -1573,6 +2495,17 @@ func (r *Directory) Pipeline(name string, opts ...DirectoryPipelineOpts) *Direct
 	}
 }
 
+// Retrieves the metadata of the file or directory at the given path.
+func (r *Directory) Stat(path string) *Stat {
+	q := r.q.Select("stat")
+	q = q.Arg("path", path)
+
+	return &Stat{
+		q: q,
+		c: r.c,
+	}
+}
+
 // Force evaluation in the engine.
 func (r *Directory) Sync(ctx context.Context) (*Directory, error) {
 	q := r.q.Select("sync")
@@ -1689,6 +2622,21 @@ func (r *Directory) WithNewFile(path string, contents string, opts ...DirectoryW
 	}
 }
 
+// Retrieves this directory with a patch applied.
+//
+// The patch is a unified diff, as output by diff -u or git diff. Each hunk must
+// apply cleanly, though it may be offset from the line it expects.
+func (r *Directory) WithPatch(patch *File) *Directory {
+	assertNotNil("patch", patch)
+	q := r.q.Select("withPatch")
+	q = q.Arg("patch", patch)
+
+	return &Directory{
+		q: q,
+		c: r.c,
+	}
+}
+
 // Retrieves this directory with all file/dir timestamps set to the given time.
 func (r *Directory) WithTimestamps(timestamp int) *Directory {
 	q := r.q.Select("withTimestamps")
@@ -1763,6 +2711,7 @@ type File struct {
 	c graphql.Client
 
 	contents *string
+	digest   *string
 	export   *bool
 	id       *FileID
 	size     *int
@@ -1790,6 +2739,35 @@ func (r *File) Contents(ctx context.Context) (string, error) {
 	return response, q.Execute(ctx, r.c)
 }
 
+// FileDigestOpts contains options for File.Digest
+type FileDigestOpts struct {
+	// Leave the file's modification time out of the digest.
+	IgnoreTimestamps bool
+}
+
+// Computes a digest of the file's contents, permissions and ownership, e.g. to
+// key an external cache on.
+//
+// Unlike the ID, the digest only depends on the file itself, not on how it
+// was produced.
+func (r *File) Digest(ctx context.Context, opts ...FileDigestOpts) (string, error) {
+	if r.digest != nil {
+		return *r.digest, nil
+	}
+	q := r.q.Select("digest")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `ignoreTimestamps` optional argument
+		if !querybuilder.IsZeroValue(opts[i].IgnoreTimestamps) {
+			q = q.Arg("ignoreTimestamps", opts[i].IgnoreTimestamps)
+		}
+	}
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
 // FileExportOpts contains options for File.Export
 type FileExportOpts struct {
 	// If allowParentDirPath is true, the path argument can be a directory path, in which case
@@ -1862,6 +2840,16 @@ func (r *File) Size(ctx context.Context) (int, error) {
 	return response, q.Execute(ctx, r.c)
 }
 
+// Retrieves the metadata of the file.
+func (r *File) Stat() *Stat {
+	q := r.q.Select("stat")
+
+	return &Stat{
+		q: q,
+		c: r.c,
+	}
+}
+
 // Force evaluation in the engine.
 func (r *File) Sync(ctx context.Context) (*File, error) {
 	q := r.q.Select("sync")
@@ -1869,26 +2857,66 @@ func (r *File) Sync(ctx context.Context) (*File, error) {
 	return r, q.Execute(ctx, r.c)
 }
 
-// Retrieves this file with its created/modified timestamps set to the given time.
-func (r *File) WithTimestamps(timestamp int) *File {
-	q := r.q.Select("withTimestamps")
-	q = q.Arg("timestamp", timestamp)
+// Unpacks this file, which must be a tarball (optionally compressed with gzip
+// or zstd) or a zip archive, into a directory.
+//
+// The format is detected from the file's contents, and the archive is unpacked
+// in the engine, without needing an image with tar or unzip.
+func (r *File) Unpack() *Directory {
+	q := r.q.Select("unpack")
 
-	return &File{
+	return &Directory{
 		q: q,
 		c: r.c,
 	}
 }
 
-// A git ref (tag, branch or commit).
-type GitRef struct {
-	q *querybuilder.Selection
-	c graphql.Client
+// Retrieves this file with every occurrence of a string in its contents replaced.
+func (r *File) WithReplaced(search string, replacement string) *File {
+	q := r.q.Select("withReplaced")
+	q = q.Arg("search", search)
+	q = q.Arg("replacement", replacement)
+
+	return &File{
+		q: q,
+		c: r.c,
+	}
 }
 
-// GitRefTreeOpts contains options for GitRef.Tree
-type GitRefTreeOpts struct {
-	SSHKnownHosts string
+// Retrieves this file with its contents rendered as a Go template.
+//
+// Each variable is available by name, e.g. {{.version}}. Referencing a variable
+// that isn't set is an error.
+func (r *File) WithTemplate(vars []TemplateVariable) *File {
+	q := r.q.Select("withTemplate")
+	q = q.Arg("vars", vars)
+
+	return &File{
+		q: q,
+		c: r.c,
+	}
+}
+
+// Retrieves this file with its created/modified timestamps set to the given time.
+func (r *File) WithTimestamps(timestamp int) *File {
+	q := r.q.Select("withTimestamps")
+	q = q.Arg("timestamp", timestamp)
+
+	return &File{
+		q: q,
+		c: r.c,
+	}
+}
+
+// A git ref (tag, branch or commit).
+type GitRef struct {
+	q *querybuilder.Selection
+	c graphql.Client
+}
+
+// GitRefTreeOpts contains options for GitRef.Tree
+type GitRefTreeOpts struct {
+	SSHKnownHosts string
 
 	SSHAuthSocket *Socket
 }
@@ -1952,6 +2980,93 @@ func (r *GitRepository) Tag(name string) *GitRef {
 	}
 }
 
+// The command that checks whether a container is healthy.
+type Healthcheck struct {
+	q *querybuilder.Selection
+	c graphql.Client
+
+	interval      *string
+	retries       *int
+	startInterval *string
+	startPeriod   *string
+	timeout       *string
+}
+
+// The time to wait between checks, if set.
+func (r *Healthcheck) Interval(ctx context.Context) (string, error) {
+	if r.interval != nil {
+		return *r.interval, nil
+	}
+	q := r.q.Select("interval")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The number of consecutive failed checks after which the container is unhealthy, if set.
+func (r *Healthcheck) Retries(ctx context.Context) (int, error) {
+	if r.retries != nil {
+		return *r.retries, nil
+	}
+	q := r.q.Select("retries")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The time to wait between checks during the start period, if set.
+func (r *Healthcheck) StartInterval(ctx context.Context) (string, error) {
+	if r.startInterval != nil {
+		return *r.startInterval, nil
+	}
+	q := r.q.Select("startInterval")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The time to give the container to start before failed checks count, if set.
+func (r *Healthcheck) StartPeriod(ctx context.Context) (string, error) {
+	if r.startPeriod != nil {
+		return *r.startPeriod, nil
+	}
+	q := r.q.Select("startPeriod")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The test to run.
+func (r *Healthcheck) Test(ctx context.Context) ([]string, error) {
+	q := r.q.Select("test")
+
+	var response []string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The time to wait before considering a check to have hung, if set.
+func (r *Healthcheck) Timeout(ctx context.Context) (string, error) {
+	if r.timeout != nil {
+		return *r.timeout, nil
+	}
+	q := r.q.Select("timeout")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
 // Information about the host execution environment.
 type Host struct {
 	q *querybuilder.Selection
@@ -1998,6 +3113,32 @@ func (r *Host) File(path string) *File {
 	}
 }
 
+// HostServiceOpts contains options for Host.Service
+type HostServiceOpts struct {
+	// Upstream host to forward traffic to.
+	Host string
+}
+
+// Creates a service that forwards connections to the host.
+//
+// Containers bound to the service with withBoundService reach the host's
+// ports through it, e.g. to use a database running on the host from tests.
+func (r *Host) Service(ports []PortForward, opts ...HostServiceOpts) *Service {
+	q := r.q.Select("service")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `host` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Host) {
+			q = q.Arg("host", opts[i].Host)
+		}
+	}
+	q = q.Arg("ports", ports)
+
+	return &Service{
+		q: q,
+		c: r.c,
+	}
+}
+
 // Sets a secret given a user-defined name and the file path on the host, and returns the secret.
 // The file is limited to a size of 512000 bytes.
 func (r *Host) SetSecretFile(name string, path string) *Secret {
@@ -2011,6 +3152,45 @@ func (r *Host) SetSecretFile(name string, path string) *Secret {
 	}
 }
 
+// HostTunnelOpts contains options for Host.Tunnel
+type HostTunnelOpts struct {
+	// The ports to forward from the host to the service.
+	//
+	// Defaults to every port exposed by the service, on the same or a random
+	// port of the host depending on native.
+	Ports []PortForward
+	// Forward every port of the service to the same port of the host, instead of
+	// a random one.
+	//
+	// Only used when no ports are given.
+	Native bool
+}
+
+// Creates a service that forwards ports of the host to a service.
+//
+// Once started, connections made to the host's ports on 127.0.0.1 reach the
+// service, e.g. to open a web app running in a container from a browser.
+func (r *Host) Tunnel(service *Service, opts ...HostTunnelOpts) *Service {
+	assertNotNil("service", service)
+	q := r.q.Select("tunnel")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `ports` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Ports) {
+			q = q.Arg("ports", opts[i].Ports)
+		}
+		// `native` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Native) {
+			q = q.Arg("native", opts[i].Native)
+		}
+	}
+	q = q.Arg("service", service)
+
+	return &Service{
+		q: q,
+		c: r.c,
+	}
+}
+
 // Accesses a Unix socket on the host.
 func (r *Host) UnixSocket(path string) *Socket {
 	q := r.q.Select("unixSocket")
@@ -2022,6 +3202,132 @@ func (r *Host) UnixSocket(path string) *Socket {
 	}
 }
 
+// An entry in the history of a container image.
+type ImageHistory struct {
+	q *querybuilder.Selection
+	c graphql.Client
+
+	author     *string
+	comment    *string
+	created    *string
+	createdBy  *string
+	emptyLayer *bool
+}
+
+// The author of the entry.
+func (r *ImageHistory) Author(ctx context.Context) (string, error) {
+	if r.author != nil {
+		return *r.author, nil
+	}
+	q := r.q.Select("author")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// A comment on the entry.
+func (r *ImageHistory) Comment(ctx context.Context) (string, error) {
+	if r.comment != nil {
+		return *r.comment, nil
+	}
+	q := r.q.Select("comment")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// When the entry was created, in RFC 3339 format.
+func (r *ImageHistory) Created(ctx context.Context) (string, error) {
+	if r.created != nil {
+		return *r.created, nil
+	}
+	q := r.q.Select("created")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The command that created the entry.
+func (r *ImageHistory) CreatedBy(ctx context.Context) (string, error) {
+	if r.createdBy != nil {
+		return *r.createdBy, nil
+	}
+	q := r.q.Select("createdBy")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// Whether the entry didn't create a layer, such as one that only changed the config.
+func (r *ImageHistory) EmptyLayer(ctx context.Context) (bool, error) {
+	if r.emptyLayer != nil {
+		return *r.emptyLayer, nil
+	}
+	q := r.q.Select("emptyLayer")
+
+	var response bool
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// A layer of a container image.
+type ImageLayer struct {
+	q *querybuilder.Selection
+	c graphql.Client
+
+	digest    *string
+	mediaType *string
+	size      *int
+}
+
+// The digest of the layer's blob.
+func (r *ImageLayer) Digest(ctx context.Context) (string, error) {
+	if r.digest != nil {
+		return *r.digest, nil
+	}
+	q := r.q.Select("digest")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The media type of the layer's blob.
+func (r *ImageLayer) MediaType(ctx context.Context) (string, error) {
+	if r.mediaType != nil {
+		return *r.mediaType, nil
+	}
+	q := r.q.Select("mediaType")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The size of the layer's blob in bytes.
+func (r *ImageLayer) Size(ctx context.Context) (int, error) {
+	if r.size != nil {
+		return *r.size, nil
+	}
+	q := r.q.Select("size")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
 // A simple key value object that represents a label.
 type Label struct {
 	q *querybuilder.Selection
@@ -2398,6 +3704,55 @@ func (r *ProjectCommandFlag) Name(ctx context.Context) (string, error) {
 	return response, q.Execute(ctx, r.c)
 }
 
+// The outcome of publishing an image to an address.
+type PublishResult struct {
+	q *querybuilder.Selection
+	c graphql.Client
+
+	address *string
+	digest  *string
+	error   *string
+}
+
+// The address the image was published to.
+func (r *PublishResult) Address(ctx context.Context) (string, error) {
+	if r.address != nil {
+		return *r.address, nil
+	}
+	q := r.q.Select("address")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The digest of the published image, or empty if publishing to the address failed.
+func (r *PublishResult) Digest(ctx context.Context) (string, error) {
+	if r.digest != nil {
+		return *r.digest, nil
+	}
+	q := r.q.Select("digest")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// Why publishing to the address failed, if it did.
+func (r *PublishResult) Error(ctx context.Context) (string, error) {
+	if r.error != nil {
+		return *r.error, nil
+	}
+	q := r.q.Select("error")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
 type WithClientFunc func(r *Client) *Client
 
 // With calls the provided function with current Client.
@@ -2460,6 +3815,39 @@ func (r *Client) Container(opts ...ContainerOpts) *Container {
 	}
 }
 
+// ContainerFromOCILayoutOpts contains options for Client.ContainerFromOCILayout
+type ContainerFromOCILayoutOpts struct {
+	// Identifies the tag to import from the layout, if the layout bundles
+	// multiple tags.
+	Tag string
+	// Platform of the image to load from the layout, if it bundles multiple
+	// platforms. Defaults to that of the builder's host.
+	Platform Platform
+}
+
+// Loads a container from an OCI image layout directory, such as one returned
+// by Container.asOCILayout.
+func (r *Client) ContainerFromOCILayout(dir *Directory, opts ...ContainerFromOCILayoutOpts) *Container {
+	assertNotNil("dir", dir)
+	q := r.q.Select("containerFromOCILayout")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `tag` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Tag) {
+			q = q.Arg("tag", opts[i].Tag)
+		}
+		// `platform` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Platform) {
+			q = q.Arg("platform", opts[i].Platform)
+		}
+	}
+	q = q.Arg("dir", dir)
+
+	return &Container{
+		q: q,
+		c: r.c,
+	}
+}
+
 // The default platform of the builder.
 func (r *Client) DefaultPlatform(ctx context.Context) (Platform, error) {
 	q := r.q.Select("defaultPlatform")
@@ -2646,6 +4034,17 @@ func (r *Client) Secret(id SecretID) *Secret {
 	}
 }
 
+// Loads a service from ID.
+func (r *Client) Service(id ServiceID) *Service {
+	q := r.q.Select("service")
+	q = q.Arg("id", id)
+
+	return &Service{
+		q: q,
+		c: r.c,
+	}
+}
+
 // Sets a secret given a user defined name to its plaintext and returns the secret.
 // The plaintext value is limited to a size of 128000 bytes.
 func (r *Client) SetSecret(name string, plaintext string) *Secret {
@@ -2734,22 +4133,323 @@ func (r *Secret) Plaintext(ctx context.Context) (string, error) {
 	return response, q.Execute(ctx, r.c)
 }
 
-type Socket struct {
+// A long-running process that other containers can reach over the network.
+//
+// Services are started when a container bound to them runs, and stopped once
+// nothing uses them anymore. They can also be started and stopped explicitly,
+// e.g. to share a service between many consumers.
+type Service struct {
 	q *querybuilder.Selection
 	c graphql.Client
 
-	id *SocketID
+	endpoint *string
+	exitCode *int
+	hostname *string
+	id       *ServiceID
+	logs     *string
+	start    *ServiceID
+	stop     *ServiceID
 }
+type WithServiceFunc func(r *Service) *Service
+
+// With calls the provided function with current Service.
+//
+// This is useful for reusability and readability by not breaking the calling chain.
+func (r *Service) With(f WithServiceFunc) *Service {
+	return f(r)
+}
+
+// ServiceEndpointOpts contains options for Service.Endpoint
+type ServiceEndpointOpts struct {
+	// The exposed port number for the endpoint
+	Port int
+	// Return a URL with the given scheme, eg. http for http://
+	Scheme string
+}
+
+// Retrieves an endpoint that clients can use to reach this service.
+//
+// If no port is specified, the first exposed port is used. If none exist an error is returned.
+//
+// If a scheme is specified, a URL is returned. Otherwise, a host:port pair is returned.
+func (r *Service) Endpoint(ctx context.Context, opts ...ServiceEndpointOpts) (string, error) {
+	if r.endpoint != nil {
+		return *r.endpoint, nil
+	}
+	q := r.q.Select("endpoint")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `port` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Port) {
+			q = q.Arg("port", opts[i].Port)
+		}
+		// `scheme` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Scheme) {
+			q = q.Arg("scheme", opts[i].Scheme)
+		}
+	}
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The exit code of the latest run of the service.
+//
+// Null if the service is still running or has never been started.
+func (r *Service) ExitCode(ctx context.Context) (int, error) {
+	if r.exitCode != nil {
+		return *r.exitCode, nil
+	}
+	q := r.q.Select("exitCode")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// Retrieves a hostname which can be used by clients to reach this service.
+func (r *Service) Hostname(ctx context.Context) (string, error) {
+	if r.hostname != nil {
+		return *r.hostname, nil
+	}
+	q := r.q.Select("hostname")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// A unique identifier for this service.
+func (r *Service) ID(ctx context.Context) (ServiceID, error) {
+	if r.id != nil {
+		return *r.id, nil
+	}
+	q := r.q.Select("id")
+
+	var response ServiceID
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// XXX_GraphQLType is an internal function. It returns the native GraphQL type name
+func (r *Service) XXX_GraphQLType() string {
+	return "Service"
+}
+
+// XXX_GraphQLIDType is an internal function. It returns the native GraphQL type name for the ID of this object
+func (r *Service) XXX_GraphQLIDType() string {
+	return "ServiceID"
+}
+
+// XXX_GraphQLID is an internal function. It returns the underlying type ID
+func (r *Service) XXX_GraphQLID(ctx context.Context) (string, error) {
+	id, err := r.ID(ctx)
+	if err != nil {
+		return "", err
+	}
+	return string(id), nil
+}
+
+// ServiceLogsOpts contains options for Service.Logs
+type ServiceLogsOpts struct {
+	// Only return output written since this time, given as an RFC 3339 timestamp
+	// (e.g., "2023-10-18T15:04:05Z") or as a duration before now (e.g., "5m").
+	Since string
+	// Only return this many of the last lines.
+	Tail int
+}
+
+// Retrieves the output of the latest run of the service, with its stdout and
+// stderr interleaved line by line.
+//
+// Output is kept after the service stops or exits, until the session ends,
+// up to the last 10000 lines.
+func (r *Service) Logs(ctx context.Context, opts ...ServiceLogsOpts) (string, error) {
+	if r.logs != nil {
+		return *r.logs, nil
+	}
+	q := r.q.Select("logs")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `since` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Since) {
+			q = q.Arg("since", opts[i].Since)
+		}
+		// `tail` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Tail) {
+			q = q.Arg("tail", opts[i].Tail)
+		}
+	}
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// Retrieves the list of ports provided by the service.
+func (r *Service) Ports(ctx context.Context) ([]Port, error) {
+	q := r.q.Select("ports")
+
+	q = q.Select("description port protocol")
+
+	type ports struct {
+		Description string
+		Port        int
+		Protocol    NetworkProtocol
+	}
+
+	convert := func(fields []ports) []Port {
+		out := []Port{}
+
+		for i := range fields {
+			out = append(out, Port{description: &fields[i].Description, port: &fields[i].Port, protocol: &fields[i].Protocol})
+		}
+
+		return out
+	}
+	var response []ports
+
+	q = q.Bind(&response)
+
+	err := q.Execute(ctx, r.c)
+	if err != nil {
+		return nil, err
+	}
+
+	return convert(response), nil
+}
+
+// Starts the service, waiting for its health check to pass.
+//
+// The service keeps running until it is stopped, or the session ends.
+// Starting a service that is already running does nothing.
+func (r *Service) Start(ctx context.Context) (*Service, error) {
+	q := r.q.Select("start")
+
+	return r, q.Execute(ctx, r.c)
+}
+
+// Stops the service, even if containers bound to it are still running.
+//
+// Stopping a service that isn't running does nothing.
+func (r *Service) Stop(ctx context.Context) (*Service, error) {
+	q := r.q.Select("stop")
+
+	return r, q.Execute(ctx, r.c)
+}
+
+// ServiceWithHealthcheckOpts contains options for Service.WithHealthcheck
+type ServiceWithHealthcheckOpts struct {
+	// Run this command in the service's container, which is healthy when it
+	// exits with 0 (e.g., ["pg_isready", "-U", "postgres"]).
+	Exec []string
+	// Send an HTTP GET request for this path to the service (e.g., "/healthz").
+	HTTPPath string
+	// The HTTP status a healthy service responds with.
+	HTTPStatus int
+	// Check that the service accepts TCP connections.
+	TCP bool
+	// Run the HEALTHCHECK of the container's image. Timings that aren't set
+	// here are taken from the image, or else default like Docker's to a 30s
+	// interval, a 30s timeout and 3 retries.
+	Image bool
+	// The port to send requests or connect to. Defaults to the first exposed port.
+	Port int
+	// The time to wait between checks. Defaults to 1s.
+	Interval string
+	// The time to wait before considering a check to have hung. Defaults to 30s.
+	Timeout string
+	// The time to give the service to start before failed checks count.
+	StartPeriod string
+	// The time to wait between checks during the start period. Defaults to interval.
+	StartInterval string
+	// The number of consecutive failed checks after the start period after
+	// which the service fails to start. If 0, checks continue until the service
+	// exits.
+	Retries int
+}
+
+// Retrieves this service with a health check that must pass for it to start,
+// in place of waiting for its exposed ports to accept connections.
+//
+// Exactly one of exec, httpPath, tcp or image must be set.
+//
+// Durations are formatted like "500ms" or "1m30s".
+func (r *Service) WithHealthcheck(opts ...ServiceWithHealthcheckOpts) *Service {
+	q := r.q.Select("withHealthcheck")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `exec` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Exec) {
+			q = q.Arg("exec", opts[i].Exec)
+		}
+		// `httpPath` optional argument
+		if !querybuilder.IsZeroValue(opts[i].HTTPPath) {
+			q = q.Arg("httpPath", opts[i].HTTPPath)
+		}
+		// `httpStatus` optional argument
+		if !querybuilder.IsZeroValue(opts[i].HTTPStatus) {
+			q = q.Arg("httpStatus", opts[i].HTTPStatus)
+		}
+		// `tcp` optional argument
+		if !querybuilder.IsZeroValue(opts[i].TCP) {
+			q = q.Arg("tcp", opts[i].TCP)
+		}
+		// `image` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Image) {
+			q = q.Arg("image", opts[i].Image)
+		}
+		// `port` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Port) {
+			q = q.Arg("port", opts[i].Port)
+		}
+		// `interval` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Interval) {
+			q = q.Arg("interval", opts[i].Interval)
+		}
+		// `timeout` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Timeout) {
+			q = q.Arg("timeout", opts[i].Timeout)
+		}
+		// `startPeriod` optional argument
+		if !querybuilder.IsZeroValue(opts[i].StartPeriod) {
+			q = q.Arg("startPeriod", opts[i].StartPeriod)
+		}
+		// `startInterval` optional argument
+		if !querybuilder.IsZeroValue(opts[i].StartInterval) {
+			q = q.Arg("startInterval", opts[i].StartInterval)
+		}
+		// `retries` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Retries) {
+			q = q.Arg("retries", opts[i].Retries)
+		}
+	}
+
+	return &Service{
+		q: q,
+		c: r.c,
+	}
+}
+
+type Socket struct {
+	q *querybuilder.Selection
+	c graphql.Client
+
+	id *SocketID
+}
+
+// The content-addressed identifier of the socket.
+func (r *Socket) ID(ctx context.Context) (SocketID, error) {
+	if r.id != nil {
+		return *r.id, nil
+	}
+	q := r.q.Select("id")
+
+	var response SocketID
 
-// The content-addressed identifier of the socket.
-func (r *Socket) ID(ctx context.Context) (SocketID, error) {
-	if r.id != nil {
-		return *r.id, nil
-	}
-	q := r.q.Select("id")
-
-	var response SocketID
-
 	q = q.Bind(&response)
 	return response, q.Execute(ctx, r.c)
 }
@@ -2773,6 +4473,187 @@ func (r *Socket) XXX_GraphQLID(ctx context.Context) (string, error) {
 	return string(id), nil
 }
 
+// Metadata of a file or directory.
+type Stat struct {
+	q *querybuilder.Selection
+	c graphql.Client
+
+	fileType      *FileType
+	gid           *int
+	modifiedAt    *int
+	path          *string
+	permissions   *int
+	size          *int
+	symlinkTarget *string
+	uid           *int
+}
+
+// The type of the file or directory.
+func (r *Stat) FileType(ctx context.Context) (FileType, error) {
+	if r.fileType != nil {
+		return *r.fileType, nil
+	}
+	q := r.q.Select("fileType")
+
+	var response FileType
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The group ID that owns the file or directory.
+func (r *Stat) Gid(ctx context.Context) (int, error) {
+	if r.gid != nil {
+		return *r.gid, nil
+	}
+	q := r.q.Select("gid")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The time the file or directory was last modified.
+//
+// Formatted in seconds following Unix epoch (e.g., 1672531199).
+func (r *Stat) ModifiedAt(ctx context.Context) (int, error) {
+	if r.modifiedAt != nil {
+		return *r.modifiedAt, nil
+	}
+	q := r.q.Select("modifiedAt")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The path of the file or directory, relative to the directory it was looked up in.
+func (r *Stat) Path(ctx context.Context) (string, error) {
+	if r.path != nil {
+		return *r.path, nil
+	}
+	q := r.q.Select("path")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The permission bits of the file or directory (e.g., 420 for 0o644).
+func (r *Stat) Permissions(ctx context.Context) (int, error) {
+	if r.permissions != nil {
+		return *r.permissions, nil
+	}
+	q := r.q.Select("permissions")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The size of the file, in bytes.
+func (r *Stat) Size(ctx context.Context) (int, error) {
+	if r.size != nil {
+		return *r.size, nil
+	}
+	q := r.q.Select("size")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The path that the symlink points to, if the file is a symlink.
+func (r *Stat) SymlinkTarget(ctx context.Context) (string, error) {
+	if r.symlinkTarget != nil {
+		return *r.symlinkTarget, nil
+	}
+	q := r.q.Select("symlinkTarget")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The user ID that owns the file or directory.
+func (r *Stat) UID(ctx context.Context) (int, error) {
+	if r.uid != nil {
+		return *r.uid, nil
+	}
+	q := r.q.Select("uid")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// An interactive terminal running in a container.
+type Terminal struct {
+	q *querybuilder.Selection
+	c graphql.Client
+
+	id                *TerminalID
+	websocketEndpoint *string
+}
+
+// A unique identifier for this terminal.
+func (r *Terminal) ID(ctx context.Context) (TerminalID, error) {
+	if r.id != nil {
+		return *r.id, nil
+	}
+	q := r.q.Select("id")
+
+	var response TerminalID
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// XXX_GraphQLType is an internal function. It returns the native GraphQL type name
+func (r *Terminal) XXX_GraphQLType() string {
+	return "Terminal"
+}
+
+// XXX_GraphQLIDType is an internal function. It returns the native GraphQL type name for the ID of this object
+func (r *Terminal) XXX_GraphQLIDType() string {
+	return "TerminalID"
+}
+
+// XXX_GraphQLID is an internal function. It returns the underlying type ID
+func (r *Terminal) XXX_GraphQLID(ctx context.Context) (string, error) {
+	id, err := r.ID(ctx)
+	if err != nil {
+		return "", err
+	}
+	return string(id), nil
+}
+
+// The websocket endpoint to attach to this terminal at, relative to the session (e.g., "ws://dagger/terminals/<id>").
+//
+// Every message sent over the websocket is prefixed with a channel byte:
+// 0 for stdin, 1 for stdout, 2 for stderr, 3 for a JSON encoded resize event
+// (e.g., {"width":80,"height":24}) and 4 for the process's exit code.
+//
+// The terminal's container is released once its process exits.
+func (r *Terminal) WebsocketEndpoint(ctx context.Context) (string, error) {
+	if r.websocketEndpoint != nil {
+		return *r.websocketEndpoint, nil
+	}
+	q := r.q.Select("websocketEndpoint")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
 type CacheSharingMode string
 
 const (
@@ -2781,6 +4662,23 @@ const (
 	Shared  CacheSharingMode = "SHARED"
 )
 
+type ChangeKind string
+
+const (
+	Added    ChangeKind = "ADDED"
+	Deleted  ChangeKind = "DELETED"
+	Modified ChangeKind = "MODIFIED"
+)
+
+type FileType string
+
+const (
+	Dir     FileType = "DIR"
+	Other   FileType = "OTHER"
+	Regular FileType = "REGULAR"
+	Symlink FileType = "SYMLINK"
+)
+
 type ImageLayerCompression string
 
 const (
@@ -2797,9 +4695,25 @@ const (
 	Ocimediatypes    ImageMediaTypes = "OCIMediaTypes"
 )
 
+type ImagePullPolicy string
+
+const (
+	Always       ImagePullPolicy = "ALWAYS"
+	IfNotPresent ImagePullPolicy = "IF_NOT_PRESENT"
+	Never        ImagePullPolicy = "NEVER"
+)
+
 type NetworkProtocol string
 
 const (
 	Tcp NetworkProtocol = "TCP"
 	Udp NetworkProtocol = "UDP"
 )
+
+type ReturnType string
+
+const (
+	Any     ReturnType = "ANY"
+	Failure ReturnType = "FAILURE"
+	Success ReturnType = "SUCCESS"
+)
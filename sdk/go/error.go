@@ -50,6 +50,9 @@ func getCustomError(err error) error {
 		if stderr, ok := ext["stderr"].(string); ok {
 			e.Stderr = stderr
 		}
+		if limit, ok := ext["limitExceeded"].(string); ok {
+			e.LimitExceeded = limit
+		}
 		return e
 	}
 
@@ -63,6 +66,10 @@ type ExecError struct {
 	ExitCode int
 	Stdout   string
 	Stderr   string
+
+	// The resource limit that the exec exceeded, e.g. "timeout" or
+	// "memory", if any.
+	LimitExceeded string
 }
 
 func (e *ExecError) Error() string {
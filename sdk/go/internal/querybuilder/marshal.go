@@ -42,6 +42,7 @@ var (
 		"Platform":         {},
 		"ProjectID":        {},
 		"ProjectCommandID": {},
+		"ServiceID":        {},
 	}
 )
 
@@ -56,6 +57,10 @@ func MarshalGQL(ctx context.Context, v any) (string, error) {
 func marshalValue(ctx context.Context, v reflect.Value) (string, error) {
 	t := v.Type()
 
+	if t.Kind() == reflect.Pointer && v.IsNil() {
+		return "null", nil
+	}
+
 	if t.Implements(gqlMarshaller) {
 		return marshalCustom(ctx, v)
 	}
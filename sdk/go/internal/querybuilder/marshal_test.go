@@ -133,6 +133,12 @@ func TestCustomMarshaller(t *testing.T) {
 			},
 			expect: `["custom1","custom2"]`,
 		},
+		{
+			v: struct {
+				M *customMarshaller `json:"m"`
+			}{},
+			expect: `{m:null}`,
+		},
 	}
 
 	for _, testCase := range testCases {
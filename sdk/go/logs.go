@@ -0,0 +1,121 @@
+package dagger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// LogLine is a line of output from a container's exec.
+type LogLine struct {
+	// Stream is either "stdout" or "stderr".
+	Stream string `json:"stream"`
+
+	// Line is the line of output, without its trailing newline.
+	Line string `json:"line"`
+}
+
+// ContainerLogsOpts contains options for Client.ContainerLogs
+type ContainerLogsOpts struct {
+	// Keep streaming output until ctx is canceled rather than running the
+	// container's exec to completion, e.g. to tail the logs of a container
+	// that's bound as a service.
+	Follow bool
+}
+
+type logsRequest struct {
+	Container ContainerID `json:"container"`
+	Follow    bool        `json:"follow,omitempty"`
+}
+
+type logMessage struct {
+	LogLine
+	ExitCode *int            `json:"exitCode,omitempty"`
+	Error    *gqlerror.Error `json:"error,omitempty"`
+}
+
+// ContainerLogs runs the container's last exec, calling fn with each line of
+// its output as soon as it's written, in contrast to Container.Stdout and
+// Container.Stderr which only return once the exec has completed. If the exec
+// is cached, its output is replayed.
+//
+// It returns an *ExecError if the exec fails.
+func (c *Client) ContainerLogs(ctx context.Context, ctr *Container, fn func(LogLine) error, opts ...ContainerLogsOpts) (rerr error) {
+	defer func() {
+		if rerr != nil {
+			var execErr *ExecError
+			if !errors.As(rerr, &execErr) {
+				rerr = withErrorHelp(rerr)
+			}
+		}
+	}()
+
+	id, err := ctr.ID(ctx)
+	if err != nil {
+		return err
+	}
+
+	logsReq := logsRequest{Container: id}
+	for _, opt := range opts {
+		if opt.Follow {
+			logsReq.Follow = true
+		}
+	}
+
+	body, err := json.Marshal(logsReq)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+c.conn.Host()+"/logs", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.conn.Do(req)
+	if err != nil {
+		return fmt.Errorf("stream logs: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("stream logs: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg logMessage
+		if err := dec.Decode(&msg); err != nil {
+			if logsReq.Follow && ctx.Err() != nil {
+				return nil
+			}
+			if errors.Is(err, io.EOF) {
+				return errors.New("stream logs: ended before the exec finished")
+			}
+			return fmt.Errorf("stream logs: %w", err)
+		}
+
+		if msg.Error != nil {
+			if e := getCustomError(msg.Error); e != nil {
+				return e
+			}
+			return msg.Error
+		}
+
+		if msg.ExitCode != nil {
+			return nil
+		}
+
+		if err := fn(msg.LogLine); err != nil {
+			return err
+		}
+	}
+}